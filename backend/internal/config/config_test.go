@@ -0,0 +1,46 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	cases := []struct {
+		value string
+		want  []string
+	}{
+		{"", nil},
+		{" , ,", nil},
+		{"*", []string{"*"}},
+		{"https://a.example.com", []string{"https://a.example.com"}},
+		{"https://a.example.com, https://b.example.com ,,", []string{"https://a.example.com", "https://b.example.com"}},
+	}
+	for _, tc := range cases {
+		if got := parseCSV(tc.value); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseCSV(%q) = %q, want %q", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestGetEnvBool(t *testing.T) {
+	cases := []struct {
+		value string
+		set   bool
+		want  bool
+	}{
+		{"", false, false},
+		{"true", true, true},
+		{"1", true, true},
+		{"false", true, false},
+		{"yes please", true, false},
+	}
+	for _, tc := range cases {
+		if tc.set {
+			t.Setenv("ALLOW_LOCALHOST_ORIGINS", tc.value)
+		}
+		if got := getEnvBool("ALLOW_LOCALHOST_ORIGINS", false); got != tc.want {
+			t.Errorf("ALLOW_LOCALHOST_ORIGINS=%q gave %v, want %v", tc.value, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// Package errs defines domain errors shared by repositories, services and handlers.
+// Wrap them with fmt.Errorf("...: %w", err) to add context; HTTPStatus still matches.
+package errs
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrNotConnected    = errors.New("client is not connected")
+	ErrForbidden       = errors.New("session not accessible")
+	ErrRateLimited     = errors.New("rate limit exceeded")
+	ErrInvalidInput    = errors.New("invalid input")
+	ErrQuotaExceeded   = errors.New("session quota exceeded")
+	ErrAlreadyPaired   = errors.New("session is already paired")
+
+	ErrTemplateNotFound = errors.New("template not found")
+	ErrTemplateExists   = errors.New("template already exists")
+
+	ErrGroupSettingNotFound = errors.New("group setting not found")
+
+	ErrScheduledMessageNotFound = errors.New("scheduled message not found")
+	ErrScheduleNotPending       = errors.New("scheduled message is no longer pending")
+
+	ErrBroadcastNotFound   = errors.New("broadcast not found")
+	ErrBroadcastNotRunning = errors.New("broadcast is no longer running")
+)
+
+// HTTPStatus maps a domain error to its HTTP status; unknown errors are 500.
+func HTTPStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrSessionNotFound), errors.Is(err, ErrTemplateNotFound), errors.Is(err, ErrGroupSettingNotFound),
+		errors.Is(err, ErrScheduledMessageNotFound), errors.Is(err, ErrBroadcastNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrForbidden), errors.Is(err, ErrQuotaExceeded):
+		return http.StatusForbidden
+	case errors.Is(err, ErrNotConnected), errors.Is(err, ErrAlreadyPaired), errors.Is(err, ErrTemplateExists),
+		errors.Is(err, ErrScheduleNotPending), errors.Is(err, ErrBroadcastNotRunning):
+		return http.StatusConflict
+	case errors.Is(err, ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrInvalidInput):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"wago-backend/internal/model"
+	"wago-backend/internal/repository"
+	"wago-backend/internal/service"
+	"wago-backend/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+type AdminHandler struct {
+	SessionService *service.SessionService
+	AuditRepo      *repository.AuditRepository
+}
+
+func NewAdminHandler(sessionService *service.SessionService, auditRepo *repository.AuditRepository) *AdminHandler {
+	return &AdminHandler{SessionService: sessionService, AuditRepo: auditRepo}
+}
+
+func (h *AdminHandler) GetStoreReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.SessionService.StoreReport()
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, report, "Store report generated")
+}
+
+// DisconnectSession force-disconnects any user's session, e.g. one that is misbehaving.
+func (h *AdminHandler) DisconnectSession(w http.ResponseWriter, r *http.Request) {
+	if err := h.SessionService.ForceDisconnect(mux.Vars(r)["id"]); err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, nil, "Session disconnected")
+}
+
+// GetAuditLog lists audit entries, newest first, optionally filtered by actor, action and target.
+func (h *AdminHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := model.AuditFilter{
+		Actor:  query.Get("actor"),
+		Action: query.Get("action"),
+		Target: query.Get("target"),
+		Limit:  100,
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > 1000 {
+			utils.ErrorResponse(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := h.AuditRepo.List(filter)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, entries, "Audit log retrieved successfully")
+}
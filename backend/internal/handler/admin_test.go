@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wago-backend/internal/config"
+	"wago-backend/internal/model"
+	"wago-backend/internal/repository"
+	"wago-backend/internal/service"
+	"wago-backend/internal/whatsapp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestDisconnectSession(t *testing.T) {
+	sessions, mock := newMockDB(t)
+	audit := repository.NewAuditRepository(sessions.DB)
+	h := NewAdminHandler(service.NewSessionService(sessions, nil, audit, &whatsapp.ClientManager{}, &config.Config{}), audit)
+
+	expectSessionLookup(mock, "s1", "user-1")
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(model.AuditActorAdmin, model.AuditSessionDisconnected, "s1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	req := httptest.NewRequest(http.MethodPost, "/admin/sessions/s1/disconnect", nil)
+	if rec := serve(h.DisconnectSession, req, map[string]string{"id": "s1"}, ""); rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	// Nothing is audited for a session that does not exist.
+	expectMissingSession(mock, "s2")
+	req = httptest.NewRequest(http.MethodPost, "/admin/sessions/s2/disconnect", nil)
+	if rec := serve(h.DisconnectSession, req, map[string]string{"id": "s2"}, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("missing session: status = %d, want 404: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"wago-backend/internal/model"
+)
+
+var messageExportHeader = []string{"id", "timestamp", "direction", "from_number", "to_number", "message_type", "content",
+	"media_url", "is_group", "group_id", "group_name", "quoted_message_id", "message_id"}
+
+func messageExportRow(m model.MessageLog) []string {
+	return []string{strconv.FormatInt(m.ID, 10), m.Timestamp.UTC().Format(time.RFC3339), m.Direction, m.FromNumber, m.ToNumber,
+		m.MessageType, m.Content, m.MediaURL, strconv.FormatBool(m.IsGroup), m.GroupID, m.GroupName, m.QuotedMessageID, m.MessageID}
+}
+
+var analyticsExportHeader = []string{"id", "created_at", "message_id", "from_number", "message_type", "is_group", "is_mention",
+	"webhook_sent", "webhook_success", "webhook_response_time_ms", "webhook_status_code", "error_message", "dry_run"}
+
+func analyticsExportRow(a model.Analytics) []string {
+	return []string{strconv.FormatInt(a.ID, 10), a.CreatedAt.UTC().Format(time.RFC3339), a.MessageID, a.FromNumber, a.MessageType,
+		strconv.FormatBool(a.IsGroup), strconv.FormatBool(a.IsMention), strconv.FormatBool(a.WebhookSent),
+		strconv.FormatBool(a.WebhookSuccess), strconv.Itoa(a.WebhookResponseTime), strconv.Itoa(a.WebhookStatusCode),
+		a.ErrorMessage, strconv.FormatBool(a.DryRun)}
+}
+
+// ExportAnalytics streams the session's message log (?data=messages, default) or webhook
+// analytics (?data=analytics) between ?from= and ?to= (YYYY-MM-DD, inclusive, in ?tz=) as a
+// CSV (?format=csv, default) or JSON array download. Rows are written as they are read.
+func (h *AnalyticsHandler) ExportAnalytics(w http.ResponseWriter, r *http.Request) {
+	session := requireOwnedSession(h.SessionService, w, r)
+	if session == nil {
+		return
+	}
+	sessionID := session.ID
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "json" {
+		http.Error(w, "format must be csv or json", http.StatusBadRequest)
+		return
+	}
+	data := r.URL.Query().Get("data")
+	if data == "" {
+		data = "messages"
+	}
+	if data != "messages" && data != "analytics" {
+		http.Error(w, "data must be messages or analytics", http.StatusBadRequest)
+		return
+	}
+
+	loc, ok := requestLocation(w, r)
+	if !ok {
+		return
+	}
+	from, to, ok := requestDayRange(w, r, loc)
+	if !ok {
+		return
+	}
+
+	filename := fmt.Sprintf("%s-%s-%s-%s.%s", data, sessionID, from.Format("20060102"), to.AddDate(0, 0, -1).Format("20060102"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+
+	// Headers go out with the first rows, so a failure after that can only truncate the download.
+	out := newExportWriter(w, format)
+	var err error
+	if data == "messages" {
+		out.header(messageExportHeader)
+		err = h.Repo.StreamMessages(sessionID, from, to, func(m model.MessageLog) error {
+			return out.row(m, messageExportRow(m))
+		})
+	} else {
+		out.header(analyticsExportHeader)
+		err = h.Repo.StreamAnalytics(sessionID, from, to, func(a model.Analytics) error {
+			return out.row(a, analyticsExportRow(a))
+		})
+	}
+	if err == nil {
+		err = out.close()
+	}
+	if err != nil && out.rows == 0 {
+		w.Header().Del("Content-Disposition")
+		http.Error(w, "Failed to export "+data, http.StatusInternalServerError)
+		return
+	}
+	if err != nil {
+		log.Printf("Export of %s for session %s failed after %d rows: %v", data, sessionID, out.rows, err)
+	}
+}
+
+// exportWriter writes export rows as CSV records or elements of a JSON array.
+type exportWriter struct {
+	w      io.Writer
+	csv    *csv.Writer
+	rows   int
+	isJSON bool
+}
+
+func newExportWriter(w io.Writer, format string) *exportWriter {
+	if format == "json" {
+		return &exportWriter{w: w, isJSON: true}
+	}
+	return &exportWriter{w: w, csv: csv.NewWriter(w)}
+}
+
+func (e *exportWriter) header(columns []string) {
+	if !e.isJSON {
+		e.csv.Write(columns)
+	}
+}
+
+// row writes one record: v as JSON, or record as CSV. CSV output is flushed every 500 rows.
+func (e *exportWriter) row(v interface{}, record []string) error {
+	e.rows++
+	if !e.isJSON {
+		if err := e.csv.Write(record); err != nil {
+			return err
+		}
+		if e.rows%500 == 0 {
+			e.csv.Flush()
+			return e.csv.Error()
+		}
+		return nil
+	}
+
+	sep := ","
+	if e.rows == 1 {
+		sep = "["
+	}
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	return json.NewEncoder(e.w).Encode(v)
+}
+
+func (e *exportWriter) close() error {
+	if !e.isJSON {
+		e.csv.Flush()
+		return e.csv.Error()
+	}
+	closing := "]\n"
+	if e.rows == 0 {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(e.w, closing)
+	return err
+}
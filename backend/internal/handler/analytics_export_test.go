@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wago-backend/internal/repository"
+)
+
+func TestExportAnalyticsRequiresOwnership(t *testing.T) {
+	cases := []struct {
+		name       string
+		owner      string
+		wantStatus int
+	}{
+		{"another user's session", "user-2", http.StatusForbidden},
+		{"unknown session", "", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sessions, mock := newMockDB(t)
+			if tc.owner == "" {
+				expectMissingSession(mock, "s1")
+			} else {
+				expectSessionLookup(mock, "s1", tc.owner)
+			}
+			// The analytics repository has no expectations: any query fails the test.
+			h := NewAnalyticsHandler(repository.NewAnalyticsRepository(sessions.DB), newSessionService(sessions))
+
+			req := httptest.NewRequest(http.MethodGet, "/sessions/s1/analytics/export", nil)
+			rec := serve(h.ExportAnalytics, req, map[string]string{"id": "s1"}, "user-1")
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tc.wantStatus, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Disposition"); got != "" {
+				t.Errorf("Content-Disposition = %q on a rejected export", got)
+			}
+		})
+	}
+}
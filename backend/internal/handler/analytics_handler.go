@@ -3,19 +3,74 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 	"wago-backend/internal/repository"
+	"wago-backend/internal/service"
 
 	"github.com/gorilla/mux"
 )
 
 type AnalyticsHandler struct {
-	Repo *repository.AnalyticsRepository
+	Repo           *repository.AnalyticsRepository
+	SessionService *service.SessionService
 }
 
-func NewAnalyticsHandler(repo *repository.AnalyticsRepository) *AnalyticsHandler {
-	return &AnalyticsHandler{Repo: repo}
+func NewAnalyticsHandler(repo *repository.AnalyticsRepository, sessionService *service.SessionService) *AnalyticsHandler {
+	return &AnalyticsHandler{Repo: repo, SessionService: sessionService}
 }
 
+// requestLocation reads the ?tz= IANA timezone used to bucket daily stats, defaulting to UTC.
+// It writes a 400 and returns false for unknown zones.
+func requestLocation(w http.ResponseWriter, r *http.Request) (*time.Location, bool) {
+	name := r.URL.Query().Get("tz")
+	if name == "" {
+		return time.UTC, true
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		http.Error(w, "Invalid tz, expected an IANA timezone such as Asia/Jakarta", http.StatusBadRequest)
+		return nil, false
+	}
+	return loc, true
+}
+
+// requestDayRange reads ?from= and ?to= (YYYY-MM-DD, inclusive, in loc), defaulting to the
+// last 30 days. It returns the half-open range [from, day after to) or writes a 400.
+func requestDayRange(w http.ResponseWriter, r *http.Request, loc *time.Location) (time.Time, time.Time, bool) {
+	const layout = "2006-01-02"
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	from := today.AddDate(0, 0, -29)
+	to := today
+
+	if raw := r.URL.Query().Get("from"); raw != "" {
+		parsed, err := time.ParseInLocation(layout, raw, loc)
+		if err != nil {
+			http.Error(w, "Invalid from date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return time.Time{}, time.Time{}, false
+		}
+		from = parsed
+	}
+	if raw := r.URL.Query().Get("to"); raw != "" {
+		parsed, err := time.ParseInLocation(layout, raw, loc)
+		if err != nil {
+			http.Error(w, "Invalid to date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return time.Time{}, time.Time{}, false
+		}
+		to = parsed
+	}
+	if to.Before(from) {
+		http.Error(w, "to must not be before from", http.StatusBadRequest)
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to.AddDate(0, 0, 1), true
+}
+
+// maxAnalyticsRange caps ?from=/?to= on session analytics so one request can't scan years of rows.
+const maxAnalyticsRange = 366 * 24 * time.Hour
+
+// GetSessionAnalytics returns the session's stats. Without ?from= / ?to= totals are all-time and
+// daily stats cover the last 7 days; with either, both cover that range (see requestDayRange).
 func (h *AnalyticsHandler) GetSessionAnalytics(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	sessionID := vars["id"]
@@ -25,7 +80,24 @@ func (h *AnalyticsHandler) GetSessionAnalytics(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	stats, err := h.Repo.GetSessionAnalytics(sessionID)
+	loc, ok := requestLocation(w, r)
+	if !ok {
+		return
+	}
+
+	var from, to time.Time
+	if r.URL.Query().Get("from") != "" || r.URL.Query().Get("to") != "" {
+		from, to, ok = requestDayRange(w, r, loc)
+		if !ok {
+			return
+		}
+		if to.Sub(from) > maxAnalyticsRange {
+			http.Error(w, "Date range must not exceed 366 days", http.StatusBadRequest)
+			return
+		}
+	}
+
+	stats, err := h.Repo.GetSessionAnalytics(sessionID, loc, from, to)
 	if err != nil {
 		http.Error(w, "Failed to fetch analytics", http.StatusInternalServerError)
 		return
@@ -53,3 +125,59 @@ func (h *AnalyticsHandler) GetSessionContacts(w http.ResponseWriter, r *http.Req
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(contacts)
 }
+
+// GetContactGrowth returns new contacts per day between ?from= and ?to= (YYYY-MM-DD, inclusive,
+// in ?tz=). Defaults to the last 30 days.
+func (h *AnalyticsHandler) GetContactGrowth(w http.ResponseWriter, r *http.Request) {
+	session := requireOwnedSession(h.SessionService, w, r)
+	if session == nil {
+		return
+	}
+	sessionID := session.ID
+
+	loc, ok := requestLocation(w, r)
+	if !ok {
+		return
+	}
+	from, to, ok := requestDayRange(w, r, loc)
+	if !ok {
+		return
+	}
+
+	growth, err := h.Repo.GetContactGrowth(sessionID, from, to, loc)
+	if err != nil {
+		http.Error(w, "Failed to fetch contact growth", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(growth)
+}
+
+// GetWebhookStatsTimeline returns webhook calls, successes and failures per day between ?from=
+// and ?to= (YYYY-MM-DD, inclusive, in ?tz=). Defaults to the last 30 days.
+func (h *AnalyticsHandler) GetWebhookStatsTimeline(w http.ResponseWriter, r *http.Request) {
+	session := requireOwnedSession(h.SessionService, w, r)
+	if session == nil {
+		return
+	}
+	sessionID := session.ID
+
+	loc, ok := requestLocation(w, r)
+	if !ok {
+		return
+	}
+	from, to, ok := requestDayRange(w, r, loc)
+	if !ok {
+		return
+	}
+
+	stats, err := h.Repo.GetWebhookStatsTimeline(sessionID, from, to, loc)
+	if err != nil {
+		http.Error(w, "Failed to fetch webhook stats", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wago-backend/internal/repository"
+)
+
+// testAnalyticsOwnership checks that handler refuses sessions the caller doesn't own
+// before touching the analytics repository.
+func testAnalyticsOwnership(t *testing.T, path string, handler func(*AnalyticsHandler) http.HandlerFunc) {
+	t.Helper()
+	cases := []struct {
+		name       string
+		owner      string
+		wantStatus int
+	}{
+		{"another user's session", "user-2", http.StatusForbidden},
+		{"unknown session", "", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sessions, mock := newMockDB(t)
+			if tc.owner == "" {
+				expectMissingSession(mock, "s1")
+			} else {
+				expectSessionLookup(mock, "s1", tc.owner)
+			}
+			// The analytics repository has no expectations: any query fails the test.
+			h := NewAnalyticsHandler(repository.NewAnalyticsRepository(sessions.DB), newSessionService(sessions))
+
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			rec := serve(handler(h), req, map[string]string{"id": "s1"}, "user-1")
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tc.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestWebhookStatsTimelineRequiresOwnership(t *testing.T) {
+	testAnalyticsOwnership(t, "/sessions/s1/analytics/webhooks/timeline", func(h *AnalyticsHandler) http.HandlerFunc {
+		return h.GetWebhookStatsTimeline
+	})
+}
+
+func TestContactGrowthRequiresOwnership(t *testing.T) {
+	testAnalyticsOwnership(t, "/sessions/s1/analytics/contacts/growth", func(h *AnalyticsHandler) http.HandlerFunc {
+		return h.GetContactGrowth
+	})
+}
@@ -2,17 +2,23 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 	"wago-backend/internal/service"
 	"wago-backend/internal/utils"
 )
 
 type AuthHandler struct {
-	AuthService *service.AuthService
+	AuthService    *service.AuthService
+	TrustedProxies utils.TrustedProxies // for the client IP recorded with failed logins
 }
 
 func NewAuthHandler(authService *service.AuthService) *AuthHandler {
-	return &AuthHandler{AuthService: authService}
+	return &AuthHandler{
+		AuthService:    authService,
+		TrustedProxies: utils.ParseTrustedProxies(authService.Config.TrustedProxies),
+	}
 }
 
 func (h *AuthHandler) GeneratePIN(w http.ResponseWriter, r *http.Request) {
@@ -50,21 +56,98 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	token, user, err := h.AuthService.Login(pin)
+	tokens, user, err := h.AuthService.Login(pin, h.TrustedProxies.ClientIP(r))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	utils.SuccessResponse(w, http.StatusOK, map[string]interface{}{
-		"user_id": user.ID,
-		"token":   token,
-		"pin":     user.PIN,
+		"user_id":            user.ID,
+		"token":              tokens.AccessToken,
+		"expires_at":         tokens.AccessExpiresAt,
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+		"pin":                user.PIN,
 	}, "Login successful")
 }
 
+// Refresh issues a new token pair from a valid refresh token (JSON body) or, failing that,
+// the current access token (Authorization: Bearer). It never extends the login beyond
+// JWT_REFRESH_EXPIRY_HOURS.
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	token := strings.TrimSpace(req.RefreshToken)
+	if token == "" {
+		token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+
+	tokens, userID, err := h.AuthService.Refresh(token)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, map[string]interface{}{
+		"user_id":            userID,
+		"token":              tokens.AccessToken,
+		"expires_at":         tokens.AccessExpiresAt,
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+	}, "Token refreshed")
+}
+
+// RotatePIN replaces the caller's PIN with a new one and returns it. It accepts a bearer
+// token, `Authorization: Pin <pin>` or X-Pin; an expired PIN is refused, so once a PIN has
+// expired only a signed-in token can rotate it.
+func (h *AuthHandler) RotatePIN(w http.ResponseWriter, r *http.Request) {
+	var userID string
+	var err error
+	scheme, credential, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	switch {
+	case scheme == "Bearer":
+		userID, err = h.AuthService.Authenticate(credential)
+	case strings.EqualFold(scheme, "Pin"):
+		userID, err = h.AuthService.PINOwner(credential)
+	case r.Header.Get("X-Pin") != "":
+		userID, err = h.AuthService.PINOwner(r.Header.Get("X-Pin"))
+	default:
+		err = errors.New("missing or invalid credentials")
+	}
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	user, err := h.AuthService.RotatePIN(userID)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, map[string]interface{}{
+		"user_id":        user.ID,
+		"pin":            user.PIN,
+		"pin_rotated_at": user.PINRotatedAt,
+	}, "PIN rotated. Please save the new PIN; the old one no longer works.")
+}
+
+// Logout revokes the bearer token and, when given in the body, the refresh token,
+// so neither can be used again even though they haven't expired.
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	// Since JWT is stateless, we can't really "logout" on server side without a blacklist.
-	// For now, just return success as per PRD.
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	accessToken := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if err := h.AuthService.Logout(accessToken, strings.TrimSpace(req.RefreshToken)); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	utils.SuccessResponse(w, http.StatusOK, nil, "Logout successful")
 }
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wago-backend/internal/config"
+	"wago-backend/internal/repository"
+	"wago-backend/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRotatePINRefusesExpiredPIN(t *testing.T) {
+	sessions, mock := newMockDB(t)
+	db := sessions.DB
+	cfg := &config.Config{PINMaxAge: 30 * 24 * time.Hour}
+	h := NewAuthHandler(service.NewAuthService(repository.NewUserRepository(db), nil, repository.NewTokenRepository(db), cfg))
+
+	rotatedAt := time.Now().Add(-31 * 24 * time.Hour)
+	mock.ExpectQuery("FROM users").WithArgs("123456").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "pin", "pin_rotated_at", "created_at", "updated_at", "last_login"}).
+			AddRow("user-1", "123456", rotatedAt, rotatedAt, rotatedAt, nil))
+	// No rotation is expected: an UPDATE would fail the mock.
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/pin/rotate", nil)
+	req.Header.Set("X-Pin", "123456")
+	rec := serve(h.RotatePIN, req, nil, "")
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401: %s", rec.Code, rec.Body)
+	}
+}
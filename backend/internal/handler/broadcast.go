@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"wago-backend/internal/model"
+	"wago-backend/internal/service"
+	"wago-backend/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+type BroadcastHandler struct {
+	BroadcastService *service.BroadcastService
+	SessionService   *service.SessionService
+}
+
+func NewBroadcastHandler(broadcastService *service.BroadcastService, sessionService *service.SessionService) *BroadcastHandler {
+	return &BroadcastHandler{
+		BroadcastService: broadcastService,
+		SessionService:   sessionService,
+	}
+}
+
+// broadcastID reads the {broadcast_id} path variable, writing a 400 when it isn't a number.
+func broadcastID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["broadcast_id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid broadcast id")
+		return 0, false
+	}
+	return id, true
+}
+
+// CreateBroadcast starts sending message to every recipient; progress is streamed over the
+// session websocket and the job can be queried with GetBroadcast.
+func (h *BroadcastHandler) CreateBroadcast(w http.ResponseWriter, r *http.Request) {
+	session := requireOwnedSession(h.SessionService, w, r)
+	if session == nil {
+		return
+	}
+
+	var req struct {
+		Recipients []string                      `json:"recipients"`
+		Message    string                        `json:"message"`
+		Variables  map[string]model.TemplateVars `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !validTemplateBody(req.Message) {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Message is required and must be at most 4096 characters")
+		return
+	}
+
+	job, err := h.BroadcastService.CreateBroadcast(session.ID, req.Message, req.Recipients, req.Variables)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusAccepted, job, "Broadcast started")
+}
+
+func (h *BroadcastHandler) ListBroadcasts(w http.ResponseWriter, r *http.Request) {
+	session := requireOwnedSession(h.SessionService, w, r)
+	if session == nil {
+		return
+	}
+
+	jobs, err := h.BroadcastService.ListBroadcasts(session.ID)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, jobs, "Broadcasts retrieved successfully")
+}
+
+// GetBroadcast returns a job with the per-recipient results.
+func (h *BroadcastHandler) GetBroadcast(w http.ResponseWriter, r *http.Request) {
+	session := requireOwnedSession(h.SessionService, w, r)
+	if session == nil {
+		return
+	}
+	id, ok := broadcastID(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := h.BroadcastService.GetBroadcast(session.ID, id)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, job, "Broadcast retrieved successfully")
+}
+
+// CancelBroadcast stops a running job; recipients already sent to are unaffected.
+func (h *BroadcastHandler) CancelBroadcast(w http.ResponseWriter, r *http.Request) {
+	session := requireOwnedSession(h.SessionService, w, r)
+	if session == nil {
+		return
+	}
+	id, ok := broadcastID(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := h.BroadcastService.CancelBroadcast(session.ID, id)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, job, "Broadcast cancelled successfully")
+}
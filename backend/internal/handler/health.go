@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+	"wago-backend/internal/utils"
+	"wago-backend/internal/websocket"
+)
+
+// readyTimeout bounds the database ping in readiness checks so a hung database fails the probe quickly.
+const readyTimeout = 2 * time.Second
+
+// HealthHandler serves the unauthenticated liveness and readiness probes.
+type HealthHandler struct {
+	DB    *sql.DB
+	WSHub *websocket.Hub
+}
+
+func NewHealthHandler(db *sql.DB, wsHub *websocket.Hub) *HealthHandler {
+	return &HealthHandler{DB: db, WSHub: wsHub}
+}
+
+// Healthz reports that the process is up and serving requests.
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	utils.SuccessResponse(w, http.StatusOK, map[string]interface{}{"status": "ok"}, "")
+}
+
+// Readyz reports whether the instance can take traffic: the database answers
+// SELECT 1 and the websocket hub is running. It returns 503 otherwise.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{"database": "ok", "websocket_hub": "ok"}
+	ready := true
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+	var one int
+	if h.DB == nil {
+		checks["database"] = "not connected"
+		ready = false
+	} else if err := h.DB.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		// The probe is unauthenticated: the error may name hosts or users, so it is only logged.
+		log.Printf("Readiness check: database unavailable: %v", err)
+		checks["database"] = "unavailable"
+		ready = false
+	}
+
+	if h.WSHub == nil || !h.WSHub.Running() {
+		checks["websocket_hub"] = "not running"
+		ready = false
+	}
+
+	if !ready {
+		utils.JSONResponse(w, http.StatusServiceUnavailable, false, checks, "Not ready")
+		return
+	}
+	utils.SuccessResponse(w, http.StatusOK, checks, "")
+}
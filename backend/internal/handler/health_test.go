@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadyzHidesDatabaseError(t *testing.T) {
+	sessions, mock := newMockDB(t)
+	mock.ExpectQuery("SELECT 1").WillReturnError(errors.New(`dial tcp 10.0.3.7:5432: password authentication failed for user "wago"`))
+	h := NewHealthHandler(sessions.DB, watchedHub(t))
+
+	rec := serve(h.Readyz, httptest.NewRequest(http.MethodGet, "/readyz", nil), nil, "")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+	body := rec.Body.String()
+	if strings.Contains(body, "10.0.3.7") || strings.Contains(body, "wago") {
+		t.Errorf("database error leaked: %s", body)
+	}
+	if !strings.Contains(body, `"database":"unavailable"`) || !strings.Contains(body, `"websocket_hub":"ok"`) {
+		t.Errorf("unexpected checks: %s", body)
+	}
+}
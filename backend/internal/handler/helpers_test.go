@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"wago-backend/internal/config"
+	"wago-backend/internal/repository"
+	"wago-backend/internal/service"
+	"wago-backend/internal/whatsapp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+// This file holds helpers for exercising handlers against a sqlmock database.
+
+var sessionColumnNames = strings.Split(`id, user_id, session_name, webhook_url, webhook_secret, status, phone_number, device_info, last_connected, is_group_response_enabled, busy_reply_text, busy_reply_grace_ms, dry_run, reply_privately_in_groups, trigger_pattern, mark_read_on_success, webhook_format, webhook_method, tags, webhook_include_fields, webhook_exclude_fields, ingest_history, process_own_messages, reply_footer, webhook_stream, webhook_retries, webhook_timeout_seconds, auto_mark_read, webhook_status_events, quote_replies, typing_delay, muted_chats, created_at, updated_at`, ", ")
+
+// newMockDB returns a session repository over a sqlmock database whose expectations are
+// checked when the test ends.
+func newMockDB(t *testing.T) (*repository.SessionRepository, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	return repository.NewSessionRepository(db, nil), mock
+}
+
+// newSessionService builds a SessionService over repo with an idle client manager.
+func newSessionService(repo *repository.SessionRepository) *service.SessionService {
+	return service.NewSessionService(repo, nil, nil, &whatsapp.ClientManager{}, &config.Config{})
+}
+
+// expectSessionLookup makes the next session lookup by id return a session owned by userID.
+func expectSessionLookup(mock sqlmock.Sqlmock, id, userID string) {
+	now := time.Date(2025, 1, 15, 9, 30, 0, 0, time.UTC)
+	rows := sqlmock.NewRows(sessionColumnNames).AddRow(
+		id, userID, "test", "http://webhook.test/hook", "", "connected", nil, nil, nil, false,
+		"", 0, false, false, "", false, "json", "POST", []byte("[]"), []byte("[]"), []byte("[]"), false, false,
+		"", false, 0, 0, false, false, false, false, []byte("[]"), now, now)
+	mock.ExpectQuery("FROM sessions").WithArgs(id).WillReturnRows(rows)
+}
+
+// expectMissingSession makes the next session lookup by id find nothing.
+func expectMissingSession(mock sqlmock.Sqlmock, id string) {
+	mock.ExpectQuery("FROM sessions").WithArgs(id).WillReturnRows(sqlmock.NewRows(sessionColumnNames))
+}
+
+// serve calls handler with the route vars and the authenticated userID that the router and
+// auth middleware would have set.
+func serve(handler http.HandlerFunc, req *http.Request, vars map[string]string, userID string) *httptest.ResponseRecorder {
+	req = mux.SetURLVars(req, vars)
+	if userID != "" {
+		req = req.WithContext(context.WithValue(req.Context(), "user_id", userID))
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+	"wago-backend/internal/utils"
+	"wago-backend/internal/version"
+	"wago-backend/internal/websocket"
+)
+
+type MetricsHandler struct {
+	WSHub *websocket.Hub
+}
+
+func NewMetricsHandler(wsHub *websocket.Hub) *MetricsHandler {
+	return &MetricsHandler{WSHub: wsHub}
+}
+
+// GetMetrics reports runtime totals for operators. It is served without auth, so nothing
+// here may identify a session; see GetSessionMetrics.
+func (h *MetricsHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	perSession, total := h.WSHub.ConnectionCounts()
+
+	utils.SuccessResponse(w, http.StatusOK, map[string]interface{}{
+		"websocket_connections": total,
+		"watched_sessions":      len(perSession),
+	}, "")
+}
+
+// GetSessionMetrics reports how many dashboards are watching each session, keyed by session ID.
+// It belongs behind AdminMiddleware.
+func (h *MetricsHandler) GetSessionMetrics(w http.ResponseWriter, r *http.Request) {
+	perSession, total := h.WSHub.ConnectionCounts()
+
+	utils.SuccessResponse(w, http.StatusOK, map[string]interface{}{
+		"websocket_connections":             total,
+		"websocket_connections_per_session": perSession,
+	}, "")
+}
+
+// GetVersion reports which build is running, including the whatsmeow version for protocol debugging.
+func (h *MetricsHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	utils.SuccessResponse(w, http.StatusOK, version.Get(), "")
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"wago-backend/internal/websocket"
+
+	gorilla "github.com/gorilla/websocket"
+)
+
+// watchedHub returns a running hub with two dashboards watching session-secret-1.
+func watchedHub(t *testing.T) *websocket.Hub {
+	t.Helper()
+	hub := websocket.NewHub(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	go hub.Run()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		websocket.ServeWs(hub, w, r, "session-secret-1", nil, false)
+	}))
+	t.Cleanup(func() {
+		hub.Shutdown()
+		server.Close()
+	})
+
+	for i := 0; i < 2; i++ {
+		conn, _, err := gorilla.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+	}
+	deadline := time.Now().Add(time.Second)
+	for hub.ConnectionCount("session-secret-1") != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("dashboards never registered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return hub
+}
+
+func TestMetricsHideSessionIDs(t *testing.T) {
+	h := NewMetricsHandler(watchedHub(t))
+
+	rec := serve(h.GetMetrics, httptest.NewRequest(http.MethodGet, "/metrics", nil), nil, "")
+	if strings.Contains(rec.Body.String(), "session-secret-1") {
+		t.Fatalf("unauthenticated metrics expose a session ID: %s", rec.Body)
+	}
+	var public struct {
+		Data map[string]int `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &public); err != nil {
+		t.Fatal(err)
+	}
+	if public.Data["websocket_connections"] != 2 || public.Data["watched_sessions"] != 1 {
+		t.Errorf("metrics = %v", public.Data)
+	}
+
+	rec = serve(h.GetSessionMetrics, httptest.NewRequest(http.MethodGet, "/api/v1/admin/metrics", nil), nil, "")
+	var admin struct {
+		Data struct {
+			PerSession map[string]int `json:"websocket_connections_per_session"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &admin); err != nil {
+		t.Fatal(err)
+	}
+	if admin.Data.PerSession["session-secret-1"] != 2 {
+		t.Errorf("admin metrics = %s", rec.Body)
+	}
+}
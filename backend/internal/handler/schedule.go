@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"wago-backend/internal/model"
+	"wago-backend/internal/service"
+	"wago-backend/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+type ScheduleHandler struct {
+	ScheduleService *service.ScheduleService
+	SessionService  *service.SessionService
+}
+
+func NewScheduleHandler(scheduleService *service.ScheduleService, sessionService *service.SessionService) *ScheduleHandler {
+	return &ScheduleHandler{
+		ScheduleService: scheduleService,
+		SessionService:  sessionService,
+	}
+}
+
+// ScheduleMessage queues a text message to be sent at send_at (RFC3339).
+func (h *ScheduleHandler) ScheduleMessage(w http.ResponseWriter, r *http.Request) {
+	session := requireOwnedSession(h.SessionService, w, r)
+	if session == nil {
+		return
+	}
+
+	var req struct {
+		To      string `json:"to"`
+		Message string `json:"message"`
+		SendAt  string `json:"send_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.To) == "" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Recipient is required")
+		return
+	}
+	if !validTemplateBody(req.Message) {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Message is required and must be at most 4096 characters")
+		return
+	}
+	sendAt, err := time.Parse(time.RFC3339, req.SendAt)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "send_at must be an RFC3339 timestamp")
+		return
+	}
+
+	scheduled, err := h.ScheduleService.ScheduleMessage(session.ID, req.To, req.Message, sendAt)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusCreated, scheduled, "Message scheduled successfully")
+}
+
+// ListScheduled lists the session's scheduled messages, optionally filtered by ?status=.
+func (h *ScheduleHandler) ListScheduled(w http.ResponseWriter, r *http.Request) {
+	session := requireOwnedSession(h.SessionService, w, r)
+	if session == nil {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", model.ScheduleStatusPending, model.ScheduleStatusSending, model.ScheduleStatusSent,
+		model.ScheduleStatusFailed, model.ScheduleStatusCancelled:
+	default:
+		utils.ErrorResponse(w, http.StatusBadRequest, "status must be pending, sending, sent, failed or cancelled")
+		return
+	}
+
+	scheduled, err := h.ScheduleService.ListScheduled(session.ID, status)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, scheduled, "Scheduled messages retrieved successfully")
+}
+
+// CancelScheduled cancels a message that has not been sent yet.
+func (h *ScheduleHandler) CancelScheduled(w http.ResponseWriter, r *http.Request) {
+	session := requireOwnedSession(h.SessionService, w, r)
+	if session == nil {
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["schedule_id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid scheduled message id")
+		return
+	}
+
+	scheduled, err := h.ScheduleService.CancelScheduled(session.ID, id)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, scheduled, "Scheduled message cancelled successfully")
+}
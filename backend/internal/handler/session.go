@@ -2,28 +2,42 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 	"wago-backend/internal/config"
+	"wago-backend/internal/errs"
+	"wago-backend/internal/media"
+	"wago-backend/internal/model"
+	"wago-backend/internal/repository"
 	"wago-backend/internal/service"
 	"wago-backend/internal/utils"
 	"wago-backend/internal/websocket"
 
 	"github.com/gorilla/mux"
+	qrcode "github.com/skip2/go-qrcode"
 )
 
 type SessionHandler struct {
 	SessionService *service.SessionService
 	WSHub          *websocket.Hub
 	Config         *config.Config
+	TokenRepo      *repository.TokenRepository // rejects revoked tokens on the websocket, which bypasses AuthMiddleware
 }
 
-func NewSessionHandler(sessionService *service.SessionService, wsHub *websocket.Hub, cfg *config.Config) *SessionHandler {
+func NewSessionHandler(sessionService *service.SessionService, wsHub *websocket.Hub, cfg *config.Config, tokenRepo *repository.TokenRepository) *SessionHandler {
 	return &SessionHandler{
 		SessionService: sessionService,
 		WSHub:          wsHub,
 		Config:         cfg,
+		TokenRepo:      tokenRepo,
 	}
 }
 
@@ -31,8 +45,11 @@ func (h *SessionHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("user_id").(string)
 
 	var req struct {
-		SessionName string `json:"session_name"`
-		WebhookURL  string `json:"webhook_url"`
+		SessionName           string `json:"session_name"`
+		WebhookURL            string `json:"webhook_url"`
+		WebhookRetries        *int   `json:"webhook_retries"`
+		WebhookTimeoutSeconds *int   `json:"webhook_timeout_seconds"`
+		AutoMarkRead          *bool  `json:"auto_mark_read"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
@@ -49,19 +66,67 @@ func (h *SessionHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session, err := h.SessionService.CreateSession(userID, req.SessionName, req.WebhookURL)
+	// Optional settings share the update endpoint's validation; omitted ones keep the column defaults.
+	settings, err := (&sessionUpdateRequest{
+		WebhookRetries:        req.WebhookRetries,
+		WebhookTimeoutSeconds: req.WebhookTimeoutSeconds,
+		AutoMarkRead:          req.AutoMarkRead,
+	}).fields()
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	session, err := h.SessionService.CreateSessionWithSettings(userID, req.SessionName, req.WebhookURL, settings)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
 		return
 	}
 
 	utils.SuccessResponse(w, http.StatusCreated, session, "Session created successfully")
 }
 
+// CloneSession creates a new, unpaired session with the same settings as an existing one.
+func (h *SessionHandler) CloneSession(w http.ResponseWriter, r *http.Request) {
+	source := h.ownedSession(w, r)
+	if source == nil {
+		return
+	}
+
+	var req struct {
+		SessionName string `json:"session_name"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	req.SessionName = strings.TrimSpace(req.SessionName)
+	if len(req.SessionName) > 100 {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid session name")
+		return
+	}
+
+	session, err := h.SessionService.CloneSession(source.UserID, source.ID, req.SessionName)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusCreated, session, "Session cloned successfully")
+}
+
 func (h *SessionHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("user_id").(string)
 
-	sessions, err := h.SessionService.GetSessions(userID)
+	tag := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("tag")))
+	if tag != "" && !utils.ValidTag(tag) {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid tag")
+		return
+	}
+
+	sessions, err := h.SessionService.GetSessions(userID, tag)
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
 		return
@@ -79,7 +144,8 @@ func (h *SessionHandler) StartSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	status, err := h.SessionService.StartSession(id)
+	relink := r.URL.Query().Get("relink") == "true"
+	status, err := h.SessionService.StartSession(id, relink)
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
 		return
@@ -91,6 +157,41 @@ func (h *SessionHandler) StartSession(w http.ResponseWriter, r *http.Request) {
 	}, "Session started")
 }
 
+// pairPhonePattern is an international number without "+", e.g. 628123456789.
+var pairPhonePattern = regexp.MustCompile(`^[1-9][0-9]{6,14}$`)
+
+// PairPhone links an unpaired session by phone number and returns the code to enter on the phone.
+func (h *SessionHandler) PairPhone(w http.ResponseWriter, r *http.Request) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+
+	var req struct {
+		Phone string `json:"phone"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	phone := strings.NewReplacer("+", "", " ", "", "-", "").Replace(strings.TrimSpace(req.Phone))
+	if !pairPhonePattern.MatchString(phone) {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Phone must be an international number with country code, e.g. 628123456789")
+		return
+	}
+
+	code, err := h.SessionService.PairPhone(session.ID, phone)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, map[string]string{
+		"session_id": session.ID,
+		"pair_code":  code,
+	}, "Enter the pairing code on your phone")
+}
+
 func (h *SessionHandler) StopSession(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -119,59 +220,36 @@ func (h *SessionHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
 
 	err := h.SessionService.DeleteSession(id, userID)
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		utils.ErrorFromErr(w, err)
 		return
 	}
 
 	utils.SuccessResponse(w, http.StatusOK, nil, "Session deleted successfully")
 }
 
+// UpdateSession serves both PUT and PATCH /sessions/{id}: only the fields present in the
+// body are validated and written, so unspecified columns are never clobbered.
 func (h *SessionHandler) UpdateSession(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("user_id").(string)
-	vars := mux.Vars(r)
-	id := vars["id"]
-
-	var req struct {
-		SessionName            *string `json:"session_name"`
-		WebhookURL             *string `json:"webhook_url"`
-		IsGroupResponseEnabled *bool   `json:"is_group_response_enabled"`
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
 	}
+
+	var req sessionUpdateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	session, err := h.SessionService.GetSession(id)
+	fields, err := req.fields()
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if session == nil || session.UserID != userID {
-		utils.ErrorResponse(w, http.StatusNotFound, "Session not found")
-		return
-	}
-
-	if req.SessionName != nil {
-		if strings.TrimSpace(*req.SessionName) == "" || len(*req.SessionName) > 100 {
-			utils.ErrorResponse(w, http.StatusBadRequest, "Invalid session name")
-			return
-		}
-		session.SessionName = *req.SessionName
-	}
-	if req.WebhookURL != nil {
-		if _, err := url.ParseRequestURI(*req.WebhookURL); err != nil {
-			utils.ErrorResponse(w, http.StatusBadRequest, "Invalid webhook URL")
-			return
-		}
-		session.WebhookURL = *req.WebhookURL
-	}
-	if req.IsGroupResponseEnabled != nil {
-		session.IsGroupResponseEnabled = *req.IsGroupResponseEnabled
-	}
 
-	err = h.SessionService.UpdateSession(session)
+	session, err = h.SessionService.UpdateSessionFields(session.ID, session.UserID, fields)
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		utils.ErrorFromErr(w, err)
 		return
 	}
 
@@ -189,7 +267,7 @@ func (h *SessionHandler) WebSocketHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	userID, err := utils.ParseUserIDFromToken(token, h.Config.JWTSecret)
+	userID, err := utils.AuthenticateToken(token, h.Config.JWTSecret, h.TokenRepo)
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusUnauthorized, "Invalid token")
 		return
@@ -197,12 +275,25 @@ func (h *SessionHandler) WebSocketHandler(w http.ResponseWriter, r *http.Request
 
 	// Ensure session belongs to user
 	session, err := h.SessionService.GetSession(id)
-	if err != nil || session == nil || session.UserID != userID {
+	if err != nil || session.UserID != userID {
 		utils.ErrorResponse(w, http.StatusForbidden, "Session not accessible")
 		return
 	}
 
-	websocket.ServeWs(h.WSHub, w, r, id, h.Config.AllowedOrigins)
+	// Replay a pending QR code so a page opened after it was generated doesn't wait for the next one.
+	var initial []websocket.Message
+	if qr, ok := h.SessionService.CurrentQRCode(id); ok {
+		initial = append(initial, websocket.Message{
+			Type: "qr_update",
+			Data: map[string]interface{}{
+				"qr_code":    qr.Code,
+				"expires_in": qr.ExpiresIn(time.Now()),
+			},
+			Timestamp: time.Now(),
+		})
+	}
+
+	websocket.ServeWs(h.WSHub, w, r, id, h.Config.AllowedOrigins, h.Config.AllowLocalhostOrigins, initial...)
 }
 
 func (h *SessionHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
@@ -230,19 +321,679 @@ func (h *SessionHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 
 	session, err := h.SessionService.GetSession(id)
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		utils.ErrorFromErr(w, err)
 		return
 	}
-	if session == nil || session.UserID != userID {
-		utils.ErrorResponse(w, http.StatusForbidden, "Session not accessible")
+	if session.UserID != userID {
+		utils.ErrorFromErr(w, errs.ErrForbidden)
 		return
 	}
 
 	err = h.SessionService.SendMessage(id, req.Recipient, req.Message)
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		utils.ErrorFromErr(w, err)
 		return
 	}
 
 	utils.SuccessResponse(w, http.StatusOK, nil, "Message sent successfully")
 }
+
+// SendOutgoingMessage sends a text message from the session and returns its message ID.
+// multipart/form-data requests send a file instead; see sendOutgoingMedia.
+func (h *SessionHandler) SendOutgoingMessage(w http.ResponseWriter, r *http.Request) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		h.sendOutgoingMedia(w, r, session)
+		return
+	}
+
+	var req struct {
+		To      string `json:"to"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.To) == "" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Field to is required")
+		return
+	}
+	if strings.TrimSpace(req.Message) == "" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Message is required")
+		return
+	}
+
+	messageID, err := h.SessionService.SendText(session.ID, req.To, req.Message)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, map[string]interface{}{
+		"session_id": session.ID,
+		"message_id": messageID,
+		"to":         req.To,
+	}, "Message sent successfully")
+}
+
+// sendOutgoingMedia sends the multipart "file" part to "to" with an optional "caption".
+// The MIME type comes from the part header, falling back to content sniffing.
+func (h *SessionHandler) sendOutgoingMedia(w http.ResponseWriter, r *http.Request, session *model.Session) {
+	maxBytes := int64(h.Config.WebhookMaxMediaMB) << 20
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes+1<<20) // room for the other form fields
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid multipart body or file too large")
+		return
+	}
+
+	to := r.FormValue("to")
+	if strings.TrimSpace(to) == "" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Field to is required")
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Field file is required")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Failed to read file")
+		return
+	}
+	if len(data) == 0 {
+		utils.ErrorResponse(w, http.StatusBadRequest, "File is empty")
+		return
+	}
+	if int64(len(data)) > maxBytes {
+		utils.ErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("File exceeds %d MB", h.Config.WebhookMaxMediaMB))
+		return
+	}
+
+	mimeType := header.Header.Get("Content-Type")
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	messageID, messageType, err := h.SessionService.SendMedia(session.ID, to, data, mimeType, header.Filename, r.FormValue("caption"))
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, map[string]interface{}{
+		"session_id":   session.ID,
+		"message_id":   messageID,
+		"message_type": messageType,
+		"to":           to,
+	}, "Media sent successfully")
+}
+
+// ownedSession loads the session from the route and ensures it belongs to the caller.
+// It writes the error response itself and returns nil when the request should stop.
+func (h *SessionHandler) ownedSession(w http.ResponseWriter, r *http.Request) *model.Session {
+	return requireOwnedSession(h.SessionService, w, r)
+}
+
+// requireOwnedSession is ownedSession for handlers that only hold a SessionService.
+func requireOwnedSession(sessions *service.SessionService, w http.ResponseWriter, r *http.Request) *model.Session {
+	id := mux.Vars(r)["id"]
+	userID := r.Context().Value("user_id").(string)
+
+	if strings.TrimSpace(id) == "" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid session id")
+		return nil
+	}
+
+	session, err := sessions.GetSession(id)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return nil
+	}
+	if session.UserID != userID {
+		utils.ErrorFromErr(w, errs.ErrForbidden)
+		return nil
+	}
+	return session
+}
+
+// SendLocation sends a location pin to a recipient.
+func (h *SessionHandler) SendLocation(w http.ResponseWriter, r *http.Request) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+
+	var req struct {
+		Recipient string   `json:"recipient"`
+		Latitude  *float64 `json:"latitude"`
+		Longitude *float64 `json:"longitude"`
+		Name      string   `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if strings.TrimSpace(req.Recipient) == "" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Recipient is required")
+		return
+	}
+	if req.Latitude == nil || req.Longitude == nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Latitude and longitude are required")
+		return
+	}
+	if *req.Latitude < -90 || *req.Latitude > 90 || *req.Longitude < -180 || *req.Longitude > 180 {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Latitude must be within [-90, 90] and longitude within [-180, 180]")
+		return
+	}
+	if len(req.Name) > 256 {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Name is too long")
+		return
+	}
+
+	if err := h.SessionService.SendLocation(session.ID, req.Recipient, *req.Latitude, *req.Longitude, req.Name); err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, nil, "Location sent successfully")
+}
+
+func (h *SessionHandler) RefreshDeviceInfo(w http.ResponseWriter, r *http.Request) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+
+	deviceInfo, err := h.SessionService.RefreshDeviceInfo(session.ID)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, map[string]interface{}{
+		"session_id":  session.ID,
+		"device_info": deviceInfo,
+	}, "Device info refreshed")
+}
+
+// SetPresence marks the session's account online or offline for all chats.
+func (h *SessionHandler) SetPresence(w http.ResponseWriter, r *http.Request) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+
+	var req struct {
+		Available *bool `json:"available"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Available == nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Field available (true/false) is required")
+		return
+	}
+
+	if err := h.SessionService.SetPresence(session.ID, *req.Available); err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, map[string]interface{}{
+		"session_id": session.ID,
+		"available":  *req.Available,
+	}, "Presence updated")
+}
+
+// MuteChat stops the bot from answering a chat; its messages are still logged.
+func (h *SessionHandler) MuteChat(w http.ResponseWriter, r *http.Request) {
+	h.setChatMuted(w, r, true)
+}
+
+// UnmuteChat lets the bot answer a previously muted chat again.
+func (h *SessionHandler) UnmuteChat(w http.ResponseWriter, r *http.Request) {
+	h.setChatMuted(w, r, false)
+}
+
+func (h *SessionHandler) setChatMuted(w http.ResponseWriter, r *http.Request, muted bool) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+
+	var req struct {
+		Chat string `json:"chat"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Chat) == "" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Field chat (phone number or JID) is required")
+		return
+	}
+
+	session, err := h.SessionService.SetChatMuted(session.ID, session.UserID, req.Chat, muted)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	message := "Chat unmuted"
+	if muted {
+		message = "Chat muted"
+	}
+	utils.SuccessResponse(w, http.StatusOK, map[string]interface{}{
+		"session_id":  session.ID,
+		"muted_chats": session.MutedChats,
+	}, message)
+}
+
+// GetUnansweredMessages lists incoming messages from the last ?minutes= (default 60) that got no reply.
+func (h *SessionHandler) GetUnansweredMessages(w http.ResponseWriter, r *http.Request) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+
+	minutes := 60
+	if raw := r.URL.Query().Get("minutes"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > 7*24*60 {
+			utils.ErrorResponse(w, http.StatusBadRequest, "minutes must be between 1 and 10080")
+			return
+		}
+		minutes = parsed
+	}
+
+	messages, err := h.SessionService.UnansweredMessages(session.ID, time.Duration(minutes)*time.Minute)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, messages, "Unanswered messages retrieved successfully")
+}
+
+// parseTimeParam reads an RFC3339 timestamp or a YYYY-MM-DD date (UTC midnight) from the query.
+// endOfDay moves a bare date to the following midnight so it can be used as an exclusive upper bound.
+func parseTimeParam(raw string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// GetMessageHistory pages through the session's message log, newest first. Filters:
+// ?direction=, ?message_type=, ?from= / ?to= (RFC3339, or inclusive YYYY-MM-DD days in UTC);
+// paging with ?limit= (default 50, max 200) and either ?offset= or the ?cursor= from the previous page.
+func (h *SessionHandler) GetMessageHistory(w http.ResponseWriter, r *http.Request) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := model.MessageLogFilter{
+		SessionID:   session.ID,
+		Direction:   query.Get("direction"),
+		MessageType: query.Get("message_type"),
+		Limit:       50,
+	}
+	if filter.Direction != "" && filter.Direction != "incoming" && filter.Direction != "outgoing" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "direction must be incoming or outgoing")
+		return
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > 200 {
+			utils.ErrorResponse(w, http.StatusBadRequest, "limit must be between 1 and 200")
+			return
+		}
+		filter.Limit = limit
+	}
+	if raw := query.Get("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			utils.ErrorResponse(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		filter.Offset = offset
+	}
+	if raw := query.Get("cursor"); raw != "" {
+		if filter.Offset > 0 {
+			utils.ErrorResponse(w, http.StatusBadRequest, "use either cursor or offset, not both")
+			return
+		}
+		cursor, err := model.ParseMessageCursor(raw)
+		if err != nil {
+			utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		filter.Before = &cursor
+	}
+	if raw := query.Get("from"); raw != "" {
+		from, err := parseTimeParam(raw, false)
+		if err != nil {
+			utils.ErrorResponse(w, http.StatusBadRequest, "Invalid from, expected RFC3339 or YYYY-MM-DD")
+			return
+		}
+		filter.From = from
+	}
+	if raw := query.Get("to"); raw != "" {
+		to, err := parseTimeParam(raw, true)
+		if err != nil {
+			utils.ErrorResponse(w, http.StatusBadRequest, "Invalid to, expected RFC3339 or YYYY-MM-DD")
+			return
+		}
+		filter.To = to
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		utils.ErrorResponse(w, http.StatusBadRequest, "to must not be before from")
+		return
+	}
+
+	page, err := h.SessionService.MessageHistory(filter)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, page, "Messages retrieved successfully")
+}
+
+func (h *SessionHandler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+
+	groups, err := h.SessionService.ListGroups(session.ID)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, groups, "Groups retrieved successfully")
+}
+
+// GetContactDirectory lists the session's contacts with their WhatsApp display names.
+// ?pictures=true also looks up profile picture URLs, which needs a connected session.
+func (h *SessionHandler) GetContactDirectory(w http.ResponseWriter, r *http.Request) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+
+	withPictures, _ := strconv.ParseBool(r.URL.Query().Get("pictures"))
+	contacts, err := h.SessionService.ContactDirectory(session.ID, withPictures)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+	if contacts == nil {
+		contacts = []model.Contact{}
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, contacts, "Contacts retrieved successfully")
+}
+
+// ListGroupSettings lists the groups whose response settings differ from the session default.
+func (h *SessionHandler) ListGroupSettings(w http.ResponseWriter, r *http.Request) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+
+	settings, err := h.SessionService.ListGroupSettings(session.ID)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, settings, "Group settings retrieved successfully")
+}
+
+// SetGroupSetting enables or disables responses in one group and whether they need a mention.
+func (h *SessionHandler) SetGroupSetting(w http.ResponseWriter, r *http.Request) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+
+	var req struct {
+		Enabled     *bool `json:"enabled"`
+		MentionOnly *bool `json:"mention_only"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Enabled == nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Field enabled is required")
+		return
+	}
+	mentionOnly := true
+	if req.MentionOnly != nil {
+		mentionOnly = *req.MentionOnly
+	}
+
+	setting, err := h.SessionService.SetGroupSetting(session.ID, mux.Vars(r)["jid"], *req.Enabled, mentionOnly)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, setting, "Group setting saved")
+}
+
+// ResetGroupSetting removes a group's override, returning it to the session default.
+func (h *SessionHandler) ResetGroupSetting(w http.ResponseWriter, r *http.Request) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+
+	if err := h.SessionService.ResetGroupSetting(session.ID, mux.Vars(r)["jid"]); err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, nil, "Group setting removed")
+}
+
+// ListNewsletters lists the channels the session's account follows.
+func (h *SessionHandler) ListNewsletters(w http.ResponseWriter, r *http.Request) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+
+	newsletters, err := h.SessionService.ListNewsletters(session.ID)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, newsletters, "Newsletters retrieved successfully")
+}
+
+// GetNewsletter returns a channel's metadata by its JID.
+func (h *SessionHandler) GetNewsletter(w http.ResponseWriter, r *http.Request) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+
+	newsletter, err := h.SessionService.GetNewsletter(session.ID, mux.Vars(r)["jid"])
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, newsletter, "Newsletter retrieved successfully")
+}
+
+// FollowNewsletter subscribes the session's account to a channel.
+func (h *SessionHandler) FollowNewsletter(w http.ResponseWriter, r *http.Request) {
+	h.setNewsletterFollowed(w, r, true)
+}
+
+// UnfollowNewsletter unsubscribes the session's account from a channel.
+func (h *SessionHandler) UnfollowNewsletter(w http.ResponseWriter, r *http.Request) {
+	h.setNewsletterFollowed(w, r, false)
+}
+
+func (h *SessionHandler) setNewsletterFollowed(w http.ResponseWriter, r *http.Request, follow bool) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+
+	jid := mux.Vars(r)["jid"]
+	if err := h.SessionService.SetNewsletterFollowed(session.ID, jid, follow); err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	message := "Newsletter unfollowed"
+	if follow {
+		message = "Newsletter followed"
+	}
+	utils.SuccessResponse(w, http.StatusOK, map[string]interface{}{
+		"session_id": session.ID,
+		"jid":        jid,
+		"following":  follow,
+	}, message)
+}
+
+// GetMedia serves media stored for one of the session's incoming messages.
+func (h *SessionHandler) GetMedia(w http.ResponseWriter, r *http.Request) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+
+	messageID := mux.Vars(r)["message_id"]
+	if !mediaIDPattern.MatchString(messageID) {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid message id")
+		return
+	}
+
+	data, contentType, err := h.SessionService.GetMedia(session.ID, messageID)
+	if errors.Is(err, media.ErrNotFound) {
+		utils.ErrorResponse(w, http.StatusNotFound, "Media not found")
+		return
+	}
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	// The stored type comes from the sender, so browsers must not sniff or render anything
+	// but plain media from our origin.
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", mediaDisposition(contentType))
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// mediaIDPattern matches WhatsApp message IDs and keeps them safe to use as storage keys.
+var mediaIDPattern = regexp.MustCompile(`^[A-Za-z0-9]{1,64}$`)
+
+// mediaDisposition shows images, video and audio inline and downloads everything else.
+// SVG is downloaded too, since it can carry script.
+func mediaDisposition(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "image/svg+xml" {
+		return "attachment"
+	}
+	switch strings.SplitN(mediaType, "/", 2)[0] {
+	case "image", "video", "audio":
+		return "inline"
+	}
+	return "attachment"
+}
+
+// GetSessionStatus merges the persisted session status with the live client state, so
+// drift between the two (e.g. the row says connected but the socket is down) is visible.
+func (h *SessionHandler) GetSessionStatus(w http.ResponseWriter, r *http.Request) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+
+	live := h.SessionService.ClientStatus(session.ID)
+	dbConnected := session.Status == model.SessionStatusConnected
+	utils.SuccessResponse(w, http.StatusOK, map[string]interface{}{
+		"session_id":       session.ID,
+		"db_status":        session.Status,
+		"client_loaded":    live.Loaded,
+		"connected":        live.Connected,
+		"logged_in":        live.LoggedIn,
+		"jid":              live.JID,
+		"last_event_at":    live.LastEventAt,
+		"last_activity_at": session.LastActivity,
+		"status_mismatch":  dbConnected != (live.Connected && live.LoggedIn),
+	}, "Session status retrieved")
+}
+
+// GetQRCode returns the session's pending QR string, for pages opened after it was pushed over the websocket.
+func (h *SessionHandler) GetQRCode(w http.ResponseWriter, r *http.Request) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+
+	qr, ok := h.SessionService.CurrentQRCode(session.ID)
+	if !ok || qr.Code == "" {
+		utils.ErrorResponse(w, http.StatusNotFound, "No QR code available")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.SuccessResponse(w, http.StatusOK, map[string]interface{}{
+		"qr_code":    qr.Code,
+		"expires_at": qr.ExpiresAt,
+		"expires_in": qr.ExpiresIn(time.Now()),
+	}, "QR code retrieved")
+}
+
+// GetQRCodePNG renders the session's pending QR code server-side for clients that can't draw it.
+func (h *SessionHandler) GetQRCodePNG(w http.ResponseWriter, r *http.Request) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+
+	qr, ok := h.SessionService.CurrentQRCode(session.ID)
+	if !ok || qr.Code == "" {
+		utils.ErrorResponse(w, http.StatusNotFound, "No QR code available")
+		return
+	}
+
+	png, err := qrcode.Encode(qr.Code, qrcode.Medium, 256)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to render QR code")
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(png)
+}
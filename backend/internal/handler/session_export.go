@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"wago-backend/internal/model"
+	"wago-backend/internal/utils"
+)
+
+// sessionImportRequest is an exported session document. Settings are validated exactly
+// like a session update; unknown settings are ignored so newer exports still import.
+type sessionImportRequest struct {
+	Version     int                  `json:"version"`
+	SessionName string               `json:"session_name"`
+	WebhookURL  string               `json:"webhook_url"`
+	Settings    sessionUpdateRequest `json:"settings"`
+}
+
+// ExportSession downloads the session's configuration as a JSON document for ImportSession.
+func (h *SessionHandler) ExportSession(w http.ResponseWriter, r *http.Request) {
+	session := h.ownedSession(w, r)
+	if session == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="session-%s.json"`, session.ID))
+	json.NewEncoder(w).Encode(h.SessionService.ExportSession(session))
+}
+
+// ImportSession creates a new, unpaired session from an exported configuration.
+func (h *SessionHandler) ImportSession(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	var req sessionImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Version != model.SessionExportVersion {
+		utils.ErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Unsupported export version %d", req.Version))
+		return
+	}
+	if strings.TrimSpace(req.SessionName) == "" || len(req.SessionName) > 100 {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid session name")
+		return
+	}
+	if _, err := url.ParseRequestURI(req.WebhookURL); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid webhook URL")
+		return
+	}
+
+	settings, err := req.Settings.fields()
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Name and URL come from the document itself; secrets are never part of an export.
+	delete(settings, "session_name")
+	delete(settings, "webhook_url")
+	delete(settings, "webhook_secret")
+
+	session, err := h.SessionService.ImportSession(userID, req.SessionName, req.WebhookURL, settings)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusCreated, session, "Session imported successfully")
+}
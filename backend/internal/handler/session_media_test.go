@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wago-backend/internal/config"
+	"wago-backend/internal/media"
+	"wago-backend/internal/service"
+	"wago-backend/internal/whatsapp"
+)
+
+// stubMediaStore serves one stored blob for every key.
+type stubMediaStore struct {
+	data        []byte
+	contentType string
+}
+
+func (s stubMediaStore) Put(context.Context, string, []byte, string) error { return nil }
+
+func (s stubMediaStore) Get(context.Context, string) ([]byte, string, error) {
+	return s.data, s.contentType, nil
+}
+
+func TestGetMediaHeaders(t *testing.T) {
+	cases := []struct {
+		name            string
+		contentType     string
+		data            string
+		wantType        string
+		wantDisposition string
+	}{
+		{"image", "image/jpeg", "\xff\xd8\xff\xe0", "image/jpeg", "inline"},
+		{"voice note", "audio/ogg; codecs=opus", "OggS", "audio/ogg; codecs=opus", "inline"},
+		{"video", "video/mp4", "\x00\x00\x00\x18ftypmp42", "video/mp4", "inline"},
+		{"html", "text/html", "<script>alert(1)</script>", "text/html", "attachment"},
+		{"svg", "image/svg+xml", "<svg onload=alert(1)>", "image/svg+xml", "attachment"},
+		{"document", "application/pdf", "%PDF-1.7", "application/pdf", "attachment"},
+		{"unparseable", "image/", "data", "image/", "attachment"},
+		{"sniffed", "", "<html><body>", "text/html; charset=utf-8", "attachment"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mock := newMockDB(t)
+			expectSessionLookup(mock, "s1", "user-1")
+			clients := &whatsapp.ClientManager{MediaStore: stubMediaStore{[]byte(tc.data), tc.contentType}}
+			h := &SessionHandler{SessionService: service.NewSessionService(repo, nil, nil, clients, &config.Config{})}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/sessions/s1/media/ABC123", nil)
+			rec := serve(h.GetMedia, req, map[string]string{"id": "s1", "message_id": "ABC123"}, "user-1")
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			header := rec.Header()
+			if got := header.Get("Content-Type"); got != tc.wantType {
+				t.Errorf("Content-Type = %q, want %q", got, tc.wantType)
+			}
+			if got := header.Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("X-Content-Type-Options = %q, want nosniff", got)
+			}
+			if got := header.Get("Content-Disposition"); got != tc.wantDisposition {
+				t.Errorf("Content-Disposition = %q, want %q", got, tc.wantDisposition)
+			}
+		})
+	}
+}
+
+var _ media.MediaStore = stubMediaStore{}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"wago-backend/internal/config"
+	"wago-backend/internal/model"
+	"wago-backend/internal/repository"
+	"wago-backend/internal/service"
+	"wago-backend/internal/whatsapp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUpdateSessionOwnership(t *testing.T) {
+	cases := []struct {
+		name   string
+		expect func(mock sqlmock.Sqlmock)
+		want   int
+	}{
+		{"another user's session", func(m sqlmock.Sqlmock) { expectSessionLookup(m, "s1", "user-2") }, http.StatusForbidden},
+		{"missing session", func(m sqlmock.Sqlmock) { expectMissingSession(m, "s1") }, http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mock := newMockDB(t)
+			tc.expect(mock)
+			h := &SessionHandler{SessionService: newSessionService(repo)}
+
+			// No update is expected: a write would fail the mock.
+			req := httptest.NewRequest(http.MethodPut, "/api/sessions/s1", strings.NewReader(`{"dry_run": true}`))
+			rec := serve(h.UpdateSession, req, map[string]string{"id": "s1"}, "user-1")
+			if rec.Code != tc.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tc.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestDeleteSession(t *testing.T) {
+	cases := []struct {
+		name   string
+		owner  string
+		delete bool // the DELETE runs
+		rows   int64
+		want   int
+	}{
+		{"own session", "user-1", true, 1, http.StatusOK},
+		{"another user's session", "user-2", false, 0, http.StatusForbidden},
+		{"deleted concurrently", "user-1", true, 0, http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mock := newMockDB(t)
+			audit := repository.NewAuditRepository(repo.DB)
+			h := &SessionHandler{SessionService: service.NewSessionService(repo, nil, audit, &whatsapp.ClientManager{}, &config.Config{})}
+
+			expectSessionLookup(mock, "s1", tc.owner)
+			if tc.delete {
+				mock.ExpectExec("DELETE FROM sessions").WithArgs("s1", "user-1").WillReturnResult(sqlmock.NewResult(0, tc.rows))
+			}
+			// Only a real delete is audited; an unexpected INSERT fails the mock.
+			if tc.rows == 1 {
+				mock.ExpectExec("INSERT INTO audit_log").
+					WithArgs("user-1", model.AuditSessionDeleted, "s1").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/sessions/s1", nil)
+			rec := serve(h.DeleteSession, req, map[string]string{"id": "s1"}, "user-1")
+			if rec.Code != tc.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tc.want, rec.Body)
+			}
+		})
+	}
+}
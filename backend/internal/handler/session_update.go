@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"wago-backend/internal/model"
+	"wago-backend/internal/utils"
+	"wago-backend/internal/webhook"
+)
+
+// sessionUpdateRequest is the partial body accepted by PUT/PATCH /sessions/{id}.
+// Only fields present in the JSON are validated and written.
+type sessionUpdateRequest struct {
+	SessionName            *string   `json:"session_name"`
+	WebhookURL             *string   `json:"webhook_url"`
+	WebhookSecret          *string   `json:"webhook_secret"`
+	IsGroupResponseEnabled *bool     `json:"is_group_response_enabled"`
+	BusyReplyText          *string   `json:"busy_reply_text"`
+	BusyReplyGraceMs       *int      `json:"busy_reply_grace_ms"`
+	DryRun                 *bool     `json:"dry_run"`
+	ReplyPrivatelyInGroups *bool     `json:"reply_privately_in_groups"`
+	TriggerPattern         *string   `json:"trigger_pattern"`
+	MarkReadOnSuccess      *bool     `json:"mark_read_on_success"`
+	WebhookFormat          *string   `json:"webhook_format"`
+	WebhookMethod          *string   `json:"webhook_method"`
+	Tags                   *[]string `json:"tags"`
+	WebhookIncludeFields   *[]string `json:"webhook_include_fields"`
+	WebhookExcludeFields   *[]string `json:"webhook_exclude_fields"`
+	IngestHistory          *bool     `json:"ingest_history"`
+	ProcessOwnMessages     *bool     `json:"process_own_messages"`
+	ReplyFooter            *string   `json:"reply_footer"`
+	WebhookStream          *bool     `json:"webhook_stream"`
+	WebhookRetries         *int      `json:"webhook_retries"`
+	WebhookTimeoutSeconds  *int      `json:"webhook_timeout_seconds"`
+	AutoMarkRead           *bool     `json:"auto_mark_read"`
+	WebhookStatusEvents    *bool     `json:"webhook_status_events"`
+	QuoteReplies           *bool     `json:"quote_replies"`
+	TypingDelay            *bool     `json:"typing_delay"`
+}
+
+// fields validates the provided values and returns them keyed by column name.
+func (req *sessionUpdateRequest) fields() (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+
+	if req.SessionName != nil {
+		if strings.TrimSpace(*req.SessionName) == "" || len(*req.SessionName) > 100 {
+			return nil, errors.New("Invalid session name")
+		}
+		fields["session_name"] = *req.SessionName
+	}
+	if req.WebhookURL != nil {
+		if _, err := url.ParseRequestURI(*req.WebhookURL); err != nil {
+			return nil, errors.New("Invalid webhook URL")
+		}
+		fields["webhook_url"] = *req.WebhookURL
+	}
+	if req.WebhookSecret != nil {
+		if len(*req.WebhookSecret) > 256 {
+			return nil, errors.New("Webhook secret is too long")
+		}
+		fields["webhook_secret"] = *req.WebhookSecret
+	}
+	if req.IsGroupResponseEnabled != nil {
+		fields["is_group_response_enabled"] = *req.IsGroupResponseEnabled
+	}
+	if req.BusyReplyText != nil {
+		if len(*req.BusyReplyText) > 1000 {
+			return nil, errors.New("Busy reply text is too long")
+		}
+		fields["busy_reply_text"] = strings.TrimSpace(*req.BusyReplyText)
+	}
+	if req.BusyReplyGraceMs != nil {
+		if *req.BusyReplyGraceMs < 0 || *req.BusyReplyGraceMs > 30000 {
+			return nil, errors.New("Busy reply grace must be between 0 and 30000 ms")
+		}
+		fields["busy_reply_grace_ms"] = *req.BusyReplyGraceMs
+	}
+	if req.DryRun != nil {
+		fields["dry_run"] = *req.DryRun
+	}
+	if req.ReplyPrivatelyInGroups != nil {
+		fields["reply_privately_in_groups"] = *req.ReplyPrivatelyInGroups
+	}
+	if req.TriggerPattern != nil {
+		if len(*req.TriggerPattern) > 200 {
+			return nil, errors.New("Trigger pattern is too long")
+		}
+		if _, err := utils.CompileTriggerPattern(*req.TriggerPattern); err != nil {
+			return nil, errors.New("Invalid trigger pattern: " + err.Error())
+		}
+		fields["trigger_pattern"] = *req.TriggerPattern
+	}
+	if req.MarkReadOnSuccess != nil {
+		fields["mark_read_on_success"] = *req.MarkReadOnSuccess
+	}
+	if req.WebhookFormat != nil {
+		switch *req.WebhookFormat {
+		case model.WebhookFormatJSON, model.WebhookFormatForm, model.WebhookFormatArgo:
+			fields["webhook_format"] = *req.WebhookFormat
+		default:
+			return nil, errors.New("Webhook format must be json, form or argo")
+		}
+	}
+	if req.WebhookMethod != nil {
+		method := strings.ToUpper(strings.TrimSpace(*req.WebhookMethod))
+		if !model.AllowedWebhookMethods[method] {
+			return nil, errors.New("Webhook method must be POST or PUT")
+		}
+		fields["webhook_method"] = method
+	}
+	if req.Tags != nil {
+		tags, err := utils.NormalizeTags(*req.Tags)
+		if err != nil {
+			return nil, err
+		}
+		fields["tags"] = model.StringList(tags)
+	}
+	if req.WebhookIncludeFields != nil {
+		list, err := webhook.ValidatePayloadFields(*req.WebhookIncludeFields)
+		if err != nil {
+			return nil, err
+		}
+		fields["webhook_include_fields"] = model.StringList(list)
+	}
+	if req.WebhookExcludeFields != nil {
+		list, err := webhook.ValidatePayloadFields(*req.WebhookExcludeFields)
+		if err != nil {
+			return nil, err
+		}
+		fields["webhook_exclude_fields"] = model.StringList(list)
+	}
+	if req.IngestHistory != nil {
+		fields["ingest_history"] = *req.IngestHistory
+	}
+	if req.ProcessOwnMessages != nil {
+		fields["process_own_messages"] = *req.ProcessOwnMessages
+	}
+	if req.ReplyFooter != nil {
+		if len(*req.ReplyFooter) > 200 {
+			return nil, errors.New("Reply footer is too long")
+		}
+		fields["reply_footer"] = strings.TrimSpace(*req.ReplyFooter)
+	}
+	if req.WebhookStream != nil {
+		fields["webhook_stream"] = *req.WebhookStream
+	}
+	if req.WebhookRetries != nil {
+		if *req.WebhookRetries < 0 || *req.WebhookRetries > 10 {
+			return nil, errors.New("Webhook retries must be between 0 and 10")
+		}
+		fields["webhook_retries"] = *req.WebhookRetries
+	}
+	if req.WebhookTimeoutSeconds != nil {
+		if *req.WebhookTimeoutSeconds < 1 || *req.WebhookTimeoutSeconds > 120 {
+			return nil, errors.New("Webhook timeout must be between 1 and 120 seconds")
+		}
+		fields["webhook_timeout_seconds"] = *req.WebhookTimeoutSeconds
+	}
+	if req.AutoMarkRead != nil {
+		fields["auto_mark_read"] = *req.AutoMarkRead
+	}
+	if req.WebhookStatusEvents != nil {
+		fields["webhook_status_events"] = *req.WebhookStatusEvents
+	}
+	if req.QuoteReplies != nil {
+		fields["quote_replies"] = *req.QuoteReplies
+	}
+	if req.TypingDelay != nil {
+		fields["typing_delay"] = *req.TypingDelay
+	}
+
+	return fields, nil
+}
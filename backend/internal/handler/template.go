@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"wago-backend/internal/service"
+	"wago-backend/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+var templateNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// maxTemplateBodyLength keeps templates within WhatsApp's text message limit.
+const maxTemplateBodyLength = 4096
+
+type TemplateHandler struct {
+	TemplateService *service.TemplateService
+	SessionService  *service.SessionService
+}
+
+func NewTemplateHandler(templateService *service.TemplateService, sessionService *service.SessionService) *TemplateHandler {
+	return &TemplateHandler{
+		TemplateService: templateService,
+		SessionService:  sessionService,
+	}
+}
+
+func validTemplateBody(body string) bool {
+	return strings.TrimSpace(body) != "" && len(body) <= maxTemplateBodyLength
+}
+
+func (h *TemplateHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	session := requireOwnedSession(h.SessionService, w, r)
+	if session == nil {
+		return
+	}
+
+	templates, err := h.TemplateService.ListTemplates(session.ID)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, templates, "Templates retrieved successfully")
+}
+
+func (h *TemplateHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	session := requireOwnedSession(h.SessionService, w, r)
+	if session == nil {
+		return
+	}
+
+	template, err := h.TemplateService.GetTemplate(session.ID, mux.Vars(r)["name"])
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, template, "Template retrieved successfully")
+}
+
+func (h *TemplateHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	session := requireOwnedSession(h.SessionService, w, r)
+	if session == nil {
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !templateNamePattern.MatchString(req.Name) {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Template name must be lowercase letters, digits, \"-\" or \"_\", up to 64 characters")
+		return
+	}
+	if !validTemplateBody(req.Body) {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Template body is required and must be at most 4096 characters")
+		return
+	}
+
+	template, err := h.TemplateService.CreateTemplate(session.ID, req.Name, req.Body)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusCreated, template, "Template created successfully")
+}
+
+func (h *TemplateHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	session := requireOwnedSession(h.SessionService, w, r)
+	if session == nil {
+		return
+	}
+
+	var req struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !validTemplateBody(req.Body) {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Template body is required and must be at most 4096 characters")
+		return
+	}
+
+	template, err := h.TemplateService.UpdateTemplate(session.ID, mux.Vars(r)["name"], req.Body)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, template, "Template updated successfully")
+}
+
+func (h *TemplateHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	session := requireOwnedSession(h.SessionService, w, r)
+	if session == nil {
+		return
+	}
+
+	if err := h.TemplateService.DeleteTemplate(session.ID, mux.Vars(r)["name"]); err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, nil, "Template deleted successfully")
+}
+
+// SendTemplate renders a template for a recipient and sends it.
+func (h *TemplateHandler) SendTemplate(w http.ResponseWriter, r *http.Request) {
+	session := requireOwnedSession(h.SessionService, w, r)
+	if session == nil {
+		return
+	}
+
+	var req struct {
+		Recipient string            `json:"recipient"`
+		Variables map[string]string `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Recipient) == "" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Recipient is required")
+		return
+	}
+
+	text, err := h.TemplateService.SendTemplate(session.ID, mux.Vars(r)["name"], req.Recipient, req.Variables)
+	if err != nil {
+		utils.ErrorFromErr(w, err)
+		return
+	}
+
+	utils.SuccessResponse(w, http.StatusOK, map[string]interface{}{
+		"session_id": session.ID,
+		"message":    text,
+	}, "Message sent successfully")
+}
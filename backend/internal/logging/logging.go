@@ -0,0 +1,26 @@
+// Package logging builds the structured logger shared by the WhatsApp client manager,
+// the webhook service and the websocket hub.
+package logging
+
+import (
+	"log/slog"
+	"os"
+)
+
+// New returns a JSON logger on stdout at level, which takes the LOG_LEVEL values
+// (DEBUG, INFO, WARN, ERROR). Unknown levels fall back to INFO.
+func New(level string) *slog.Logger {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		lvl = slog.LevelInfo
+	}
+	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: lvl}))
+}
+
+// OrDefault returns logger, or slog's default logger when it is nil.
+func OrDefault(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
@@ -0,0 +1,37 @@
+// Package media persists message media behind a MediaStore so deployments can keep it
+// on local disk or offload it to S3-compatible object storage without changing callers.
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"wago-backend/internal/config"
+)
+
+// ErrNotFound is returned by Get when no object exists for the key.
+var ErrNotFound = errors.New("media not found")
+
+// MediaStore stores media blobs by key (e.g. "<session_id>/<message_id>").
+type MediaStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (data []byte, contentType string, err error)
+}
+
+// New builds the store selected by MEDIA_STORE ("local" or "s3").
+func New(cfg *config.Config) (MediaStore, error) {
+	switch cfg.MediaStore {
+	case "", "local":
+		return NewLocalStore(cfg.MediaDir)
+	case "s3":
+		return NewS3Store(S3Config{
+			Endpoint:  cfg.MediaS3Endpoint,
+			Region:    cfg.MediaS3Region,
+			Bucket:    cfg.MediaS3Bucket,
+			AccessKey: cfg.MediaS3AccessKey,
+			SecretKey: cfg.MediaS3SecretKey,
+		})
+	default:
+		return nil, fmt.Errorf("unknown MEDIA_STORE %q (want local or s3)", cfg.MediaStore)
+	}
+}
@@ -0,0 +1,55 @@
+// Package mention decides whether a WhatsApp message mentions the bot's account.
+package mention
+
+import (
+	"strings"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// Mentioned reports whether any target is mentioned, either in the explicit mention lists of
+// contexts or as "@<user>" in text. Targets are typically the account's phone JID and its LID;
+// only the user part is compared, so the server (s.whatsapp.net or lid) and device don't matter.
+// Users are compared whole: "123" does not match a mention of "1234".
+func Mentioned(contexts []*waE2E.ContextInfo, text string, targets []types.JID) bool {
+	users := make(map[string]bool, len(targets))
+	for _, jid := range targets {
+		if jid.User != "" {
+			users[jid.User] = true
+		}
+	}
+	if len(users) == 0 {
+		return false
+	}
+
+	for _, ctx := range contexts {
+		for _, mentioned := range ctx.GetMentionedJID() {
+			if jid, err := types.ParseJID(mentioned); err == nil && users[jid.User] {
+				return true
+			}
+		}
+	}
+
+	for user := range users {
+		if mentionedInText(text, user) {
+			return true
+		}
+	}
+	return false
+}
+
+// mentionedInText looks for "@<user>" not followed by another digit.
+func mentionedInText(text, user string) bool {
+	token := "@" + user
+	for rest := text; ; {
+		i := strings.Index(rest, token)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(token):]
+		if rest == "" || rest[0] < '0' || rest[0] > '9' {
+			return true
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package mention
+
+import (
+	"testing"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMentioned(t *testing.T) {
+	phone := types.NewADJID("628999999999", 0, 12) // the account's own JID carries a device
+	lid := types.NewJID("123456789012345", types.HiddenUserServer)
+	targets := []types.JID{phone, lid}
+
+	mentions := func(jids ...string) []*waE2E.ContextInfo {
+		return []*waE2E.ContextInfo{{MentionedJID: jids}}
+	}
+	// quoting wraps a context quoting a message that itself mentioned the account.
+	quoting := func(quotedText string, quotedMentions ...string) []*waE2E.ContextInfo {
+		return []*waE2E.ContextInfo{{
+			StanzaID:    proto.String("quoted-1"),
+			Participant: proto.String("628111111111@s.whatsapp.net"),
+			QuotedMessage: &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+				Text:        proto.String(quotedText),
+				ContextInfo: &waE2E.ContextInfo{MentionedJID: quotedMentions},
+			}},
+		}}
+	}
+
+	cases := []struct {
+		name     string
+		contexts []*waE2E.ContextInfo
+		text     string
+		targets  []types.JID
+		want     bool
+	}{
+		{"phone JID mention", mentions("628999999999@s.whatsapp.net"), "hi", targets, true},
+		{"LID mention", mentions("123456789012345@lid"), "hi", targets, true},
+		{"LID mention without LID target", mentions("123456789012345@lid"), "hi", []types.JID{phone}, false},
+		{"phone mention with device suffix", mentions("628999999999:3@s.whatsapp.net"), "", targets, true},
+		{"someone else mentioned", mentions("628111111111@s.whatsapp.net"), "@628111111111 hi", targets, false},
+		{"unparseable mention", mentions("not a jid@@"), "", targets, false},
+		{"nil context", []*waE2E.ContextInfo{nil}, "hello", targets, false},
+
+		{"@number in caption", nil, "see photo @628999999999", targets, true},
+		{"@LID in caption", nil, "@123456789012345, thoughts?", targets, true},
+		{"@number at end of text", nil, "thanks @628999999999", targets, true},
+		{"@number followed by punctuation", nil, "@628999999999: status?", targets, true},
+
+		{"quoted message mentioned us", quoting("@628999999999 ping", "628999999999@s.whatsapp.net"), "replying", targets, false},
+		{"reply mentions us while quoting", append(quoting("earlier"), mentions("628999999999@s.whatsapp.net")...), "@628999999999", targets, true},
+
+		{"shorter number is not a prefix match", nil, "@6289999999991 hi", targets, false},
+		{"123 vs 1234", nil, "@1234", []types.JID{types.NewJID("123", types.DefaultUserServer)}, false},
+		{"1234 vs 123", nil, "@123 and @12", []types.JID{types.NewJID("1234", types.DefaultUserServer)}, false},
+		{"later exact match after a longer one", nil, "@1234 @123", []types.JID{types.NewJID("123", types.DefaultUserServer)}, true},
+		{"123 mentioned in list", mentions("1234@s.whatsapp.net"), "", []types.JID{types.NewJID("123", types.DefaultUserServer)}, false},
+
+		{"number without @", nil, "call 628999999999", targets, false},
+		{"no targets", mentions("628999999999@s.whatsapp.net"), "@628999999999", nil, false},
+		{"empty target user", nil, "@", []types.JID{types.EmptyJID}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Mentioned(tc.contexts, tc.text, tc.targets); got != tc.want {
+				t.Errorf("Mentioned(%q) = %v, want %v", tc.text, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Package message renders {{variable}} placeholders in outgoing text: message templates,
+// broadcasts and the session's automatic replies.
+package message
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// placeholderPattern matches {{name}}, allowing whitespace inside the braces. A leading
+// backslash is captured so escaped placeholders can be told apart.
+var placeholderPattern = regexp.MustCompile(`(\\?)\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+// Render replaces {{name}} placeholders with vars[name]. Placeholders without a value are
+// left in place so missing data is visible. \{{name}} is an escape and renders as the
+// literal {{name}}. Braces beyond a pair are kept, so {{{name}}} renders as {value}.
+func Render(text string, vars map[string]string) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		match := placeholderPattern.FindStringSubmatch(placeholder)
+		if match[1] != "" {
+			return placeholder[1:]
+		}
+		if value, ok := vars[match[2]]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
+
+// Variables lists the distinct placeholder names used in text, in order of appearance.
+// Escaped placeholders are not variables.
+func Variables(text string) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, match := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+		name := match[2]
+		if match[1] == "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// RecipientVars returns the built-in variables for a message to phone: phone, date and
+// time (now's location), and name when the contact's name is known.
+func RecipientVars(phone, name string, now time.Time) map[string]string {
+	vars := map[string]string{
+		"phone": phone,
+		"date":  now.Format("2006-01-02"),
+		"time":  now.Format("15:04"),
+	}
+	if name != "" {
+		vars["name"] = name
+	}
+	return vars
+}
@@ -0,0 +1,71 @@
+package message
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	vars := map[string]string{"name": "Ann", "order": "A-17", "empty": "", "quote": "{{name}}"}
+	cases := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"no placeholders", "Hello there", "Hello there"},
+		{"single", "Hi {{name}}!", "Hi Ann!"},
+		{"whitespace inside braces", "Hi {{ name }}, order {{order}}", "Hi Ann, order A-17"},
+		{"repeated", "{{name}} / {{name}}", "Ann / Ann"},
+		{"empty value", "[{{empty}}]", "[]"},
+		{"missing variable is kept", "Hi {{nickname}}", "Hi {{nickname}}"},
+		{"missing next to known", "{{name}} {{unknown}}", "Ann {{unknown}}"},
+		{"escaped", `Use \{{name}} to insert a name`, "Use {{name}} to insert a name"},
+		{"escaped and rendered", `\{{name}} is {{name}}`, "{{name}} is Ann"},
+		{"escaped missing", `\{{nickname}}`, "{{nickname}}"},
+		{"nested braces", "{{{name}}}", "{Ann}"},
+		{"nested braces missing", "{{{nickname}}}", "{{{nickname}}}"},
+		{"single braces untouched", "{name}", "{name}"},
+		{"unclosed", "Hi {{name", "Hi {{name"},
+		{"invalid name", "{{first name}}", "{{first name}}"},
+		{"value containing a placeholder is not expanded", "{{quote}}", "{{name}}"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Render(tc.text, vars); got != tc.want {
+				t.Errorf("Render(%q) = %q, want %q", tc.text, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestVariables(t *testing.T) {
+	cases := []struct {
+		text string
+		want []string
+	}{
+		{"no placeholders", nil},
+		{"{{name}} ordered {{ order }}, thanks {{name}}", []string{"name", "order"}},
+		{`\{{name}} costs {{price}}`, []string{"price"}},
+		{"{{{name}}}", []string{"name"}},
+	}
+	for _, tc := range cases {
+		if got := Variables(tc.text); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Variables(%q) = %v, want %v", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestRecipientVars(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	now := time.Date(2025, 1, 15, 23, 30, 0, 0, time.UTC).In(jakarta)
+
+	got := RecipientVars("628111111111", "", now)
+	want := map[string]string{"phone": "628111111111", "date": "2025-01-16", "time": "06:30"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RecipientVars = %v, want %v", got, want)
+	}
+	if got := RecipientVars("628111111111", "Ann", now); got["name"] != "Ann" {
+		t.Errorf("name = %q, want Ann", got["name"])
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
 	"net/http"
 	"strings"
@@ -10,19 +11,27 @@ import (
 	"wago-backend/internal/utils"
 
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Middleware struct {
-	Config       *config.Config
-	UserRepo     *repository.UserRepository
-	rateLimiters sync.Map
+	Config    *config.Config
+	UserRepo  *repository.UserRepository
+	TokenRepo *repository.TokenRepository
+
+	// rateLimiters maps a rate limit key (see rateLimitKey) to its *limiter.
+	rateLimiters   sync.Map
+	lastSweep      atomic.Int64 // unix nanos of the last stale-limiter sweep
+	trustedProxies utils.TrustedProxies
 }
 
-func NewMiddleware(cfg *config.Config, userRepo *repository.UserRepository) *Middleware {
+func NewMiddleware(cfg *config.Config, userRepo *repository.UserRepository, tokenRepo *repository.TokenRepository) *Middleware {
 	return &Middleware{
-		Config:   cfg,
-		UserRepo: userRepo,
+		Config:         cfg,
+		UserRepo:       userRepo,
+		TokenRepo:      tokenRepo,
+		trustedProxies: utils.ParseTrustedProxies(cfg.TrustedProxies),
 	}
 }
 
@@ -66,12 +75,36 @@ func (m *Middleware) TokenOrPINMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// AdminMiddleware guards operator endpoints with the static ADMIN_TOKEN (X-Admin-Token header).
+// Admin endpoints are disabled entirely when no token is configured.
+func (m *Middleware) AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.Config.AdminToken == "" {
+			utils.ErrorResponse(w, http.StatusForbidden, "Admin API disabled")
+			return
+		}
+
+		token := r.Header.Get("X-Admin-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(m.Config.AdminToken)) != 1 {
+			utils.ErrorResponse(w, http.StatusUnauthorized, "Invalid admin token")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (m *Middleware) parseToken(authHeader string) (string, error) {
 	parts := strings.Split(authHeader, " ")
 	if len(parts) != 2 || parts[0] != "Bearer" {
 		return "", errors.New("invalid authorization format")
 	}
-	return utils.ParseUserIDFromToken(parts[1], m.Config.JWTSecret)
+	return m.userIDFromToken(parts[1])
+}
+
+// userIDFromToken authenticates a bearer token, rejecting revoked ones.
+func (m *Middleware) userIDFromToken(token string) (string, error) {
+	return utils.AuthenticateToken(token, m.Config.JWTSecret, m.TokenRepo)
 }
 
 func (m *Middleware) parseTokenOrPin(authHeader string) (string, error) {
@@ -82,7 +115,7 @@ func (m *Middleware) parseTokenOrPin(authHeader string) (string, error) {
 
 	switch parts[0] {
 	case "Bearer":
-		return utils.ParseUserIDFromToken(parts[1], m.Config.JWTSecret)
+		return m.userIDFromToken(parts[1])
 	case "Pin", "PIN", "pin":
 		return m.userIDFromPIN(parts[1])
 	default:
@@ -102,17 +135,21 @@ func (m *Middleware) userIDFromPIN(pin string) (string, error) {
 	if user == nil {
 		return "", errors.New("invalid credentials")
 	}
+	if user.PINExpired(m.Config.PINMaxAge, time.Now()) {
+		return "", errors.New("PIN expired")
+	}
 	return user.ID, nil
 }
 
 func (m *Middleware) CORS(next http.Handler) http.Handler {
 	allowed := m.Config.AllowedOrigins
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Only echo origins that are explicitly allowed; with an empty list no CORS headers are sent
+		// and browsers block cross-origin calls.
 		origin := r.Header.Get("Origin")
-		if originAllowed(origin, allowed) {
+		if origin != "" && utils.OriginAllowed(origin, allowed) {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
-		} else if len(allowed) == 1 && allowed[0] == "*" {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
+			w.Header().Add("Vary", "Origin")
 		}
 
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
@@ -126,49 +163,3 @@ func (m *Middleware) CORS(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
-
-func originAllowed(origin string, allowed []string) bool {
-	if origin == "" {
-		return true // non-browser clients
-	}
-	for _, o := range allowed {
-		if o == "*" || strings.EqualFold(o, origin) {
-			return true
-		}
-	}
-	return false
-}
-
-// simple token bucket per IP
-type limiter struct {
-	tokens     int
-	lastRefill time.Time
-}
-
-func (m *Middleware) RateLimitMiddleware(next http.Handler) http.Handler {
-	const (
-		maxTokens    = 60
-		refillPeriod = time.Minute
-	)
-
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := strings.Split(r.RemoteAddr, ":")[0]
-
-		val, _ := m.rateLimiters.LoadOrStore(ip, &limiter{tokens: maxTokens, lastRefill: time.Now()})
-		lim := val.(*limiter)
-
-		now := time.Now()
-		if since := now.Sub(lim.lastRefill); since > refillPeriod {
-			lim.tokens = maxTokens
-			lim.lastRefill = now
-		}
-
-		if lim.tokens <= 0 {
-			utils.ErrorResponse(w, http.StatusTooManyRequests, "Rate limit exceeded")
-			return
-		}
-		lim.tokens--
-
-		next.ServeHTTP(w, r)
-	})
-}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"time"
+	"wago-backend/internal/errs"
+	"wago-backend/internal/utils"
+)
+
+const (
+	// limiterIdleTTL is how long an unused limiter is kept; by then its bucket is full again anyway.
+	limiterIdleTTL = 10 * time.Minute
+	// limiterSweepInterval spaces out sweeps for stale limiters.
+	limiterSweepInterval = time.Minute
+)
+
+// limiter is a token bucket refilled continuously at rate tokens per second, up to burst.
+type limiter struct {
+	mu       sync.Mutex
+	tokens   float64
+	lastSeen time.Time
+}
+
+// allow takes a token if one is available.
+func (l *limiter) allow(now time.Time, rate, burst float64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = min(burst, l.tokens+now.Sub(l.lastSeen).Seconds()*rate)
+	l.lastSeen = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// RateLimitMiddleware allows APIRateLimitPerMinute requests per minute per user, or per
+// client IP for unauthenticated requests, with bursts up to the same number. It keys by user
+// only when it runs after AuthMiddleware; 0 disables it.
+func (m *Middleware) RateLimitMiddleware(next http.Handler) http.Handler {
+	perMinute := m.Config.APIRateLimitPerMinute
+	burst := float64(perMinute)
+	rate := burst / 60
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if perMinute <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		now := time.Now()
+		m.sweepLimiters(now)
+
+		val, _ := m.rateLimiters.LoadOrStore(m.rateLimitKey(r), &limiter{tokens: burst, lastSeen: now})
+		if !val.(*limiter).allow(now, rate, burst) {
+			utils.ErrorFromErr(w, errs.ErrRateLimited)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitKey is the authenticated user's ID, or the client IP when there is none.
+func (m *Middleware) rateLimitKey(r *http.Request) string {
+	if userID, ok := r.Context().Value("user_id").(string); ok && userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + m.trustedProxies.ClientIP(r)
+}
+
+// sweepLimiters drops limiters idle for longer than limiterIdleTTL, at most once per
+// limiterSweepInterval, so one-off clients don't accumulate forever.
+func (m *Middleware) sweepLimiters(now time.Time) {
+	last := m.lastSweep.Load()
+	if now.UnixNano()-last < int64(limiterSweepInterval) || !m.lastSweep.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	m.rateLimiters.Range(func(key, val any) bool {
+		lim := val.(*limiter)
+		lim.mu.Lock()
+		stale := now.Sub(lim.lastSeen) > limiterIdleTTL
+		lim.mu.Unlock()
+		if stale {
+			m.rateLimiters.Delete(key)
+		}
+		return true
+	})
+}
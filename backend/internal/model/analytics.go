@@ -1,6 +1,12 @@
 package model
 
-import "time"
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type Analytics struct {
 	ID                  int64     `json:"id"`
@@ -15,13 +21,15 @@ type Analytics struct {
 	WebhookResponseTime int       `json:"webhook_response_time_ms"`
 	WebhookStatusCode   int       `json:"webhook_status_code"`
 	ErrorMessage        string    `json:"error_message"`
+	DryRun              bool      `json:"dry_run"`
 	CreatedAt           time.Time `json:"created_at"`
 }
 
 type MessageLog struct {
 	ID              int64     `json:"id"`
 	SessionID       string    `json:"session_id"`
-	Direction       string    `json:"direction"` // incoming, outgoing
+	MessageID       string    `json:"message_id"` // WhatsApp message ID; empty for rows logged before it was stored
+	Direction       string    `json:"direction"`  // incoming, outgoing
 	FromNumber      string    `json:"from_number"`
 	ToNumber        string    `json:"to_number"`
 	MessageType     string    `json:"message_type"`
@@ -35,17 +43,83 @@ type MessageLog struct {
 }
 
 type SessionAnalytics struct {
-	TotalMessages      int         `json:"total_messages"`
-	IncomingMessages   int         `json:"incoming_messages"`
-	OutgoingMessages   int         `json:"outgoing_messages"`
-	WebhookSuccessRate float64     `json:"webhook_success_rate"`
-	AvgResponseTime    float64     `json:"avg_response_time"`
-	LastActive         *time.Time  `json:"last_active"`
-	GroupMentions      int         `json:"group_mentions"`
-	DailyStats         []DailyStat `json:"daily_stats"`
+	TotalMessages            int         `json:"total_messages"`
+	IncomingMessages         int         `json:"incoming_messages"`
+	OutgoingMessages         int         `json:"outgoing_messages"`
+	WebhookSuccessRate       float64     `json:"webhook_success_rate"`
+	AvgResponseTime          float64     `json:"avg_response_time"` // milliseconds
+	P50ResponseTime          float64     `json:"p50_response_time"` // milliseconds, like the percentiles below
+	P95ResponseTime          float64     `json:"p95_response_time"`
+	P99ResponseTime          float64     `json:"p99_response_time"`
+	LastActive               *time.Time  `json:"last_active"`
+	GroupMentions            int         `json:"group_mentions"`
+	DryRunWebhooks           int         `json:"dry_run_webhooks"`           // webhook calls whose replies, if any, were only logged
+	UptimeSeconds            int64       `json:"uptime_seconds"`             // total time connected
+	ConnectedSince           *time.Time  `json:"connected_since,omitempty"`  // start of the current connection
+	CurrentConnectionSeconds int64       `json:"current_connection_seconds"` // 0 while disconnected
+	DailyStats               []DailyStat `json:"daily_stats"`
 }
 
 type DailyStat struct {
 	Date  string `json:"date"`
 	Count int    `json:"count"`
 }
+
+// WebhookDailyStat counts one day's webhook calls and their outcomes.
+type WebhookDailyStat struct {
+	Date    string `json:"date"`
+	Sent    int    `json:"sent"`
+	Success int    `json:"success"`
+	Failed  int    `json:"failed"`
+}
+
+// MessageLogFilter narrows a message log query; zero fields match everything.
+type MessageLogFilter struct {
+	SessionID   string
+	Direction   string // incoming or outgoing
+	MessageType string
+	From        time.Time // inclusive
+	To          time.Time // exclusive
+	Limit       int
+	Offset      int
+	// Before continues a previous page: only rows after this position (newest first) are returned.
+	Before *MessageCursor
+}
+
+// MessageCursor is a position in the message log, ordered by timestamp then ID.
+type MessageCursor struct {
+	Timestamp time.Time
+	ID        int64
+}
+
+// String encodes the cursor as an opaque token for API clients.
+func (c MessageCursor) String() string {
+	raw := c.Timestamp.UTC().Format(time.RFC3339Nano) + "_" + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseMessageCursor decodes a token produced by MessageCursor.String.
+func ParseMessageCursor(token string) (MessageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return MessageCursor{}, errors.New("invalid cursor")
+	}
+	ts, id, ok := strings.Cut(string(raw), "_")
+	if !ok {
+		return MessageCursor{}, errors.New("invalid cursor")
+	}
+	var c MessageCursor
+	if c.Timestamp, err = time.Parse(time.RFC3339Nano, ts); err != nil {
+		return MessageCursor{}, errors.New("invalid cursor")
+	}
+	if c.ID, err = strconv.ParseInt(id, 10, 64); err != nil {
+		return MessageCursor{}, errors.New("invalid cursor")
+	}
+	return c, nil
+}
+
+// MessagePage is one page of the message log, newest first. NextCursor is empty on the last page.
+type MessagePage struct {
+	Messages   []MessageLog `json:"messages"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// Audited actions. Actor is the user ID performing the action, AuditActorAdmin for admin-token
+// calls, or "ip:<client IP>" for failed logins, where no user is known.
+const (
+	AuditPINGenerated         = "pin.generated"
+	AuditPINRotated           = "pin.rotated"
+	AuditLogin                = "auth.login"
+	AuditLoginFailed          = "auth.login_failed"
+	AuditLogout               = "auth.logout"
+	AuditSessionDeleted       = "session.deleted"
+	AuditSessionDisconnected  = "session.force_disconnected"
+	AuditWebhookSecretRotated = "session.webhook_secret_rotated"
+
+	AuditActorAdmin = "admin"
+)
+
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditFilter narrows an audit log query; empty fields match everything.
+type AuditFilter struct {
+	Actor  string
+	Action string
+	Target string
+	Limit  int
+}
@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+// Broadcast job states. A running job is resumed after restarts and reconnects until every
+// recipient has been tried; cancelling skips the recipients not reached yet.
+const (
+	BroadcastStatusRunning   = "running"
+	BroadcastStatusCompleted = "completed"
+	BroadcastStatusCancelled = "cancelled"
+)
+
+// Broadcast recipient states; sending marks the one recipient a job is currently sending to.
+const (
+	RecipientStatusPending   = "pending"
+	RecipientStatusSending   = "sending"
+	RecipientStatusSent      = "sent"
+	RecipientStatusFailed    = "failed"
+	RecipientStatusCancelled = "cancelled"
+)
+
+// BroadcastJob sends one message to many recipients through the session's send queue.
+// The message may contain {{variable}} placeholders, filled in per recipient.
+type BroadcastJob struct {
+	ID          int64                `json:"id"`
+	SessionID   string               `json:"session_id"`
+	Message     string               `json:"message"`
+	Status      string               `json:"status"`
+	Total       int                  `json:"total"`
+	Sent        int                  `json:"sent"`
+	Failed      int                  `json:"failed"`
+	CompletedAt *time.Time           `json:"completed_at,omitempty"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+	Recipients  []BroadcastRecipient `json:"recipients,omitempty"`
+}
+
+// BroadcastRecipient is one recipient of a job and the outcome of sending to it.
+type BroadcastRecipient struct {
+	Position  int          `json:"position"`
+	Recipient string       `json:"to"`
+	Variables TemplateVars `json:"variables,omitempty"`
+	Status    string       `json:"status"`
+	MessageID string       `json:"message_id,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
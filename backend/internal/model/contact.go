@@ -6,4 +6,14 @@ type Contact struct {
 	PhoneNumber  string    `json:"phone_number"`
 	LastActive   time.Time `json:"last_active"`
 	MessageCount int       `json:"message_count"`
+
+	// Name and ProfilePictureURL are filled from WhatsApp by the contact directory only.
+	Name              string `json:"name,omitempty"`
+	ProfilePictureURL string `json:"profile_picture_url,omitempty"`
+}
+
+// ContactGrowth counts contacts by the day they first messaged the session.
+type ContactGrowth struct {
+	TotalContacts int         `json:"total_contacts"`
+	NewContacts   []DailyStat `json:"new_contacts"`
 }
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+type Group struct {
+	JID              string `json:"jid"`
+	Name             string `json:"name"`
+	ParticipantCount int    `json:"participant_count"`
+}
+
+// GroupSetting overrides the session's group response default for one group.
+type GroupSetting struct {
+	SessionID   string    `json:"session_id"`
+	GroupJID    string    `json:"group_jid"`
+	Enabled     bool      `json:"enabled"`
+	MentionOnly bool      `json:"mention_only"` // answer only messages that mention the bot
+	UpdatedAt   time.Time `json:"updated_at"`
+}
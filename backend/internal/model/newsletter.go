@@ -0,0 +1,12 @@
+package model
+
+// Newsletter is a WhatsApp channel as seen by a session's account.
+type Newsletter struct {
+	JID             string `json:"jid"`
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	SubscriberCount int    `json:"subscriber_count"`
+	State           string `json:"state"`
+	Role            string `json:"role,omitempty"` // subscriber, admin, owner; empty when not followed
+	Muted           bool   `json:"muted"`
+}
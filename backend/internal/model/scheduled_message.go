@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// Scheduled message states. A message moves from pending to sending when the scheduler
+// picks it up, then to sent or failed; only pending messages can be cancelled.
+const (
+	ScheduleStatusPending   = "pending"
+	ScheduleStatusSending   = "sending"
+	ScheduleStatusSent      = "sent"
+	ScheduleStatusFailed    = "failed"
+	ScheduleStatusCancelled = "cancelled"
+)
+
+// ScheduledMessage is a text message queued to be sent by a session at SendAt.
+type ScheduledMessage struct {
+	ID        int64      `json:"id"`
+	SessionID string     `json:"session_id"`
+	Recipient string     `json:"to"`
+	Message   string     `json:"message"`
+	SendAt    time.Time  `json:"send_at"`
+	Status    string     `json:"status"`
+	MessageID string     `json:"message_id,omitempty"` // WhatsApp message ID once sent
+	Error     string     `json:"error,omitempty"`
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
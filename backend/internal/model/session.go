@@ -13,12 +13,27 @@ const (
 	SessionStatusQR           SessionStatus = "qr"
 	SessionStatusConnected    SessionStatus = "connected"
 	SessionStatusDisconnected SessionStatus = "disconnected"
+	// SessionStatusNeedsRelink marks a session whose stored device is gone from the
+	// whatsmeow store, so it can only come back by scanning a new QR code.
+	SessionStatusNeedsRelink SessionStatus = "needs_relink"
 )
 
+// Encodings for webhook requests that carry no media (media always goes as multipart).
+const (
+	WebhookFormatJSON = "json"
+	WebhookFormatForm = "form"
+	WebhookFormatArgo = "argo"
+)
+
+// AllowedWebhookMethods are the HTTP methods a session may use to call its webhook.
+var AllowedWebhookMethods = map[string]bool{"POST": true, "PUT": true}
+
 type DeviceInfo struct {
 	Platform           string `json:"platform,omitempty"`
 	DeviceManufacturer string `json:"device_manufacturer,omitempty"`
 	DeviceModel        string `json:"device_model,omitempty"`
+	PushName           string `json:"push_name,omitempty"`
+	BusinessName       string `json:"business_name,omitempty"`
 }
 
 // Make DeviceInfo implement sql.Scanner and driver.Valuer
@@ -34,11 +49,31 @@ func (d *DeviceInfo) Scan(value interface{}) error {
 	return json.Unmarshal(b, &d)
 }
 
+// StringList is a list of strings stored as a JSONB array (session tags, webhook field lists).
+type StringList []string
+
+func (t StringList) Value() (driver.Value, error) {
+	if t == nil {
+		t = StringList{}
+	}
+	return json.Marshal(t)
+}
+
+func (t *StringList) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, t)
+}
+
 type Session struct {
 	ID                     string        `json:"session_id"`
 	UserID                 string        `json:"-"`
 	SessionName            string        `json:"session_name"`
 	WebhookURL             string        `json:"webhook_url"`
+	WebhookSecret          string        `json:"-"`
+	HasWebhookSecret       bool          `json:"has_webhook_secret"`
 	Status                 SessionStatus `json:"status"`
 	QRCode                 string        `json:"qr_code,omitempty"`
 	PhoneNumber            string        `json:"phone_number,omitempty"`
@@ -47,5 +82,38 @@ type Session struct {
 	UpdatedAt              time.Time     `json:"updated_at"`
 	LastConnected          *time.Time    `json:"last_connected,omitempty"`
 	UptimeSeconds          int64         `json:"uptime_seconds,omitempty"`
+	LastActivity           *time.Time    `json:"last_activity,omitempty"` // in-memory only, not persisted
 	IsGroupResponseEnabled bool          `json:"is_group_response_enabled"`
+	BusyReplyText          string        `json:"busy_reply_text"`
+	BusyReplyGraceMs       int           `json:"busy_reply_grace_ms"`
+	DryRun                 bool          `json:"dry_run"`
+	ReplyPrivatelyInGroups bool          `json:"reply_privately_in_groups"`
+	TriggerPattern         string        `json:"trigger_pattern"`
+	MarkReadOnSuccess      bool          `json:"mark_read_on_success"`
+	WebhookFormat          string        `json:"webhook_format"`
+	WebhookMethod          string        `json:"webhook_method"`
+	Tags                   StringList    `json:"tags"`
+	WebhookIncludeFields   StringList    `json:"webhook_include_fields"`
+	WebhookExcludeFields   StringList    `json:"webhook_exclude_fields"`
+	IngestHistory          bool          `json:"ingest_history"`
+	ProcessOwnMessages     bool          `json:"process_own_messages"`
+	ReplyFooter            string        `json:"reply_footer"`
+	WebhookStream          bool          `json:"webhook_stream"`
+	WebhookRetries         int           `json:"webhook_retries"`         // extra attempts after a failed delivery
+	WebhookTimeoutSeconds  int           `json:"webhook_timeout_seconds"` // per attempt
+	AutoMarkRead           bool          `json:"auto_mark_read"`          // mark incoming messages read as soon as they are accepted
+	WebhookStatusEvents    bool          `json:"webhook_status_events"`   // post delivered/read/played receipts to the webhook
+	QuoteReplies           bool          `json:"quote_replies"`           // replies quote the message that triggered them
+	TypingDelay            bool          `json:"typing_delay"`            // show "typing..." for a time proportional to each reply
+	MutedChats             StringList    `json:"muted_chats"`             // chat JIDs whose messages are logged but not answered
+}
+
+// IsChatMuted reports whether replies to the given chat JID are suppressed.
+func (s *Session) IsChatMuted(chat string) bool {
+	for _, muted := range s.MutedChats {
+		if muted == chat {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// SessionExportVersion is the current format of exported session configurations.
+const SessionExportVersion = 1
+
+// SessionExport is a portable copy of a session's configuration. It carries no device
+// pairing or webhook secret, so an imported session starts disconnected and unsigned.
+type SessionExport struct {
+	Version     int                    `json:"version"`
+	ExportedAt  time.Time              `json:"exported_at"`
+	SessionName string                 `json:"session_name"`
+	WebhookURL  string                 `json:"webhook_url"`
+	Settings    map[string]interface{} `json:"settings"` // same keys as the session update body
+}
@@ -0,0 +1,9 @@
+package model
+
+// StoreReport compares devices in the whatsmeow store with the sessions referencing them.
+type StoreReport struct {
+	DeviceCount            int      `json:"device_count"`
+	PairedSessionCount     int      `json:"paired_session_count"`
+	OrphanedDevices        []string `json:"orphaned_devices"`
+	SessionsMissingDevices []string `json:"sessions_missing_devices"`
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// MessageTemplate is a reusable outgoing message of a session. Its body may contain
+// {{variable}} placeholders resolved at send time.
+type MessageTemplate struct {
+	ID        int64     `json:"id"`
+	SessionID string    `json:"session_id"`
+	Name      string    `json:"name"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TemplateVars holds {{variable}} values, stored as a JSONB object.
+type TemplateVars map[string]string
+
+func (v TemplateVars) Value() (driver.Value, error) {
+	if v == nil {
+		v = TemplateVars{}
+	}
+	return json.Marshal(v)
+}
+
+func (v *TemplateVars) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, v)
+}
@@ -5,9 +5,15 @@ import (
 )
 
 type User struct {
-	ID        string     `json:"id"`
-	PIN       string     `json:"pin"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	LastLogin *time.Time `json:"last_login,omitempty"`
+	ID           string     `json:"id"`
+	PIN          string     `json:"pin"`
+	PINRotatedAt time.Time  `json:"pin_rotated_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	LastLogin    *time.Time `json:"last_login,omitempty"`
+}
+
+// PINExpired reports whether the PIN is older than maxAge and must be rotated. A zero maxAge never expires.
+func (u *User) PINExpired(maxAge time.Duration, now time.Time) bool {
+	return maxAge > 0 && now.Sub(u.PINRotatedAt) > maxAge
 }
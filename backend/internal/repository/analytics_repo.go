@@ -2,6 +2,9 @@ package repository
 
 import (
 	"database/sql"
+	"fmt"
+	"strings"
+	"time"
 	"wago-backend/internal/model"
 )
 
@@ -13,70 +16,146 @@ func NewAnalyticsRepository(db *sql.DB) *AnalyticsRepository {
 	return &AnalyticsRepository{DB: db}
 }
 
+// LogMessage stores a message. A message whose ID is already logged for the session, e.g. one
+// seen live and again in a history sync, is skipped.
 func (r *AnalyticsRepository) LogMessage(log *model.MessageLog) error {
 	query := `
-		INSERT INTO messages_log (session_id, direction, from_number, to_number, message_type, content, media_url, group_id, group_name, is_group, quoted_message_id, timestamp)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		INSERT INTO messages_log (session_id, message_id, direction, from_number, to_number, message_type, content, media_url, group_id, group_name, is_group, quoted_message_id, timestamp)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (session_id, message_id) WHERE message_id IS NOT NULL DO NOTHING
 	`
-	_, err := r.DB.Exec(query, log.SessionID, log.Direction, log.FromNumber, log.ToNumber, log.MessageType, log.Content, log.MediaURL, log.GroupID, log.GroupName, log.IsGroup, log.QuotedMessageID, log.Timestamp)
+	_, err := r.DB.Exec(query, log.SessionID, log.MessageID, log.Direction, log.FromNumber, log.ToNumber, log.MessageType, log.Content, log.MediaURL, log.GroupID, log.GroupName, log.IsGroup, log.QuotedMessageID, log.Timestamp)
 	return err
 }
 
 func (r *AnalyticsRepository) LogAnalytics(a *model.Analytics) error {
 	query := `
-		INSERT INTO analytics (session_id, message_id, from_number, message_type, is_group, is_mention, webhook_sent, webhook_success, webhook_response_time_ms, webhook_status_code, error_message)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		INSERT INTO analytics (session_id, message_id, from_number, message_type, is_group, is_mention, webhook_sent, webhook_success, webhook_response_time_ms, webhook_status_code, error_message, dry_run)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
-	_, err := r.DB.Exec(query, a.SessionID, a.MessageID, a.FromNumber, a.MessageType, a.IsGroup, a.IsMention, a.WebhookSent, a.WebhookSuccess, a.WebhookResponseTime, a.WebhookStatusCode, a.ErrorMessage)
+	_, err := r.DB.Exec(query, a.SessionID, a.MessageID, a.FromNumber, a.MessageType, a.IsGroup, a.IsMention, a.WebhookSent, a.WebhookSuccess, a.WebhookResponseTime, a.WebhookStatusCode, a.ErrorMessage, a.DryRun)
+	return err
+}
+
+// RecordConnected opens a connection interval for the session starting at at (UTC). It is a
+// no-op while one is already open, so repeated Connected events don't double count.
+func (r *AnalyticsRepository) RecordConnected(sessionID string, at time.Time) error {
+	_, err := r.DB.Exec(`
+		INSERT INTO session_connections (session_id, connected_at)
+		VALUES ($1, $2)
+		ON CONFLICT (session_id) WHERE disconnected_at IS NULL DO NOTHING
+	`, sessionID, at)
+	return err
+}
+
+// RecordDisconnected closes the session's open connection interval at at (UTC), if any.
+func (r *AnalyticsRepository) RecordDisconnected(sessionID string, at time.Time) error {
+	_, err := r.DB.Exec(`
+		UPDATE session_connections SET disconnected_at = GREATEST($2, connected_at)
+		WHERE session_id = $1 AND disconnected_at IS NULL
+	`, sessionID, at)
 	return err
 }
 
-func (r *AnalyticsRepository) GetSessionAnalytics(sessionID string) (*model.SessionAnalytics, error) {
+// CloseOpenConnections closes intervals left open by an unclean shutdown at at (UTC).
+// The real end is unknown, so this is an upper bound.
+func (r *AnalyticsRepository) CloseOpenConnections(at time.Time) error {
+	_, err := r.DB.Exec(`
+		UPDATE session_connections SET disconnected_at = GREATEST($1, connected_at)
+		WHERE disconnected_at IS NULL
+	`, at)
+	return err
+}
+
+// localDay renders a stored timestamp column (UTC wall clock) as YYYY-MM-DD in the timezone bound to param.
+func localDay(column, param string) string {
+	return fmt.Sprintf("to_char((%s AT TIME ZONE 'UTC') AT TIME ZONE %s, 'YYYY-MM-DD')", column, param)
+}
+
+// GetSessionAnalytics computes the session's stats; daily buckets follow loc's calendar days.
+// With a zero from, message and webhook totals are all-time and daily stats cover the last 7 days;
+// otherwise both are limited to [from, to). Uptime and last activity are always all-time.
+func (r *AnalyticsRepository) GetSessionAnalytics(sessionID string, loc *time.Location, from, to time.Time) (*model.SessionAnalytics, error) {
 	stats := &model.SessionAnalytics{
 		DailyStats: []model.DailyStat{},
 	}
 
+	// messagesIn and analyticsIn restrict the count queries to the range; args holds its bounds.
+	args := []interface{}{sessionID}
+	messagesIn, analyticsIn := "", ""
+	if !from.IsZero() {
+		args = append(args, from.UTC(), to.UTC())
+		messagesIn = " AND timestamp >= $2 AND timestamp < $3"
+		analyticsIn = " AND created_at >= $2 AND created_at < $3"
+	}
+
 	// Total Messages
-	err := r.DB.QueryRow("SELECT COUNT(*) FROM messages_log WHERE session_id = $1", sessionID).Scan(&stats.TotalMessages)
+	err := r.DB.QueryRow("SELECT COUNT(*) FROM messages_log WHERE session_id = $1"+messagesIn, args...).Scan(&stats.TotalMessages)
 	if err != nil {
 		return nil, err
 	}
 
 	// Incoming
-	err = r.DB.QueryRow("SELECT COUNT(*) FROM messages_log WHERE session_id = $1 AND direction = 'incoming'", sessionID).Scan(&stats.IncomingMessages)
+	err = r.DB.QueryRow("SELECT COUNT(*) FROM messages_log WHERE session_id = $1 AND direction = 'incoming'"+messagesIn, args...).Scan(&stats.IncomingMessages)
 	if err != nil {
 		return nil, err
 	}
 
 	// Outgoing
-	err = r.DB.QueryRow("SELECT COUNT(*) FROM messages_log WHERE session_id = $1 AND direction = 'outgoing'", sessionID).Scan(&stats.OutgoingMessages)
+	err = r.DB.QueryRow("SELECT COUNT(*) FROM messages_log WHERE session_id = $1 AND direction = 'outgoing'"+messagesIn, args...).Scan(&stats.OutgoingMessages)
 	if err != nil {
 		return nil, err
 	}
 
-	// Webhook Stats
-	var totalWebhooks int
-	var successWebhooks int
-	var totalTime int64
+	// Webhook Stats. The average is computed in SQL as float8 so summing millions of
+	// millisecond values can't overflow, and stays in milliseconds like the column.
+	var totalWebhooks int64
+	var successWebhooks int64
+	var avgTime, p50, p95, p99 float64
 	err = r.DB.QueryRow(`
-		SELECT COUNT(*), COALESCE(SUM(CASE WHEN webhook_success THEN 1 ELSE 0 END), 0), COALESCE(SUM(webhook_response_time_ms), 0)
-		FROM analytics WHERE session_id = $1 AND webhook_sent = true
-	`, sessionID).Scan(&totalWebhooks, &successWebhooks, &totalTime)
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE webhook_success), COALESCE(AVG(webhook_response_time_ms::float8), 0),
+		       COALESCE(percentile_cont(0.50) WITHIN GROUP (ORDER BY webhook_response_time_ms), 0),
+		       COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY webhook_response_time_ms), 0),
+		       COALESCE(percentile_cont(0.99) WITHIN GROUP (ORDER BY webhook_response_time_ms), 0)
+		FROM analytics WHERE session_id = $1 AND webhook_sent = true`+analyticsIn,
+		args...).Scan(&totalWebhooks, &successWebhooks, &avgTime, &p50, &p95, &p99)
 	if err != nil {
 		return nil, err
 	}
 
 	if totalWebhooks > 0 {
 		stats.WebhookSuccessRate = float64(successWebhooks) / float64(totalWebhooks) * 100
-		stats.AvgResponseTime = float64(totalTime) / float64(totalWebhooks)
+		stats.AvgResponseTime = avgTime
+		stats.P50ResponseTime, stats.P95ResponseTime, stats.P99ResponseTime = p50, p95, p99
 	}
 
 	// Group Mentions
-	err = r.DB.QueryRow("SELECT COUNT(*) FROM analytics WHERE session_id = $1 AND is_mention = true", sessionID).Scan(&stats.GroupMentions)
+	err = r.DB.QueryRow("SELECT COUNT(*) FROM analytics WHERE session_id = $1 AND is_mention = true"+analyticsIn, args...).Scan(&stats.GroupMentions)
 	if err != nil {
 		return nil, err
 	}
 
+	// Webhook calls made in dry-run mode; whether they returned a reply is not recorded.
+	err = r.DB.QueryRow("SELECT COUNT(*) FROM analytics WHERE session_id = $1 AND dry_run = true"+analyticsIn, args...).Scan(&stats.DryRunWebhooks)
+	if err != nil {
+		return nil, err
+	}
+
+	// Uptime: closed intervals plus the open one up to now.
+	var connectedSince sql.NullTime
+	err = r.DB.QueryRow(`
+		SELECT COALESCE(SUM(EXTRACT(EPOCH FROM COALESCE(disconnected_at, NOW() AT TIME ZONE 'UTC') - connected_at)), 0)::bigint,
+			MAX(connected_at) FILTER (WHERE disconnected_at IS NULL)
+		FROM session_connections WHERE session_id = $1
+	`, sessionID).Scan(&stats.UptimeSeconds, &connectedSince)
+	if err != nil {
+		return nil, err
+	}
+	if connectedSince.Valid {
+		stats.ConnectedSince = &connectedSince.Time
+		stats.CurrentConnectionSeconds = int64(time.Now().UTC().Sub(connectedSince.Time).Seconds())
+	}
+
 	// Last Active
 	var lastActive sql.NullTime
 	err = r.DB.QueryRow("SELECT MAX(timestamp) FROM messages_log WHERE session_id = $1", sessionID).Scan(&lastActive)
@@ -84,14 +163,19 @@ func (r *AnalyticsRepository) GetSessionAnalytics(sessionID string) (*model.Sess
 		stats.LastActive = &lastActive.Time
 	}
 
-	// Daily Stats (Last 7 days)
+	// Daily Stats (the range, or the last 7 days)
+	dayRange := messagesIn
+	if dayRange == "" {
+		dayRange = " AND timestamp > (NOW() AT TIME ZONE 'UTC') - INTERVAL '7 days'"
+	}
+	locParam := fmt.Sprintf("$%d", len(args)+1)
 	rows, err := r.DB.Query(`
-		SELECT to_char(timestamp, 'YYYY-MM-DD') as date, COUNT(*)
+		SELECT `+localDay("timestamp", locParam)+` as date, COUNT(*)
 		FROM messages_log
-		WHERE session_id = $1 AND timestamp > NOW() - INTERVAL '7 days'
+		WHERE session_id = $1`+dayRange+`
 		GROUP BY date
 		ORDER BY date ASC
-	`, sessionID)
+	`, append(args, loc.String())...)
 	if err != nil {
 		return nil, err
 	}
@@ -131,3 +215,231 @@ func (r *AnalyticsRepository) GetUniqueContacts(sessionID string) ([]model.Conta
 	}
 	return contacts, nil
 }
+
+// GetUnansweredMessages returns incoming messages since the given time with no outgoing
+// message to the same chat (or, for groups answered privately, to the sender) after them.
+func (r *AnalyticsRepository) GetUnansweredMessages(sessionID string, since time.Time) ([]model.MessageLog, error) {
+	query := `
+		SELECT i.id, i.session_id, i.direction, i.from_number, i.to_number, i.message_type, i.content,
+		       i.group_id, i.group_name, i.is_group, i.timestamp
+		FROM messages_log i
+		WHERE i.session_id = $1
+		  AND i.direction = 'incoming'
+		  AND i.timestamp >= $2
+		  AND NOT EXISTS (
+		      SELECT 1 FROM messages_log o
+		      WHERE o.session_id = i.session_id
+		        AND o.direction = 'outgoing'
+		        AND o.timestamp >= i.timestamp
+		        AND (o.to_number = i.from_number OR (i.is_group AND o.group_id = i.group_id))
+		  )
+		ORDER BY i.timestamp DESC
+	`
+	rows, err := r.DB.Query(query, sessionID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []model.MessageLog{}
+	for rows.Next() {
+		var m model.MessageLog
+		if err := rows.Scan(&m.ID, &m.SessionID, &m.Direction, &m.FromNumber, &m.ToNumber, &m.MessageType, &m.Content,
+			&m.GroupID, &m.GroupName, &m.IsGroup, &m.Timestamp); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// ListMessages returns one page of the session's message log matching filter, newest first.
+// The page's NextCursor is set when more rows may follow.
+func (r *AnalyticsRepository) ListMessages(filter model.MessageLogFilter) (*model.MessagePage, error) {
+	conditions := []string{"session_id = $1"}
+	args := []interface{}{filter.SessionID}
+	addCondition := func(format string, values ...interface{}) {
+		placeholders := make([]interface{}, len(values))
+		for i, value := range values {
+			args = append(args, value)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		conditions = append(conditions, fmt.Sprintf(format, placeholders...))
+	}
+
+	if filter.Direction != "" {
+		addCondition("direction = %s", filter.Direction)
+	}
+	if filter.MessageType != "" {
+		addCondition("message_type = %s", filter.MessageType)
+	}
+	if !filter.From.IsZero() {
+		addCondition("timestamp >= %s", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		addCondition("timestamp < %s", filter.To.UTC())
+	}
+	if filter.Before != nil {
+		addCondition("(timestamp, id) < (%s, %s)", filter.Before.Timestamp.UTC(), filter.Before.ID)
+	}
+
+	query := `
+		SELECT id, session_id, COALESCE(message_id, ''), direction, from_number, to_number, message_type, content, COALESCE(media_url, ''),
+		       group_id, group_name, is_group, COALESCE(quoted_message_id, ''), timestamp
+		FROM messages_log
+		WHERE ` + strings.Join(conditions, " AND ")
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(` ORDER BY timestamp DESC, id DESC LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &model.MessagePage{Messages: []model.MessageLog{}}
+	for rows.Next() {
+		var m model.MessageLog
+		if err := rows.Scan(&m.ID, &m.SessionID, &m.MessageID, &m.Direction, &m.FromNumber, &m.ToNumber, &m.MessageType, &m.Content, &m.MediaURL,
+			&m.GroupID, &m.GroupName, &m.IsGroup, &m.QuotedMessageID, &m.Timestamp); err != nil {
+			return nil, err
+		}
+		page.Messages = append(page.Messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// A full page may have more behind it; a short one is the last.
+	if n := len(page.Messages); n > 0 && n == filter.Limit {
+		last := page.Messages[n-1]
+		page.NextCursor = model.MessageCursor{Timestamp: last.Timestamp, ID: last.ID}.String()
+	}
+	return page, nil
+}
+
+// GetContactGrowth returns the session's total distinct contacts and, per day in [from, to)
+// bucketed in loc, how many contacts messaged it for the first time.
+func (r *AnalyticsRepository) GetContactGrowth(sessionID string, from, to time.Time, loc *time.Location) (*model.ContactGrowth, error) {
+	growth := &model.ContactGrowth{NewContacts: []model.DailyStat{}}
+
+	err := r.DB.QueryRow(`
+		SELECT COUNT(DISTINCT from_number)
+		FROM messages_log
+		WHERE session_id = $1 AND direction = 'incoming' AND from_number <> ''
+	`, sessionID).Scan(&growth.TotalContacts)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.DB.Query(`
+		SELECT `+localDay("first_seen", "$4")+` AS date, COUNT(*)
+		FROM (
+			SELECT from_number, MIN(timestamp) AS first_seen
+			FROM messages_log
+			WHERE session_id = $1 AND direction = 'incoming' AND from_number <> ''
+			GROUP BY from_number
+		) contacts
+		WHERE first_seen >= $2 AND first_seen < $3
+		GROUP BY date
+		ORDER BY date ASC
+	`, sessionID, from.UTC(), to.UTC(), loc.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ds model.DailyStat
+		if err := rows.Scan(&ds.Date, &ds.Count); err != nil {
+			return nil, err
+		}
+		growth.NewContacts = append(growth.NewContacts, ds)
+	}
+	return growth, rows.Err()
+}
+
+// GetWebhookStatsTimeline returns, per day in [from, to) bucketed in loc, how many webhook calls
+// the session made and how many succeeded or failed. Days without calls are omitted.
+func (r *AnalyticsRepository) GetWebhookStatsTimeline(sessionID string, from, to time.Time, loc *time.Location) ([]model.WebhookDailyStat, error) {
+	rows, err := r.DB.Query(`
+		SELECT `+localDay("created_at", "$4")+` AS date,
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE webhook_success),
+		       COUNT(*) FILTER (WHERE NOT webhook_success)
+		FROM analytics
+		WHERE session_id = $1 AND webhook_sent = true AND created_at >= $2 AND created_at < $3
+		GROUP BY date
+		ORDER BY date ASC
+	`, sessionID, from.UTC(), to.UTC(), loc.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []model.WebhookDailyStat{}
+	for rows.Next() {
+		var ds model.WebhookDailyStat
+		if err := rows.Scan(&ds.Date, &ds.Sent, &ds.Success, &ds.Failed); err != nil {
+			return nil, err
+		}
+		stats = append(stats, ds)
+	}
+	return stats, rows.Err()
+}
+
+// StreamMessages calls fn for each of the session's message log rows in [from, to), oldest
+// first, reading them one at a time so large exports are never held in memory.
+// It stops at the first error from fn.
+func (r *AnalyticsRepository) StreamMessages(sessionID string, from, to time.Time, fn func(model.MessageLog) error) error {
+	rows, err := r.DB.Query(`
+		SELECT id, session_id, COALESCE(message_id, ''), direction, from_number, to_number, message_type, content, COALESCE(media_url, ''),
+		       group_id, group_name, is_group, COALESCE(quoted_message_id, ''), timestamp
+		FROM messages_log
+		WHERE session_id = $1 AND timestamp >= $2 AND timestamp < $3
+		ORDER BY timestamp ASC, id ASC
+	`, sessionID, from.UTC(), to.UTC())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m model.MessageLog
+		if err := rows.Scan(&m.ID, &m.SessionID, &m.MessageID, &m.Direction, &m.FromNumber, &m.ToNumber, &m.MessageType, &m.Content, &m.MediaURL,
+			&m.GroupID, &m.GroupName, &m.IsGroup, &m.QuotedMessageID, &m.Timestamp); err != nil {
+			return err
+		}
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// StreamAnalytics is StreamMessages for the session's webhook analytics rows.
+func (r *AnalyticsRepository) StreamAnalytics(sessionID string, from, to time.Time, fn func(model.Analytics) error) error {
+	rows, err := r.DB.Query(`
+		SELECT id, session_id, message_id, from_number, message_type, is_group, is_mention, webhook_sent, webhook_success,
+		       COALESCE(webhook_response_time_ms, 0), COALESCE(webhook_status_code, 0), COALESCE(error_message, ''), dry_run, created_at
+		FROM analytics
+		WHERE session_id = $1 AND created_at >= $2 AND created_at < $3
+		ORDER BY created_at ASC, id ASC
+	`, sessionID, from.UTC(), to.UTC())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a model.Analytics
+		if err := rows.Scan(&a.ID, &a.SessionID, &a.MessageID, &a.FromNumber, &a.MessageType, &a.IsGroup, &a.IsMention, &a.WebhookSent,
+			&a.WebhookSuccess, &a.WebhookResponseTime, &a.WebhookStatusCode, &a.ErrorMessage, &a.DryRun, &a.CreatedAt); err != nil {
+			return err
+		}
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
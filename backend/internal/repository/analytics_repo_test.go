@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"math"
+	"regexp"
+	"testing"
+	"time"
+	"wago-backend/internal/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newTestAnalyticsRepo(t *testing.T) (*AnalyticsRepository, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	return NewAnalyticsRepository(db), mock
+}
+
+func countRow(n int64) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"count"}).AddRow(n)
+}
+
+// webhookStatsQuery matches the webhook stats query only if it counts rows with COUNT and
+// averages response times as float8 in SQL, so neither the counts nor a sum can overflow.
+var webhookStatsQuery = regexp.QuoteMeta("SELECT COUNT(*), COUNT(*) FILTER (WHERE webhook_success), COALESCE(AVG(webhook_response_time_ms::float8), 0),")
+
+// expectSessionAnalytics queues the queries GetSessionAnalytics runs, in order, answering the
+// webhook stats query with webhookStats (count, successes, avg, p50, p95, p99).
+func expectSessionAnalytics(mock sqlmock.Sqlmock, webhookStats []driver.Value) {
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM messages_log WHERE session_id = $1")).WillReturnRows(countRow(10))
+	mock.ExpectQuery("direction = 'incoming'").WillReturnRows(countRow(6))
+	mock.ExpectQuery("direction = 'outgoing'").WillReturnRows(countRow(4))
+	mock.ExpectQuery(webhookStatsQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "success", "avg", "p50", "p95", "p99"}).AddRow(webhookStats...))
+	mock.ExpectQuery("is_mention = true").WillReturnRows(countRow(0))
+	mock.ExpectQuery("dry_run = true").WillReturnRows(countRow(3))
+	mock.ExpectQuery("FROM session_connections").WillReturnRows(sqlmock.NewRows([]string{"uptime", "since"}).AddRow(0, nil))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT MAX(timestamp)")).WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+	mock.ExpectQuery("GROUP BY date").WillReturnRows(sqlmock.NewRows([]string{"date", "count"}))
+}
+
+// TestSessionAnalyticsWebhookStatsQuery checks the webhook stats come from webhookStatsQuery
+// and that its counts are read as int64: counts past the int32 range would give a success
+// rate of about 64.5% instead of 95% if they were truncated.
+func TestSessionAnalyticsWebhookStatsQuery(t *testing.T) {
+	const total, success = int64(5_000_000_000), int64(4_750_000_000)
+	const avg = 29_999.75 // ms
+	repo, mock := newTestAnalyticsRepo(t)
+	expectSessionAnalytics(mock, []driver.Value{total, success, avg, 1_200.0, 55_000.0, 59_000.0})
+
+	stats, err := repo.GetSessionAnalytics("s1", time.UTC, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("GetSessionAnalytics: %v", err)
+	}
+	if stats.AvgResponseTime != avg {
+		t.Errorf("AvgResponseTime = %v, want %v", stats.AvgResponseTime, avg)
+	}
+	if math.Abs(stats.WebhookSuccessRate-95) > 1e-9 {
+		t.Errorf("WebhookSuccessRate = %v, want 95", stats.WebhookSuccessRate)
+	}
+	if stats.DryRunWebhooks != 3 {
+		t.Errorf("DryRunWebhooks = %d, want 3", stats.DryRunWebhooks)
+	}
+	if stats.P50ResponseTime != 1_200 || stats.P95ResponseTime != 55_000 || stats.P99ResponseTime != 59_000 {
+		t.Errorf("percentiles = %v/%v/%v", stats.P50ResponseTime, stats.P95ResponseTime, stats.P99ResponseTime)
+	}
+}
+
+func TestSessionAnalyticsWithoutWebhooks(t *testing.T) {
+	repo, mock := newTestAnalyticsRepo(t)
+	expectSessionAnalytics(mock, []driver.Value{int64(0), int64(0), 0.0, 0.0, 0.0, 0.0})
+
+	stats, err := repo.GetSessionAnalytics("s1", time.UTC, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("GetSessionAnalytics: %v", err)
+	}
+	if stats.AvgResponseTime != 0 || stats.WebhookSuccessRate != 0 {
+		t.Errorf("stats without webhooks = %v avg, %v%% success", stats.AvgResponseTime, stats.WebhookSuccessRate)
+	}
+}
+
+// utcTime matches a time.Time argument bound as the given instant in UTC, since messages_log
+// stores timestamps without a time zone.
+type utcTime time.Time
+
+func (want utcTime) Match(v driver.Value) bool {
+	got, ok := v.(time.Time)
+	return ok && got.Location() == time.UTC && got.Equal(time.Time(want))
+}
+
+func TestListMessagesBindsTimesInUTC(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*3600)
+	from := time.Date(2025, 1, 15, 0, 0, 0, 0, jakarta)
+	to := from.AddDate(0, 0, 1)
+	before := time.Date(2025, 1, 15, 12, 0, 0, 0, jakarta)
+
+	repo, mock := newTestAnalyticsRepo(t)
+	mock.ExpectQuery("FROM messages_log").
+		WithArgs("s1", utcTime(from), utcTime(to), utcTime(before), int64(42), 50, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := repo.ListMessages(model.MessageLogFilter{
+		SessionID: "s1", From: from, To: to, Limit: 50,
+		Before: &model.MessageCursor{Timestamp: before, ID: 42},
+	})
+	if err != nil {
+		t.Fatalf("ListMessages: %v", err)
+	}
+}
+
+// TestLogMessageSkipsLoggedIDs checks that messages are keyed by their WhatsApp ID, so a
+// message seen live and again in a history sync is stored once; rows without an ID bind NULL.
+func TestLogMessageSkipsLoggedIDs(t *testing.T) {
+	for _, id := range []string{"3EB0C431C26A1916A5C2", ""} {
+		repo, mock := newTestAnalyticsRepo(t)
+		mock.ExpectExec(regexp.QuoteMeta("NULLIF($2, '')")+"(?s).*"+
+			regexp.QuoteMeta("ON CONFLICT (session_id, message_id) WHERE message_id IS NOT NULL DO NOTHING")).
+			WithArgs("s1", id, "incoming", "628111111111", "", "text", "hi", "", "", "", false, "", testTime).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.LogMessage(&model.MessageLog{
+			SessionID: "s1", MessageID: id, Direction: "incoming", FromNumber: "628111111111",
+			MessageType: "text", Content: "hi", Timestamp: testTime,
+		})
+		if err != nil {
+			t.Fatalf("LogMessage(%q): %v", id, err)
+		}
+	}
+}
+
+var testTime = time.Date(2025, 1, 15, 9, 30, 0, 0, time.UTC)
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"wago-backend/internal/model"
+)
+
+type AuditRepository struct {
+	DB *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{DB: db}
+}
+
+func (r *AuditRepository) Record(actor, action, target string) error {
+	query := `INSERT INTO audit_log (actor, action, target) VALUES ($1, $2, $3)`
+	_, err := r.DB.Exec(query, actor, action, target)
+	return err
+}
+
+// List returns the newest entries matching filter first.
+func (r *AuditRepository) List(filter model.AuditFilter) ([]model.AuditEntry, error) {
+	var conditions []string
+	var args []interface{}
+	for column, value := range map[string]string{"actor": filter.Actor, "action": filter.Action, "target": filter.Target} {
+		if value != "" {
+			args = append(args, value)
+			conditions = append(conditions, fmt.Sprintf("%s = $%d", column, len(args)))
+		}
+	}
+
+	query := `SELECT id, actor, action, target, created_at FROM audit_log`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []model.AuditEntry{}
+	for rows.Next() {
+		var e model.AuditEntry
+		if err := rows.Scan(&e.ID, &e.Actor, &e.Action, &e.Target, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"wago-backend/internal/errs"
+	"wago-backend/internal/model"
+
+	"github.com/lib/pq"
+)
+
+type BroadcastRepository struct {
+	DB *sql.DB
+}
+
+func NewBroadcastRepository(db *sql.DB) *BroadcastRepository {
+	return &BroadcastRepository{DB: db}
+}
+
+const broadcastColumns = `id, session_id, message, status, total, sent, failed, completed_at, created_at, updated_at`
+
+func scanBroadcastJob(row rowScanner) (*model.BroadcastJob, error) {
+	var j model.BroadcastJob
+	var completedAt sql.NullTime
+	if err := row.Scan(&j.ID, &j.SessionID, &j.Message, &j.Status, &j.Total, &j.Sent, &j.Failed,
+		&completedAt, &j.CreatedAt, &j.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if completedAt.Valid {
+		j.CompletedAt = &completedAt.Time
+	}
+	return &j, nil
+}
+
+const recipientColumns = `position, recipient, variables, status, message_id, error, updated_at`
+
+func scanBroadcastRecipient(row rowScanner) (*model.BroadcastRecipient, error) {
+	var r model.BroadcastRecipient
+	if err := row.Scan(&r.Position, &r.Recipient, &r.Variables, &r.Status, &r.MessageID, &r.Error, &r.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (r *BroadcastRepository) queryJobs(query string, args ...interface{}) ([]model.BroadcastJob, error) {
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []model.BroadcastJob{}
+	for rows.Next() {
+		j, err := scanBroadcastJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *j)
+	}
+	return jobs, rows.Err()
+}
+
+// Create stores a running job with its recipients, numbered from 1 in the given order.
+// Only Recipient and Variables of each recipient are used.
+func (r *BroadcastRepository) Create(sessionID, message string, recipients []model.BroadcastRecipient) (*model.BroadcastJob, error) {
+	numbers := make([]string, len(recipients))
+	variables := make([]string, len(recipients))
+	for i, recipient := range recipients {
+		numbers[i] = recipient.Recipient
+		vars, err := recipient.Variables.Value()
+		if err != nil {
+			return nil, err
+		}
+		variables[i] = string(vars.([]byte))
+	}
+
+	row := r.DB.QueryRow(`
+		WITH job AS (
+			INSERT INTO broadcast_jobs (session_id, message, total) VALUES ($1, $2, $3)
+			RETURNING `+broadcastColumns+`
+		), recipients AS (
+			INSERT INTO broadcast_recipients (job_id, position, recipient, variables)
+			SELECT job.id, t.position, t.recipient, t.variables::jsonb
+			FROM job, unnest($4::text[], $5::text[]) WITH ORDINALITY AS t(recipient, variables, position)
+		)
+		SELECT `+broadcastColumns+` FROM job`, sessionID, message, len(recipients), pq.Array(numbers), pq.Array(variables))
+	return scanBroadcastJob(row)
+}
+
+// List returns a session's jobs, newest first, without their recipients.
+func (r *BroadcastRepository) List(sessionID string) ([]model.BroadcastJob, error) {
+	return r.queryJobs(`SELECT `+broadcastColumns+` FROM broadcast_jobs WHERE session_id = $1 ORDER BY created_at DESC, id DESC`, sessionID)
+}
+
+// Get returns a job of the session with its recipients in send order.
+func (r *BroadcastRepository) Get(sessionID string, id int64) (*model.BroadcastJob, error) {
+	job, err := scanBroadcastJob(r.DB.QueryRow(`SELECT `+broadcastColumns+` FROM broadcast_jobs WHERE session_id = $1 AND id = $2`, sessionID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrBroadcastNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.DB.Query(`SELECT `+recipientColumns+` FROM broadcast_recipients WHERE job_id = $1 ORDER BY position`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	job.Recipients = []model.BroadcastRecipient{}
+	for rows.Next() {
+		recipient, err := scanBroadcastRecipient(rows)
+		if err != nil {
+			return nil, err
+		}
+		job.Recipients = append(job.Recipients, *recipient)
+	}
+	return job, rows.Err()
+}
+
+// Cancel stops a running job; recipients not reached yet are marked cancelled. Jobs that
+// already finished yield errs.ErrBroadcastNotRunning.
+func (r *BroadcastRepository) Cancel(sessionID string, id int64) (*model.BroadcastJob, error) {
+	row := r.DB.QueryRow(`
+		WITH job AS (
+			UPDATE broadcast_jobs SET status = $3, completed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			WHERE session_id = $1 AND id = $2 AND status = $4
+			RETURNING `+broadcastColumns+`
+		), skipped AS (
+			UPDATE broadcast_recipients SET status = $5, updated_at = CURRENT_TIMESTAMP
+			WHERE job_id IN (SELECT id FROM job) AND status = $6
+		)
+		SELECT `+broadcastColumns+` FROM job`,
+		sessionID, id, model.BroadcastStatusCancelled, model.BroadcastStatusRunning,
+		model.RecipientStatusCancelled, model.RecipientStatusPending)
+	job, err := scanBroadcastJob(row)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return job, err
+	}
+
+	var exists bool
+	if err := r.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM broadcast_jobs WHERE session_id = $1 AND id = $2)`, sessionID, id).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errs.ErrBroadcastNotRunning
+	}
+	return nil, errs.ErrBroadcastNotFound
+}
+
+// RunningJobs returns the running jobs of the given sessions, oldest first.
+func (r *BroadcastRepository) RunningJobs(sessionIDs []string) ([]model.BroadcastJob, error) {
+	return r.queryJobs(`
+		SELECT `+broadcastColumns+` FROM broadcast_jobs
+		WHERE status = $1 AND session_id::text = ANY($2)
+		ORDER BY created_at, id`, model.BroadcastStatusRunning, pq.Array(sessionIDs))
+}
+
+// NextRecipient returns the job's first pending recipient, or nil when none are left.
+func (r *BroadcastRepository) NextRecipient(jobID int64) (*model.BroadcastRecipient, error) {
+	recipient, err := scanBroadcastRecipient(r.DB.QueryRow(`
+		SELECT `+recipientColumns+` FROM broadcast_recipients
+		WHERE job_id = $1 AND status = $2
+		ORDER BY position LIMIT 1`, jobID, model.RecipientStatusPending))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return recipient, err
+}
+
+// ClaimRecipient marks a pending recipient as sending. It reports false when the job is no
+// longer running or the recipient was already handled.
+func (r *BroadcastRepository) ClaimRecipient(jobID int64, position int) (bool, error) {
+	res, err := r.DB.Exec(`
+		UPDATE broadcast_recipients SET status = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE job_id = $1 AND position = $2 AND status = $4
+		  AND EXISTS (SELECT 1 FROM broadcast_jobs WHERE id = $1 AND status = $5)`,
+		jobID, position, model.RecipientStatusSending, model.RecipientStatusPending, model.BroadcastStatusRunning)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ReleaseRecipient returns a claimed recipient to pending so a resumed job retries it.
+func (r *BroadcastRepository) ReleaseRecipient(jobID int64, position int) error {
+	_, err := r.DB.Exec(`
+		UPDATE broadcast_recipients SET status = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE job_id = $1 AND position = $2 AND status = $4`,
+		jobID, position, model.RecipientStatusPending, model.RecipientStatusSending)
+	return err
+}
+
+// RecordResult stores the outcome (sent or failed) for a claimed recipient and returns the
+// job with updated counters.
+func (r *BroadcastRepository) RecordResult(jobID int64, position int, status, messageID, reason string) (*model.BroadcastJob, error) {
+	return scanBroadcastJob(r.DB.QueryRow(`
+		WITH recipient AS (
+			UPDATE broadcast_recipients SET status = $3, message_id = $4, error = $5, updated_at = CURRENT_TIMESTAMP
+			WHERE job_id = $1 AND position = $2 AND status = $6
+			RETURNING status
+		)
+		UPDATE broadcast_jobs SET
+			sent = sent + (SELECT COUNT(*) FROM recipient WHERE status = $7),
+			failed = failed + (SELECT COUNT(*) FROM recipient WHERE status = $8),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING `+broadcastColumns,
+		jobID, position, status, messageID, reason, model.RecipientStatusSending,
+		model.RecipientStatusSent, model.RecipientStatusFailed))
+}
+
+// FailInterrupted fails recipients a previous run left in sending. They may or may not
+// have received the message, so they are not retried.
+func (r *BroadcastRepository) FailInterrupted(jobID int64) error {
+	_, err := r.DB.Exec(`
+		WITH interrupted AS (
+			UPDATE broadcast_recipients SET status = $2, error = 'interrupted by a restart; delivery unknown', updated_at = CURRENT_TIMESTAMP
+			WHERE job_id = $1 AND status = $3
+			RETURNING 1
+		)
+		UPDATE broadcast_jobs SET failed = failed + (SELECT COUNT(*) FROM interrupted), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`, jobID, model.RecipientStatusFailed, model.RecipientStatusSending)
+	return err
+}
+
+// Complete marks a running job completed. It returns nil, nil when the job was cancelled meanwhile.
+func (r *BroadcastRepository) Complete(jobID int64) (*model.BroadcastJob, error) {
+	job, err := scanBroadcastJob(r.DB.QueryRow(`
+		UPDATE broadcast_jobs SET status = $2, completed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $3
+		RETURNING `+broadcastColumns, jobID, model.BroadcastStatusCompleted, model.BroadcastStatusRunning))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return job, err
+}
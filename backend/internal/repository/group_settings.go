@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"wago-backend/internal/errs"
+	"wago-backend/internal/model"
+)
+
+const groupSettingColumns = `session_id, group_jid, enabled, mention_only, updated_at`
+
+func scanGroupSetting(row rowScanner) (*model.GroupSetting, error) {
+	var g model.GroupSetting
+	if err := row.Scan(&g.SessionID, &g.GroupJID, &g.Enabled, &g.MentionOnly, &g.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// GetGroupSetting returns the override for a group, or nil when the group uses the session default.
+func (r *SessionRepository) GetGroupSetting(sessionID, groupJID string) (*model.GroupSetting, error) {
+	row := r.DB.QueryRow(`SELECT `+groupSettingColumns+` FROM group_settings WHERE session_id = $1 AND group_jid = $2`, sessionID, groupJID)
+	g, err := scanGroupSetting(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return g, err
+}
+
+// ListGroupSettings returns a session's group overrides ordered by group JID.
+func (r *SessionRepository) ListGroupSettings(sessionID string) ([]model.GroupSetting, error) {
+	rows, err := r.DB.Query(`SELECT `+groupSettingColumns+` FROM group_settings WHERE session_id = $1 ORDER BY group_jid`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := []model.GroupSetting{}
+	for rows.Next() {
+		g, err := scanGroupSetting(rows)
+		if err != nil {
+			return nil, err
+		}
+		settings = append(settings, *g)
+	}
+	return settings, rows.Err()
+}
+
+// UpsertGroupSetting creates or replaces the override for a group.
+func (r *SessionRepository) UpsertGroupSetting(sessionID, groupJID string, enabled, mentionOnly bool) (*model.GroupSetting, error) {
+	row := r.DB.QueryRow(`
+		INSERT INTO group_settings (session_id, group_jid, enabled, mention_only) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (session_id, group_jid) DO UPDATE
+		SET enabled = EXCLUDED.enabled, mention_only = EXCLUDED.mention_only, updated_at = CURRENT_TIMESTAMP
+		RETURNING `+groupSettingColumns, sessionID, groupJID, enabled, mentionOnly)
+	return scanGroupSetting(row)
+}
+
+// DeleteGroupSetting removes a group's override so it falls back to the session default.
+func (r *SessionRepository) DeleteGroupSetting(sessionID, groupJID string) error {
+	res, err := r.DB.Exec(`DELETE FROM group_settings WHERE session_id = $1 AND group_jid = $2`, sessionID, groupJID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return errs.ErrGroupSettingNotFound
+	}
+	return nil
+}
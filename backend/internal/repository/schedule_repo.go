@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	"wago-backend/internal/errs"
+	"wago-backend/internal/model"
+
+	"github.com/lib/pq"
+)
+
+type ScheduleRepository struct {
+	DB *sql.DB
+}
+
+func NewScheduleRepository(db *sql.DB) *ScheduleRepository {
+	return &ScheduleRepository{DB: db}
+}
+
+const scheduleColumns = `id, session_id, recipient, message, send_at, status, message_id, error, sent_at, created_at, updated_at`
+
+func scanScheduledMessage(row rowScanner) (*model.ScheduledMessage, error) {
+	var m model.ScheduledMessage
+	var sentAt sql.NullTime
+	if err := row.Scan(&m.ID, &m.SessionID, &m.Recipient, &m.Message, &m.SendAt, &m.Status,
+		&m.MessageID, &m.Error, &sentAt, &m.CreatedAt, &m.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if sentAt.Valid {
+		m.SentAt = &sentAt.Time
+	}
+	return &m, nil
+}
+
+func (r *ScheduleRepository) queryScheduled(query string, args ...interface{}) ([]model.ScheduledMessage, error) {
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []model.ScheduledMessage{}
+	for rows.Next() {
+		m, err := scanScheduledMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, *m)
+	}
+	return messages, rows.Err()
+}
+
+// Create queues a pending message; sendAt is stored in UTC.
+func (r *ScheduleRepository) Create(sessionID, recipient, message string, sendAt time.Time) (*model.ScheduledMessage, error) {
+	row := r.DB.QueryRow(`
+		INSERT INTO scheduled_messages (session_id, recipient, message, send_at) VALUES ($1, $2, $3, $4)
+		RETURNING `+scheduleColumns, sessionID, recipient, message, sendAt.UTC())
+	return scanScheduledMessage(row)
+}
+
+// List returns a session's scheduled messages, latest send time first, optionally only those in status.
+func (r *ScheduleRepository) List(sessionID, status string) ([]model.ScheduledMessage, error) {
+	if status == "" {
+		return r.queryScheduled(`SELECT `+scheduleColumns+` FROM scheduled_messages WHERE session_id = $1 ORDER BY send_at DESC, id DESC`, sessionID)
+	}
+	return r.queryScheduled(`SELECT `+scheduleColumns+` FROM scheduled_messages WHERE session_id = $1 AND status = $2 ORDER BY send_at DESC, id DESC`, sessionID, status)
+}
+
+// Cancel marks a pending message cancelled. Messages the scheduler already picked up
+// yield errs.ErrScheduleNotPending.
+func (r *ScheduleRepository) Cancel(sessionID string, id int64) (*model.ScheduledMessage, error) {
+	row := r.DB.QueryRow(`
+		UPDATE scheduled_messages SET status = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE session_id = $1 AND id = $2 AND status = $4
+		RETURNING `+scheduleColumns, sessionID, id, model.ScheduleStatusCancelled, model.ScheduleStatusPending)
+	m, err := scanScheduledMessage(row)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return m, err
+	}
+
+	var exists bool
+	if err := r.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM scheduled_messages WHERE session_id = $1 AND id = $2)`, sessionID, id).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errs.ErrScheduleNotPending
+	}
+	return nil, errs.ErrScheduledMessageNotFound
+}
+
+// ListDue returns up to limit pending messages due at now for the given sessions, oldest first.
+func (r *ScheduleRepository) ListDue(now time.Time, sessionIDs []string, limit int) ([]model.ScheduledMessage, error) {
+	return r.queryScheduled(`
+		SELECT `+scheduleColumns+` FROM scheduled_messages
+		WHERE status = $1 AND send_at <= $2 AND session_id::text = ANY($3)
+		ORDER BY send_at, id
+		LIMIT $4`, model.ScheduleStatusPending, now.UTC(), pq.Array(sessionIDs), limit)
+}
+
+// Claim moves a pending message to sending. It reports false when the message was
+// cancelled or claimed by someone else in the meantime.
+func (r *ScheduleRepository) Claim(id int64) (bool, error) {
+	res, err := r.DB.Exec(`
+		UPDATE scheduled_messages SET status = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $3`, id, model.ScheduleStatusSending, model.ScheduleStatusPending)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// MarkSent records a delivered message.
+func (r *ScheduleRepository) MarkSent(id int64, messageID string, sentAt time.Time) error {
+	_, err := r.DB.Exec(`
+		UPDATE scheduled_messages SET status = $2, message_id = $3, sent_at = $4, error = '', updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`, id, model.ScheduleStatusSent, messageID, sentAt.UTC())
+	return err
+}
+
+// MarkFailed records why a message could not be sent.
+func (r *ScheduleRepository) MarkFailed(id int64, reason string) error {
+	_, err := r.DB.Exec(`
+		UPDATE scheduled_messages SET status = $2, error = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`, id, model.ScheduleStatusFailed, reason)
+	return err
+}
+
+// Release returns a claimed message to pending so a later poll retries it.
+func (r *ScheduleRepository) Release(id int64) error {
+	_, err := r.DB.Exec(`
+		UPDATE scheduled_messages SET status = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $3`, id, model.ScheduleStatusPending, model.ScheduleStatusSending)
+	return err
+}
+
+// FailInterrupted fails messages left in sending by a previous process. They may or may
+// not have gone out, so they are not retried automatically.
+func (r *ScheduleRepository) FailInterrupted() (int64, error) {
+	res, err := r.DB.Exec(`
+		UPDATE scheduled_messages SET status = $1, error = 'interrupted by a restart; delivery unknown', updated_at = CURRENT_TIMESTAMP
+		WHERE status = $2`, model.ScheduleStatusFailed, model.ScheduleStatusSending)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// FailOverdue fails pending messages due before cutoff whose session is not among connected.
+func (r *ScheduleRepository) FailOverdue(cutoff time.Time, connected []string) (int64, error) {
+	res, err := r.DB.Exec(`
+		UPDATE scheduled_messages SET status = $1, error = 'session was not connected at send time', updated_at = CURRENT_TIMESTAMP
+		WHERE status = $2 AND send_at < $3 AND NOT (session_id::text = ANY($4))`,
+		model.ScheduleStatusFailed, model.ScheduleStatusPending, cutoff.UTC(), pq.Array(connected))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -4,15 +4,22 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"wago-backend/internal/errs"
 	"wago-backend/internal/model"
+	"wago-backend/internal/utils"
 )
 
 type SessionRepository struct {
 	DB *sql.DB
+	// Cipher encrypts sensitive columns (webhook_secret); nil stores them as plaintext.
+	Cipher *utils.Cipher
 }
 
-func NewSessionRepository(db *sql.DB) *SessionRepository {
-	return &SessionRepository{DB: db}
+func NewSessionRepository(db *sql.DB, cipher *utils.Cipher) *SessionRepository {
+	return &SessionRepository{DB: db, Cipher: cipher}
 }
 
 func (r *SessionRepository) CreateSession(session *model.Session) (*model.Session, error) {
@@ -39,220 +46,223 @@ func (r *SessionRepository) CreateSession(session *model.Session) (*model.Sessio
 
 func (r *SessionRepository) GetSessionsByUserID(userID string) ([]*model.Session, error) {
 	query := `
-		SELECT id, session_name, webhook_url, status, phone_number, last_connected, is_group_response_enabled, created_at, updated_at
+		SELECT ` + sessionColumns + `
 		FROM sessions
 		WHERE user_id = $1
 		ORDER BY created_at DESC`
 
-	rows, err := r.DB.Query(query, userID)
+	return r.querySessions(query, userID)
+}
+
+// GetSessionsByUserIDAndTag returns the user's sessions labelled with tag.
+func (r *SessionRepository) GetSessionsByUserIDAndTag(userID, tag string) ([]*model.Session, error) {
+	filter, err := json.Marshal([]string{tag})
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var sessions []*model.Session
-	for rows.Next() {
-		var s model.Session
-		var lastConnected sql.NullTime
-		var phoneNumber sql.NullString
-
-		err := rows.Scan(
-			&s.ID,
-			&s.SessionName,
-			&s.WebhookURL,
-			&s.Status,
-			&phoneNumber,
-			&lastConnected,
-			&s.IsGroupResponseEnabled,
-			&s.CreatedAt,
-			&s.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		if lastConnected.Valid {
-			s.LastConnected = &lastConnected.Time
-		}
-		if phoneNumber.Valid {
-			s.PhoneNumber = phoneNumber.String
-		}
 
-		sessions = append(sessions, &s)
-	}
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE user_id = $1 AND tags @> $2::jsonb
+		ORDER BY created_at DESC`
 
-	return sessions, nil
+	return r.querySessions(query, userID, string(filter))
 }
 
+// GetSessionByID returns errs.ErrSessionNotFound when no row matches.
 func (r *SessionRepository) GetSessionByID(id string) (*model.Session, error) {
-	var s model.Session
-	var lastConnected sql.NullTime
-	var phoneNumber sql.NullString
-	var deviceInfo []byte
-
 	query := `
-		SELECT id, user_id, session_name, webhook_url, status, phone_number, device_info, last_connected, is_group_response_enabled, created_at, updated_at
+		SELECT ` + sessionColumns + `
 		FROM sessions
 		WHERE id = $1`
 
-	err := r.DB.QueryRow(query, id).Scan(
-		&s.ID,
-		&s.UserID,
-		&s.SessionName,
-		&s.WebhookURL,
-		&s.Status,
-		&phoneNumber,
-		&deviceInfo,
-		&lastConnected,
-		&s.IsGroupResponseEnabled,
-		&s.CreatedAt,
-		&s.UpdatedAt,
-	)
-
+	s, err := r.scanSession(r.DB.QueryRow(query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
+			return nil, errs.ErrSessionNotFound
 		}
 		return nil, err
 	}
 
-	if lastConnected.Valid {
-		s.LastConnected = &lastConnected.Time
+	return s, nil
+}
+
+// updatableSessionColumns whitelists the columns UpdateFields may write.
+var updatableSessionColumns = map[string]bool{
+	"session_name":              true,
+	"webhook_url":               true,
+	"is_group_response_enabled": true,
+	"busy_reply_text":           true,
+	"busy_reply_grace_ms":       true,
+	"dry_run":                   true,
+	"reply_privately_in_groups": true,
+	"trigger_pattern":           true,
+	"webhook_secret":            true,
+	"mark_read_on_success":      true,
+	"webhook_format":            true,
+	"webhook_method":            true,
+	"tags":                      true,
+	"webhook_include_fields":    true,
+	"webhook_exclude_fields":    true,
+	"ingest_history":            true,
+	"process_own_messages":      true,
+	"reply_footer":              true,
+	"webhook_stream":            true,
+	"webhook_retries":           true,
+	"webhook_timeout_seconds":   true,
+	"auto_mark_read":            true,
+	"webhook_status_events":     true,
+	"quote_replies":             true,
+	"typing_delay":              true,
+}
+
+// SetChatMuted adds chat to or removes it from a user's session muted_chats list.
+// Both are idempotent and done in SQL so concurrent calls don't lose updates.
+func (r *SessionRepository) SetChatMuted(id, userID, chat string, muted bool) error {
+	query := `UPDATE sessions SET muted_chats = muted_chats - $3::text, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2`
+	if muted {
+		query = `UPDATE sessions SET muted_chats = (muted_chats - $3::text) || jsonb_build_array($3::text), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2`
 	}
-	if phoneNumber.Valid {
-		s.PhoneNumber = phoneNumber.String
+
+	res, err := r.DB.Exec(query, id, userID, chat)
+	if err != nil {
+		return err
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return errs.ErrSessionNotFound
+	}
+	return nil
+}
+
+// encryptedSessionColumns are sealed with the repository Cipher before being written.
+var encryptedSessionColumns = map[string]bool{
+	"webhook_secret": true,
+}
+
+// UpdateFields updates only the given columns of a user's session, leaving the rest untouched.
+func (r *SessionRepository) UpdateFields(id, userID string, fields map[string]interface{}) error {
+	columns := make([]string, 0, len(fields))
+	for column := range fields {
+		if !updatableSessionColumns[column] {
+			return fmt.Errorf("column %q cannot be updated", column)
+		}
+		columns = append(columns, column)
 	}
-	if deviceInfo != nil {
-		// Assuming DeviceInfo implements Scanner, but here we scan into []byte first to be safe or if jsonb is null
-		// Actually, let's use the model's Scan method if we passed &s.DeviceInfo directly, but s.DeviceInfo is a pointer.
-		// So we need to handle it.
-		// For simplicity in this raw query scan, let's just unmarshal if not nil.
-		// But wait, I defined Scan on *DeviceInfo.
-		// Let's re-scan properly or just unmarshal here.
-		s.DeviceInfo = &model.DeviceInfo{}
-		if err := json.Unmarshal(deviceInfo, s.DeviceInfo); err != nil {
-			// ignore error or log it, maybe it's null
-			s.DeviceInfo = nil
+	sort.Strings(columns)
+
+	sets := make([]string, 0, len(columns)+1)
+	args := make([]interface{}, 0, len(columns)+2)
+	for _, column := range columns {
+		value := fields[column]
+		if encryptedSessionColumns[column] {
+			plaintext, _ := value.(string)
+			sealed, err := r.Cipher.Encrypt(plaintext)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt %s: %w", column, err)
+			}
+			value = sealed
 		}
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
 	}
+	sets = append(sets, "updated_at = CURRENT_TIMESTAMP")
+	args = append(args, id, userID)
 
-	return &s, nil
+	query := fmt.Sprintf(`UPDATE sessions SET %s WHERE id = $%d AND user_id = $%d`,
+		strings.Join(sets, ", "), len(args)-1, len(args))
+
+	res, err := r.DB.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return errs.ErrSessionNotFound
+	}
+	return nil
 }
 
-func (r *SessionRepository) UpdateSession(session *model.Session) error {
-	query := `
+// UpdatePhoneNumber stores the session's full JID without touching its status, and only when it
+// changed, so it can't race with status writes from connection events.
+func (r *SessionRepository) UpdatePhoneNumber(id, phoneNumber string) error {
+	_, err := r.DB.Exec(`
 		UPDATE sessions
-		SET session_name = $1, webhook_url = $2, is_group_response_enabled = $3, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $4 AND user_id = $5`
-
-	_, err := r.DB.Exec(query, session.SessionName, session.WebhookURL, session.IsGroupResponseEnabled, session.ID, session.UserID)
+		SET phone_number = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND phone_number IS DISTINCT FROM $2`, id, phoneNumber)
 	return err
 }
 
+// UpdateSessionStatus sets the status. The stored phone number and device info are kept
+// when nil is passed for them, so status-only updates (QR, disconnects) don't erase them.
 func (r *SessionRepository) UpdateSessionStatus(id string, status model.SessionStatus, phoneNumber *string, deviceInfo *model.DeviceInfo) error {
-	var query string
-	var args []interface{}
-
+	lastConnected := ""
 	if status == model.SessionStatusConnected {
-		query = `
-			UPDATE sessions
-			SET status = $1,
-			    phone_number = COALESCE($2, phone_number),
-			    device_info = $3,
-			    updated_at = CURRENT_TIMESTAMP,
-			    last_connected = CURRENT_TIMESTAMP
-			WHERE id = $4`
-		args = []interface{}{status, phoneNumber, deviceInfo, id}
-	} else {
-		if phoneNumber != nil {
-			query = `
-				UPDATE sessions
-				SET status = $1,
-				    phone_number = $2,
-				    device_info = $3,
-				    updated_at = CURRENT_TIMESTAMP
-				WHERE id = $4`
-			args = []interface{}{status, phoneNumber, deviceInfo, id}
-		} else {
-			query = `
-				UPDATE sessions
-				SET status = $1,
-				    device_info = $3,
-				    updated_at = CURRENT_TIMESTAMP
-				WHERE id = $4`
-			args = []interface{}{status, deviceInfo, id}
-		}
+		lastConnected = ", last_connected = CURRENT_TIMESTAMP"
 	}
+	query := `
+		UPDATE sessions
+		SET status = $1,
+		    phone_number = COALESCE($2, phone_number),
+		    device_info = COALESCE($3, device_info),
+		    updated_at = CURRENT_TIMESTAMP` + lastConnected + `
+		WHERE id = $4`
 
-	res, err := r.DB.Exec(query, args...)
+	res, err := r.DB.Exec(query, status, phoneNumber, deviceInfo, id)
 	if err != nil {
 		return err
 	}
 
 	if rows, _ := res.RowsAffected(); rows == 0 {
-		return errors.New("no session updated (invalid session id)")
+		return errs.ErrSessionNotFound
 	}
 	return nil
 }
 
+// UpdateDeviceInfo replaces the stored device info without touching status or phone number.
+func (r *SessionRepository) UpdateDeviceInfo(id string, deviceInfo *model.DeviceInfo) error {
+	query := `UPDATE sessions SET device_info = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+
+	res, err := r.DB.Exec(query, deviceInfo, id)
+	if err != nil {
+		return err
+	}
+
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return errs.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *SessionRepository) CountSessionsByUserID(userID string) (int, error) {
+	var count int
+	err := r.DB.QueryRow(`SELECT COUNT(*) FROM sessions WHERE user_id = $1`, userID).Scan(&count)
+	return count, err
+}
+
+// DeleteSession deletes the user's session, returning ErrSessionNotFound when no row of
+// theirs matched.
 func (r *SessionRepository) DeleteSession(id string, userID string) error {
 	query := `DELETE FROM sessions WHERE id = $1 AND user_id = $2`
-	_, err := r.DB.Exec(query, id, userID)
-	return err
+	res, err := r.DB.Exec(query, id, userID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return errs.ErrSessionNotFound
+	}
+	return nil
 }
 
 func (r *SessionRepository) GetSessionsByStatus(status model.SessionStatus) ([]*model.Session, error) {
 	query := `
-		SELECT id, user_id, session_name, webhook_url, status, phone_number, device_info, last_connected, is_group_response_enabled, created_at, updated_at
+		SELECT ` + sessionColumns + `
 		FROM sessions
 		WHERE status = $1`
 
-	rows, err := r.DB.Query(query, status)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var sessions []*model.Session
-	for rows.Next() {
-		var s model.Session
-		var lastConnected sql.NullTime
-		var phoneNumber sql.NullString
-		var deviceInfo []byte
-
-		err := rows.Scan(
-			&s.ID,
-			&s.UserID,
-			&s.SessionName,
-			&s.WebhookURL,
-			&s.Status,
-			&phoneNumber,
-			&deviceInfo,
-			&lastConnected,
-			&s.IsGroupResponseEnabled,
-			&s.CreatedAt,
-			&s.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		if lastConnected.Valid {
-			s.LastConnected = &lastConnected.Time
-		}
-		if phoneNumber.Valid {
-			s.PhoneNumber = phoneNumber.String
-		}
-		if deviceInfo != nil {
-			s.DeviceInfo = &model.DeviceInfo{}
-			if err := json.Unmarshal(deviceInfo, s.DeviceInfo); err != nil {
-				s.DeviceInfo = nil
-			}
-		}
-
-		sessions = append(sessions, &s)
-	}
-	return sessions, nil
+	return r.querySessions(query, status)
 }
 
 // GetSessionsWithPhoneNumber returns all sessions that have a stored JID/phone_number.
@@ -260,11 +270,22 @@ func (r *SessionRepository) GetSessionsByStatus(status model.SessionStatus) ([]*
 // was not left as "connected" (e.g. after an unexpected restart).
 func (r *SessionRepository) GetSessionsWithPhoneNumber() ([]*model.Session, error) {
 	query := `
-		SELECT id, user_id, session_name, webhook_url, status, phone_number, device_info, last_connected, is_group_response_enabled, created_at, updated_at
+		SELECT ` + sessionColumns + `
 		FROM sessions
 		WHERE phone_number IS NOT NULL AND phone_number <> ''`
 
-	rows, err := r.DB.Query(query)
+	return r.querySessions(query)
+}
+
+// sessionColumns is the column list every session read selects, in scanSession order.
+const sessionColumns = `id, user_id, session_name, webhook_url, webhook_secret, status, phone_number, device_info, last_connected, is_group_response_enabled, busy_reply_text, busy_reply_grace_ms, dry_run, reply_privately_in_groups, trigger_pattern, mark_read_on_success, webhook_format, webhook_method, tags, webhook_include_fields, webhook_exclude_fields, ingest_history, process_own_messages, reply_footer, webhook_stream, webhook_retries, webhook_timeout_seconds, auto_mark_read, webhook_status_events, quote_replies, typing_delay, muted_chats, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (r *SessionRepository) querySessions(query string, args ...interface{}) ([]*model.Session, error) {
+	rows, err := r.DB.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -272,42 +293,93 @@ func (r *SessionRepository) GetSessionsWithPhoneNumber() ([]*model.Session, erro
 
 	var sessions []*model.Session
 	for rows.Next() {
-		var s model.Session
-		var lastConnected sql.NullTime
-		var phoneNumber sql.NullString
-		var deviceInfo []byte
-
-		err := rows.Scan(
-			&s.ID,
-			&s.UserID,
-			&s.SessionName,
-			&s.WebhookURL,
-			&s.Status,
-			&phoneNumber,
-			&deviceInfo,
-			&lastConnected,
-			&s.IsGroupResponseEnabled,
-			&s.CreatedAt,
-			&s.UpdatedAt,
-		)
+		s, err := r.scanSession(rows)
 		if err != nil {
 			return nil, err
 		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
 
-		if lastConnected.Valid {
-			s.LastConnected = &lastConnected.Time
-		}
-		if phoneNumber.Valid {
-			s.PhoneNumber = phoneNumber.String
-		}
-		if deviceInfo != nil {
-			s.DeviceInfo = &model.DeviceInfo{}
-			if err := json.Unmarshal(deviceInfo, s.DeviceInfo); err != nil {
-				s.DeviceInfo = nil
-			}
-		}
+func (r *SessionRepository) scanSession(row rowScanner) (*model.Session, error) {
+	var s model.Session
+	var lastConnected sql.NullTime
+	var phoneNumber sql.NullString
+	var deviceInfo []byte
+
+	err := row.Scan(
+		&s.ID,
+		&s.UserID,
+		&s.SessionName,
+		&s.WebhookURL,
+		&s.WebhookSecret,
+		&s.Status,
+		&phoneNumber,
+		&deviceInfo,
+		&lastConnected,
+		&s.IsGroupResponseEnabled,
+		&s.BusyReplyText,
+		&s.BusyReplyGraceMs,
+		&s.DryRun,
+		&s.ReplyPrivatelyInGroups,
+		&s.TriggerPattern,
+		&s.MarkReadOnSuccess,
+		&s.WebhookFormat,
+		&s.WebhookMethod,
+		&s.Tags,
+		&s.WebhookIncludeFields,
+		&s.WebhookExcludeFields,
+		&s.IngestHistory,
+		&s.ProcessOwnMessages,
+		&s.ReplyFooter,
+		&s.WebhookStream,
+		&s.WebhookRetries,
+		&s.WebhookTimeoutSeconds,
+		&s.AutoMarkRead,
+		&s.WebhookStatusEvents,
+		&s.QuoteReplies,
+		&s.TypingDelay,
+		&s.MutedChats,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastConnected.Valid {
+		s.LastConnected = &lastConnected.Time
+	}
+	if phoneNumber.Valid {
+		s.PhoneNumber = phoneNumber.String
+	}
+	s.DeviceInfo = decodeDeviceInfo(deviceInfo)
 
-		sessions = append(sessions, &s)
+	secret, err := r.Cipher.Decrypt(s.WebhookSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt webhook secret for session %s: %w", s.ID, err)
+	}
+	s.WebhookSecret = secret
+	s.HasWebhookSecret = secret != ""
+
+	return &s, nil
+}
+
+// decodeDeviceInfo turns the raw device_info JSONB into a struct. NULL, JSON null,
+// an empty object and undecodable data all yield nil so the API never returns an
+// empty device_info object.
+func decodeDeviceInfo(raw []byte) *model.DeviceInfo {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var info model.DeviceInfo
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return nil
+	}
+	if info == (model.DeviceInfo{}) {
+		return nil
 	}
-	return sessions, nil
+	return &info
 }
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"wago-backend/internal/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestDecodeDeviceInfo(t *testing.T) {
+	cases := []struct {
+		name string
+		raw  []byte
+		want *model.DeviceInfo
+	}{
+		{"NULL", nil, nil},
+		{"empty", []byte{}, nil},
+		{"JSON null", []byte("null"), nil},
+		{"empty object", []byte("{}"), nil},
+		{"only unknown keys", []byte(`{"battery": 80}`), nil},
+		{"undecodable", []byte(`{"platform":`), nil},
+		{"populated", []byte(`{"platform":"android","device_model":"Pixel 8","push_name":"Shop"}`),
+			&model.DeviceInfo{Platform: "android", DeviceModel: "Pixel 8", PushName: "Shop"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := decodeDeviceInfo(tc.raw); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("decodeDeviceInfo(%s) = %+v, want %+v", tc.raw, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestGetSessionDeviceInfo checks that device_info survives the scan from a NULL or JSONB column.
+func TestGetSessionDeviceInfo(t *testing.T) {
+	cases := []struct {
+		name   string
+		column interface{}
+		want   *model.DeviceInfo
+	}{
+		{"NULL", nil, nil},
+		{"empty object", []byte("{}"), nil},
+		{"populated", []byte(`{"platform":"ios","business_name":"Toko"}`), &model.DeviceInfo{Platform: "ios", BusinessName: "Toko"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			now := time.Date(2025, 1, 15, 9, 30, 0, 0, time.UTC)
+			rows := sqlmock.NewRows(strings.Split(sessionColumns, ", ")).AddRow(
+				"s1", "user-1", "test", "http://webhook.test/hook", "", "connected", "628111111111", tc.column, now, false,
+				"", 0, false, false, "", false, "json", "POST", []byte("[]"), []byte("[]"), []byte("[]"), false, false,
+				"", false, 0, 0, false, false, false, false, []byte("[]"), now, now)
+			mock.ExpectQuery("FROM sessions").WithArgs("s1").WillReturnRows(rows)
+
+			session, err := NewSessionRepository(db, nil).GetSessionByID("s1")
+			if err != nil {
+				t.Fatalf("GetSessionByID: %v", err)
+			}
+			if !reflect.DeepEqual(session.DeviceInfo, tc.want) {
+				t.Errorf("DeviceInfo = %+v, want %+v", session.DeviceInfo, tc.want)
+			}
+		})
+	}
+}
+
+// TestUpdateSessionStatusKeepsDeviceInfo checks that status-only updates, such as a disconnect
+// or a new QR code, bind nil for the phone number and device info without overwriting them.
+func TestUpdateSessionStatusKeepsDeviceInfo(t *testing.T) {
+	cases := []struct {
+		status            model.SessionStatus
+		wantLastConnected bool
+	}{
+		{model.SessionStatusDisconnected, false},
+		{model.SessionStatusQR, false},
+		{model.SessionStatusConnected, true},
+	}
+	for _, tc := range cases {
+		t.Run(string(tc.status), func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			pattern := regexp.QuoteMeta("phone_number = COALESCE($2, phone_number)") + "(?s).*" +
+				regexp.QuoteMeta("device_info = COALESCE($3, device_info)")
+			if tc.wantLastConnected {
+				pattern += ".*last_connected = CURRENT_TIMESTAMP"
+			}
+			mock.ExpectExec(pattern).
+				WithArgs(tc.status, nil, nil, "s1").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			if err := NewSessionRepository(db, nil).UpdateSessionStatus("s1", tc.status, nil, nil); err != nil {
+				t.Fatalf("UpdateSessionStatus: %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"wago-backend/internal/errs"
+	"wago-backend/internal/model"
+)
+
+type TemplateRepository struct {
+	DB *sql.DB
+}
+
+func NewTemplateRepository(db *sql.DB) *TemplateRepository {
+	return &TemplateRepository{DB: db}
+}
+
+const templateColumns = `id, session_id, name, body, created_at, updated_at`
+
+func scanTemplate(row rowScanner) (*model.MessageTemplate, error) {
+	var t model.MessageTemplate
+	if err := row.Scan(&t.ID, &t.SessionID, &t.Name, &t.Body, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// List returns a session's templates ordered by name.
+func (r *TemplateRepository) List(sessionID string) ([]model.MessageTemplate, error) {
+	rows, err := r.DB.Query(`SELECT `+templateColumns+` FROM message_templates WHERE session_id = $1 ORDER BY name`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []model.MessageTemplate{}
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *t)
+	}
+	return templates, rows.Err()
+}
+
+func (r *TemplateRepository) Get(sessionID, name string) (*model.MessageTemplate, error) {
+	row := r.DB.QueryRow(`SELECT `+templateColumns+` FROM message_templates WHERE session_id = $1 AND name = $2`, sessionID, name)
+	t, err := scanTemplate(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrTemplateNotFound
+	}
+	return t, err
+}
+
+// Create stores a new template; names are unique per session.
+func (r *TemplateRepository) Create(sessionID, name, body string) (*model.MessageTemplate, error) {
+	row := r.DB.QueryRow(`
+		INSERT INTO message_templates (session_id, name, body) VALUES ($1, $2, $3)
+		ON CONFLICT (session_id, name) DO NOTHING
+		RETURNING `+templateColumns, sessionID, name, body)
+	t, err := scanTemplate(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrTemplateExists
+	}
+	return t, err
+}
+
+func (r *TemplateRepository) Update(sessionID, name, body string) (*model.MessageTemplate, error) {
+	row := r.DB.QueryRow(`
+		UPDATE message_templates SET body = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE session_id = $1 AND name = $2
+		RETURNING `+templateColumns, sessionID, name, body)
+	t, err := scanTemplate(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrTemplateNotFound
+	}
+	return t, err
+}
+
+func (r *TemplateRepository) Delete(sessionID, name string) error {
+	res, err := r.DB.Exec(`DELETE FROM message_templates WHERE session_id = $1 AND name = $2`, sessionID, name)
+	if err != nil {
+		return err
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return errs.ErrTemplateNotFound
+	}
+	return nil
+}
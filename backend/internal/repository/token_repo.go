@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+)
+
+// TokenRepository stores the IDs (jti) of JWTs revoked before their expiry.
+type TokenRepository struct {
+	DB *sql.DB
+}
+
+func NewTokenRepository(db *sql.DB) *TokenRepository {
+	return &TokenRepository{DB: db}
+}
+
+// RevokeToken records jti as revoked until expiresAt, and drops rows for tokens that
+// have expired since, which no longer need to be remembered. It reports whether this call
+// revoked the token; false means it was already revoked, so callers can use it as an atomic
+// claim on a single-use token.
+func (r *TokenRepository) RevokeToken(jti, userID string, expiresAt time.Time) (bool, error) {
+	query := `INSERT INTO revoked_tokens (jti, user_id, expires_at) VALUES ($1, $2, $3) ON CONFLICT (jti) DO NOTHING`
+	res, err := r.DB.Exec(query, jti, userID, expiresAt.UTC())
+	if err != nil {
+		return false, err
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	_, err = r.DB.Exec(`DELETE FROM revoked_tokens WHERE expires_at < $1`, time.Now().UTC())
+	return inserted > 0, err
+}
+
+// IsTokenRevoked reports whether jti was revoked. A nil repository revokes nothing.
+func (r *TokenRepository) IsTokenRevoked(jti string) (bool, error) {
+	if r == nil {
+		return false, nil
+	}
+	var revoked bool
+	err := r.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)`, jti).Scan(&revoked)
+	return revoked, err
+}
@@ -20,11 +20,12 @@ func (r *UserRepository) CreateUser(pin string) (*model.User, error) {
 	query := `
 		INSERT INTO users (pin) 
 		VALUES ($1) 
-		RETURNING id, pin, created_at, updated_at, last_login`
+		RETURNING id, pin, pin_rotated_at, created_at, updated_at, last_login`
 
 	err := r.DB.QueryRow(query, pin).Scan(
 		&user.ID,
 		&user.PIN,
+		&user.PINRotatedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.LastLogin,
@@ -40,13 +41,14 @@ func (r *UserRepository) CreateUser(pin string) (*model.User, error) {
 func (r *UserRepository) GetUserByPIN(pin string) (*model.User, error) {
 	var user model.User
 	query := `
-		SELECT id, pin, created_at, updated_at, last_login 
+		SELECT id, pin, pin_rotated_at, created_at, updated_at, last_login 
 		FROM users 
 		WHERE pin = $1`
 
 	err := r.DB.QueryRow(query, pin).Scan(
 		&user.ID,
 		&user.PIN,
+		&user.PINRotatedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.LastLogin,
@@ -67,3 +69,28 @@ func (r *UserRepository) UpdateLastLogin(userID string) error {
 	_, err := r.DB.Exec(query, time.Now(), userID)
 	return err
 }
+
+// RotatePIN replaces the user's PIN and restarts its expiry clock.
+func (r *UserRepository) RotatePIN(userID, pin string) (*model.User, error) {
+	var user model.User
+	query := `
+		UPDATE users SET pin = $1, pin_rotated_at = NOW(), updated_at = NOW()
+		WHERE id = $2
+		RETURNING id, pin, pin_rotated_at, created_at, updated_at, last_login`
+
+	err := r.DB.QueryRow(query, pin, userID).Scan(
+		&user.ID,
+		&user.PIN,
+		&user.PINRotatedAt,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.LastLogin,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
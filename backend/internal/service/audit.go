@@ -0,0 +1,17 @@
+package service
+
+import (
+	"log"
+	"wago-backend/internal/repository"
+)
+
+// recordAudit writes an audit entry. Failures are logged rather than returned so
+// auditing never blocks the action being audited.
+func recordAudit(repo *repository.AuditRepository, actor, action, target string) {
+	if repo == nil {
+		return
+	}
+	if err := repo.Record(actor, action, target); err != nil {
+		log.Printf("Failed to record audit entry %s for %s: %v", action, target, err)
+	}
+}
@@ -2,79 +2,221 @@ package service
 
 import (
 	"errors"
+	"strings"
 	"time"
 	"wago-backend/internal/config"
 	"wago-backend/internal/model"
 	"wago-backend/internal/repository"
 	"wago-backend/internal/utils"
-
-	"github.com/golang-jwt/jwt/v5"
 )
 
 type AuthService struct {
-	UserRepo *repository.UserRepository
-	Config   *config.Config
+	UserRepo  *repository.UserRepository
+	AuditRepo *repository.AuditRepository
+	TokenRepo *repository.TokenRepository
+	Config    *config.Config
 }
 
-func NewAuthService(userRepo *repository.UserRepository, cfg *config.Config) *AuthService {
+func NewAuthService(userRepo *repository.UserRepository, auditRepo *repository.AuditRepository, tokenRepo *repository.TokenRepository, cfg *config.Config) *AuthService {
 	return &AuthService{
-		UserRepo: userRepo,
-		Config:   cfg,
+		UserRepo:  userRepo,
+		AuditRepo: auditRepo,
+		TokenRepo: tokenRepo,
+		Config:    cfg,
 	}
 }
 
-func (s *AuthService) GeneratePIN() (*model.User, error) {
-	// Generate unique PIN
-	var pin string
-	var err error
-
+// uniquePIN generates a PIN no user has yet.
+func (s *AuthService) uniquePIN() (string, error) {
 	// Try up to 5 times to generate a unique PIN
 	for i := 0; i < 5; i++ {
-		pin, err = utils.GeneratePIN(6)
+		pin, err := utils.GeneratePIN(6)
 		if err != nil {
-			return nil, err
+			return "", err
 		}
 
 		// Check if PIN exists
 		existingUser, err := s.UserRepo.GetUserByPIN(pin)
 		if err != nil {
-			return nil, err
+			return "", err
 		}
 		if existingUser == nil {
-			break
-		}
-		if i == 4 {
-			return nil, errors.New("failed to generate unique PIN")
+			return pin, nil
 		}
 	}
+	return "", errors.New("failed to generate unique PIN")
+}
+
+func (s *AuthService) GeneratePIN() (*model.User, error) {
+	pin, err := s.uniquePIN()
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.UserRepo.CreateUser(pin)
+	if err != nil {
+		return nil, err
+	}
+	recordAudit(s.AuditRepo, user.ID, model.AuditPINGenerated, user.ID)
+	return user, nil
+}
 
-	return s.UserRepo.CreateUser(pin)
+// AuthTokens are issued on login and refresh. The refresh token expires with the login
+// itself (JWTRefreshExpiry after the PIN was entered), so refreshing never extends it.
+type AuthTokens struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
 }
 
-func (s *AuthService) Login(pin string) (string, *model.User, error) {
+// Login exchanges a PIN for tokens. clientIP identifies failed attempts in the audit log.
+func (s *AuthService) Login(pin, clientIP string) (*AuthTokens, *model.User, error) {
 	user, err := s.UserRepo.GetUserByPIN(pin)
 	if err != nil {
-		return "", nil, err
+		return nil, nil, err
 	}
 	if user == nil {
-		return "", nil, errors.New("invalid credentials")
+		recordAudit(s.AuditRepo, "ip:"+clientIP, model.AuditLoginFailed, "")
+		return nil, nil, errors.New("invalid credentials")
+	}
+	if user.PINExpired(s.Config.PINMaxAge, time.Now()) {
+		return nil, nil, errors.New("PIN expired, rotate it with POST /auth/pin/rotate using a signed-in token")
 	}
 
 	// Update last login
 	if err := s.UserRepo.UpdateLastLogin(user.ID); err != nil {
-		return "", nil, err
+		return nil, nil, err
 	}
 
-	// Generate JWT
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
-	})
+	tokens, err := s.issueTokens(user.ID, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
 
-	tokenString, err := token.SignedString([]byte(s.Config.JWTSecret))
+	recordAudit(s.AuditRepo, user.ID, model.AuditLogin, user.ID)
+	return tokens, user, nil
+}
+
+// RotatePIN gives the user a new unique PIN; the old one stops working immediately.
+func (s *AuthService) RotatePIN(userID string) (*model.User, error) {
+	pin, err := s.uniquePIN()
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.UserRepo.RotatePIN(userID, pin)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
+	recordAudit(s.AuditRepo, userID, model.AuditPINRotated, userID)
+	return user, nil
+}
+
+// Authenticate validates an access token, rejecting revoked ones, and returns its user ID.
+func (s *AuthService) Authenticate(token string) (string, error) {
+	return utils.AuthenticateToken(token, s.Config.JWTSecret, s.TokenRepo)
+}
 
-	return tokenString, user, nil
+// PINOwner returns the ID of the user with this PIN. An expired PIN is rejected, so a
+// leaked old PIN can't be used to mint a fresh one.
+func (s *AuthService) PINOwner(pin string) (string, error) {
+	user, err := s.UserRepo.GetUserByPIN(strings.TrimSpace(pin))
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		return "", errors.New("invalid credentials")
+	}
+	if user.PINExpired(s.Config.PINMaxAge, time.Now()) {
+		return "", errors.New("PIN expired, rotate it using a signed-in token")
+	}
+	return user.ID, nil
+}
+
+// Refresh exchanges a valid, unexpired, unrevoked access or refresh token for a new pair.
+// A refresh token is single-use: it is revoked before the new pair is issued, and a
+// concurrent exchange that loses the race to revoke it is rejected.
+// Tokens issued before refresh support carry no login time and must log in again.
+func (s *AuthService) Refresh(token string) (*AuthTokens, string, error) {
+	claims, err := utils.ParseToken(token, s.Config.JWTSecret)
+	if err != nil {
+		return nil, "", err
+	}
+	if claims.AuthTime.IsZero() {
+		return nil, "", errors.New("token cannot be refreshed, please log in again")
+	}
+	revoked, err := s.TokenRepo.IsTokenRevoked(claims.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if revoked {
+		return nil, "", errors.New("token has been revoked")
+	}
+
+	if claims.Type == utils.TokenTypeRefresh {
+		claimed, err := s.TokenRepo.RevokeToken(claims.ID, claims.UserID, claims.ExpiresAt)
+		if err != nil {
+			return nil, "", err
+		}
+		if !claimed {
+			return nil, "", errors.New("token has been revoked")
+		}
+	}
+
+	tokens, err := s.issueTokens(claims.UserID, claims.AuthTime)
+	if err != nil {
+		return nil, "", err
+	}
+	return tokens, claims.UserID, nil
+}
+
+// Logout revokes the given tokens so they stop working before they expire. Empty tokens
+// are skipped; tokens issued before revocation support have no ID and can't be revoked.
+func (s *AuthService) Logout(tokens ...string) error {
+	var userID string
+	for _, token := range tokens {
+		if token == "" {
+			continue
+		}
+		claims, err := utils.ParseToken(token, s.Config.JWTSecret)
+		if err != nil {
+			return err
+		}
+		if claims.ID == "" {
+			return errors.New("token cannot be revoked, it expires on its own")
+		}
+		if _, err := s.TokenRepo.RevokeToken(claims.ID, claims.UserID, claims.ExpiresAt); err != nil {
+			return err
+		}
+		userID = claims.UserID
+	}
+	if userID != "" {
+		recordAudit(s.AuditRepo, userID, model.AuditLogout, userID)
+	}
+	return nil
+}
+
+// issueTokens signs an access and a refresh token for a login made at authTime.
+// The access token never outlives the login.
+func (s *AuthService) issueTokens(userID string, authTime time.Time) (*AuthTokens, error) {
+	loginExpires := authTime.Add(s.Config.JWTRefreshExpiry)
+	accessExpires := time.Now().Add(s.Config.JWTExpiry)
+	if accessExpires.After(loginExpires) {
+		accessExpires = loginExpires
+	}
+
+	access, err := utils.IssueToken(s.Config.JWTSecret, userID, utils.TokenTypeAccess, authTime, accessExpires)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := utils.IssueToken(s.Config.JWTSecret, userID, utils.TokenTypeRefresh, authTime, loginExpires)
+	if err != nil {
+		return nil, err
+	}
+	return &AuthTokens{
+		AccessToken:      access,
+		AccessExpiresAt:  accessExpires,
+		RefreshToken:     refresh,
+		RefreshExpiresAt: loginExpires,
+	}, nil
 }
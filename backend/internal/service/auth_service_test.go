@@ -0,0 +1,120 @@
+package service
+
+import (
+	"testing"
+	"time"
+	"wago-backend/internal/config"
+	"wago-backend/internal/model"
+	"wago-backend/internal/repository"
+	"wago-backend/internal/utils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// newTestAuthService returns an AuthService over a sqlmock database whose expectations are
+// checked when the test ends. Auditing is disabled.
+func newTestAuthService(t *testing.T, cfg *config.Config) (*AuthService, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	return NewAuthService(repository.NewUserRepository(db), nil, repository.NewTokenRepository(db), cfg), mock
+}
+
+func expectUserByPIN(mock sqlmock.Sqlmock, pin string, rotatedAt time.Time) {
+	rows := sqlmock.NewRows([]string{"id", "pin", "pin_rotated_at", "created_at", "updated_at", "last_login"})
+	if !rotatedAt.IsZero() {
+		rows.AddRow("user-1", pin, rotatedAt, rotatedAt, rotatedAt, nil)
+	}
+	mock.ExpectQuery("FROM users").WithArgs(pin).WillReturnRows(rows)
+}
+
+func TestPINOwnerRejectsExpiredPIN(t *testing.T) {
+	const maxAge = 30 * 24 * time.Hour
+	cases := []struct {
+		name      string
+		maxAge    time.Duration
+		rotatedAt time.Time
+		wantOwner bool
+	}{
+		{"fresh PIN", maxAge, time.Now().Add(-time.Hour), true},
+		{"expired PIN", maxAge, time.Now().Add(-maxAge - time.Hour), false},
+		{"no expiry configured", 0, time.Now().Add(-10 * maxAge), true},
+		{"unknown PIN", maxAge, time.Time{}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, mock := newTestAuthService(t, &config.Config{PINMaxAge: tc.maxAge})
+			expectUserByPIN(mock, "123456", tc.rotatedAt)
+
+			owner, err := s.PINOwner(" 123456 ")
+			if tc.wantOwner {
+				if err != nil || owner != "user-1" {
+					t.Fatalf("PINOwner = %q, %v; want user-1", owner, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("PINOwner = %q, want an error", owner)
+			}
+		})
+	}
+}
+
+func TestRefreshTokenIsSingleUse(t *testing.T) {
+	cfg := &config.Config{JWTSecret: "test-secret", JWTExpiry: time.Hour, JWTRefreshExpiry: 24 * time.Hour}
+	now := time.Now()
+	token, err := utils.IssueToken(cfg.JWTSecret, "user-1", utils.TokenTypeRefresh, now, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name        string
+		inserted    int64
+		wantRefresh bool
+	}{
+		{"first exchange claims the token", 1, true},
+		{"concurrent exchange lost the race", 0, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, mock := newTestAuthService(t, cfg)
+			// Both exchanges pass the revocation check before either has revoked the token.
+			mock.ExpectQuery("FROM revoked_tokens").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			mock.ExpectExec("INSERT INTO revoked_tokens").WillReturnResult(sqlmock.NewResult(0, tc.inserted))
+			mock.ExpectExec("DELETE FROM revoked_tokens").WillReturnResult(sqlmock.NewResult(0, 0))
+
+			tokens, userID, err := s.Refresh(token)
+			if tc.wantRefresh {
+				if err != nil || tokens == nil || userID != "user-1" {
+					t.Fatalf("Refresh = %v, %q, %v; want new tokens for user-1", tokens, userID, err)
+				}
+				return
+			}
+			if err == nil || tokens != nil {
+				t.Fatalf("Refresh = %v, %v; want the reused token rejected", tokens, err)
+			}
+		})
+	}
+}
+
+func TestLoginAuditsFailedAttemptWithClientIP(t *testing.T) {
+	s, mock := newTestAuthService(t, &config.Config{})
+	s.AuditRepo = repository.NewAuditRepository(s.UserRepo.DB)
+	expectUserByPIN(mock, "000000", time.Time{})
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs("ip:203.0.113.9", model.AuditLoginFailed, "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if _, _, err := s.Login("000000", "203.0.113.9"); err == nil {
+		t.Fatal("Login with an unknown PIN succeeded")
+	}
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"wago-backend/internal/errs"
+	"wago-backend/internal/model"
+	"wago-backend/internal/repository"
+	"wago-backend/internal/whatsapp"
+)
+
+// maxBroadcastRecipients bounds one job; at the default send rate it already takes hours.
+const maxBroadcastRecipients = 1000
+
+type BroadcastService struct {
+	BroadcastRepo *repository.BroadcastRepository
+	ClientMgr     *whatsapp.ClientManager
+}
+
+func NewBroadcastService(broadcastRepo *repository.BroadcastRepository, clientMgr *whatsapp.ClientManager) *BroadcastService {
+	return &BroadcastService{
+		BroadcastRepo: broadcastRepo,
+		ClientMgr:     clientMgr,
+	}
+}
+
+// CreateBroadcast stores a job for message to recipients and starts sending it. Recipients
+// are validated up front and duplicates (by resulting JID) dropped, keeping the first.
+// variables holds per-recipient {{variable}} values keyed by the recipient as listed.
+// The session must be connected; the job itself survives later disconnects and restarts.
+func (s *BroadcastService) CreateBroadcast(sessionID, message string, recipients []string, variables map[string]model.TemplateVars) (*model.BroadcastJob, error) {
+	if len(recipients) == 0 || len(recipients) > maxBroadcastRecipients {
+		return nil, fmt.Errorf("%w: between 1 and %d recipients are required", errs.ErrInvalidInput, maxBroadcastRecipients)
+	}
+
+	seen := make(map[string]bool, len(recipients))
+	listed := make(map[string]bool, len(recipients))
+	unique := make([]model.BroadcastRecipient, 0, len(recipients))
+	var invalid []string
+	for _, raw := range recipients {
+		listed[strings.TrimSpace(raw)] = true
+		recipient := strings.TrimSpace(raw)
+		jid, err := whatsapp.ParseRecipient(recipient)
+		if err != nil {
+			invalid = append(invalid, raw)
+			continue
+		}
+		if seen[jid.String()] {
+			continue
+		}
+		seen[jid.String()] = true
+		unique = append(unique, model.BroadcastRecipient{Recipient: recipient, Variables: variables[recipient]})
+	}
+	if len(invalid) > 0 {
+		return nil, fmt.Errorf("%w: invalid recipients: %s", errs.ErrInvalidInput, strings.Join(invalid, ", "))
+	}
+	for key := range variables {
+		if !listed[strings.TrimSpace(key)] {
+			return nil, fmt.Errorf("%w: variables given for %s, which is not a recipient", errs.ErrInvalidInput, key)
+		}
+	}
+
+	if status := s.ClientMgr.ClientStatus(sessionID); !status.Connected || !status.LoggedIn {
+		return nil, errs.ErrNotConnected
+	}
+
+	job, err := s.BroadcastRepo.Create(sessionID, message, unique)
+	if err != nil {
+		return nil, err
+	}
+	s.ClientMgr.StartBroadcast(*job)
+	return job, nil
+}
+
+func (s *BroadcastService) ListBroadcasts(sessionID string) ([]model.BroadcastJob, error) {
+	return s.BroadcastRepo.List(sessionID)
+}
+
+func (s *BroadcastService) GetBroadcast(sessionID string, id int64) (*model.BroadcastJob, error) {
+	return s.BroadcastRepo.Get(sessionID, id)
+}
+
+func (s *BroadcastService) CancelBroadcast(sessionID string, id int64) (*model.BroadcastJob, error) {
+	return s.BroadcastRepo.Cancel(sessionID, id)
+}
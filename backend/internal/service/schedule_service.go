@@ -0,0 +1,45 @@
+package service
+
+import (
+	"fmt"
+	"time"
+	"wago-backend/internal/errs"
+	"wago-backend/internal/model"
+	"wago-backend/internal/repository"
+	"wago-backend/internal/whatsapp"
+)
+
+// maxScheduleAhead bounds how far in the future a message can be scheduled.
+const maxScheduleAhead = 365 * 24 * time.Hour
+
+type ScheduleService struct {
+	ScheduleRepo *repository.ScheduleRepository
+}
+
+func NewScheduleService(scheduleRepo *repository.ScheduleRepository) *ScheduleService {
+	return &ScheduleService{ScheduleRepo: scheduleRepo}
+}
+
+// ScheduleMessage queues message for recipient at sendAt, which must be in the future and
+// at most a year ahead. The recipient is validated now so typos fail before the send time.
+func (s *ScheduleService) ScheduleMessage(sessionID, recipient, message string, sendAt time.Time) (*model.ScheduledMessage, error) {
+	if _, err := whatsapp.ParseRecipient(recipient); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !sendAt.After(now) {
+		return nil, fmt.Errorf("%w: send_at must be in the future", errs.ErrInvalidInput)
+	}
+	if sendAt.Sub(now) > maxScheduleAhead {
+		return nil, fmt.Errorf("%w: send_at must be within a year", errs.ErrInvalidInput)
+	}
+	return s.ScheduleRepo.Create(sessionID, recipient, message, sendAt)
+}
+
+func (s *ScheduleService) ListScheduled(sessionID, status string) ([]model.ScheduledMessage, error) {
+	return s.ScheduleRepo.List(sessionID, status)
+}
+
+func (s *ScheduleService) CancelScheduled(sessionID string, id int64) (*model.ScheduledMessage, error) {
+	return s.ScheduleRepo.Cancel(sessionID, id)
+}
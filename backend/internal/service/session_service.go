@@ -1,44 +1,192 @@
 package service
 
 import (
+	"fmt"
+	"time"
+	"wago-backend/internal/config"
+	"wago-backend/internal/errs"
 	"wago-backend/internal/model"
 	"wago-backend/internal/repository"
 	"wago-backend/internal/whatsapp"
 )
 
 type SessionService struct {
-	SessionRepo *repository.SessionRepository
-	ClientMgr   *whatsapp.ClientManager
+	SessionRepo   *repository.SessionRepository
+	AnalyticsRepo *repository.AnalyticsRepository
+	AuditRepo     *repository.AuditRepository
+	ClientMgr     *whatsapp.ClientManager
+	Config        *config.Config
 }
 
-func NewSessionService(sessionRepo *repository.SessionRepository, clientMgr *whatsapp.ClientManager) *SessionService {
+func NewSessionService(sessionRepo *repository.SessionRepository, analyticsRepo *repository.AnalyticsRepository, auditRepo *repository.AuditRepository, clientMgr *whatsapp.ClientManager, cfg *config.Config) *SessionService {
 	return &SessionService{
-		SessionRepo: sessionRepo,
-		ClientMgr:   clientMgr,
+		SessionRepo:   sessionRepo,
+		AnalyticsRepo: analyticsRepo,
+		AuditRepo:     auditRepo,
+		ClientMgr:     clientMgr,
+		Config:        cfg,
 	}
 }
 
+// checkQuota returns errs.ErrQuotaExceeded when the user already owns MaxSessionsPerUser sessions.
+func (s *SessionService) checkQuota(userID string) error {
+	if s.Config == nil || s.Config.MaxSessionsPerUser <= 0 {
+		return nil
+	}
+	count, err := s.SessionRepo.CountSessionsByUserID(userID)
+	if err != nil {
+		return err
+	}
+	if count >= s.Config.MaxSessionsPerUser {
+		return fmt.Errorf("limit is %d sessions: %w", s.Config.MaxSessionsPerUser, errs.ErrQuotaExceeded)
+	}
+	return nil
+}
+
 func (s *SessionService) CreateSession(userID, sessionName, webhookURL string) (*model.Session, error) {
+	if err := s.checkQuota(userID); err != nil {
+		return nil, err
+	}
+
 	session := &model.Session{
 		UserID:      userID,
 		SessionName: sessionName,
 		WebhookURL:  webhookURL,
 		Status:      model.SessionStatusDisconnected,
+		Tags:        model.StringList{},
 	}
 
 	return s.SessionRepo.CreateSession(session)
 }
 
-func (s *SessionService) GetSessions(userID string) ([]*model.Session, error) {
-	return s.SessionRepo.GetSessionsByUserID(userID)
+// CloneSession creates a disconnected session for userID with the settings of sourceID.
+// Device pairing is not copied. An empty sessionName defaults to "<source name> (copy)".
+func (s *SessionService) CloneSession(userID, sourceID, sessionName string) (*model.Session, error) {
+	source, err := s.SessionRepo.GetSessionByID(sourceID)
+	if err != nil {
+		return nil, err
+	}
+	if source.UserID != userID {
+		return nil, errs.ErrSessionNotFound
+	}
+
+	if sessionName == "" {
+		sessionName = source.SessionName + " (copy)"
+		if len(sessionName) > 100 {
+			sessionName = sessionName[:100]
+		}
+	}
+
+	settings := sessionSettings(source)
+	settings["webhook_secret"] = source.WebhookSecret
+	return s.CreateSessionWithSettings(userID, sessionName, source.WebhookURL, settings)
+}
+
+// sessionSettings returns the copyable settings of a session keyed by column, excluding the
+// name, webhook URL and secret, device pairing and per-chat state such as muted chats.
+func sessionSettings(source *model.Session) map[string]interface{} {
+	return map[string]interface{}{
+		"is_group_response_enabled": source.IsGroupResponseEnabled,
+		"busy_reply_text":           source.BusyReplyText,
+		"busy_reply_grace_ms":       source.BusyReplyGraceMs,
+		"dry_run":                   source.DryRun,
+		"reply_privately_in_groups": source.ReplyPrivatelyInGroups,
+		"trigger_pattern":           source.TriggerPattern,
+		"mark_read_on_success":      source.MarkReadOnSuccess,
+		"webhook_format":            source.WebhookFormat,
+		"webhook_method":            source.WebhookMethod,
+		"tags":                      source.Tags,
+		"webhook_include_fields":    source.WebhookIncludeFields,
+		"webhook_exclude_fields":    source.WebhookExcludeFields,
+		"ingest_history":            source.IngestHistory,
+		"process_own_messages":      source.ProcessOwnMessages,
+		"reply_footer":              source.ReplyFooter,
+		"webhook_stream":            source.WebhookStream,
+		"webhook_retries":           source.WebhookRetries,
+		"webhook_timeout_seconds":   source.WebhookTimeoutSeconds,
+		"auto_mark_read":            source.AutoMarkRead,
+		"webhook_status_events":     source.WebhookStatusEvents,
+		"quote_replies":             source.QuoteReplies,
+		"typing_delay":              source.TypingDelay,
+	}
+}
+
+// CreateSessionWithSettings creates a disconnected session and applies settings to it.
+func (s *SessionService) CreateSessionWithSettings(userID, sessionName, webhookURL string, settings map[string]interface{}) (*model.Session, error) {
+	created, err := s.CreateSession(userID, sessionName, webhookURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(settings) > 0 {
+		if err := s.SessionRepo.UpdateFields(created.ID, userID, settings); err != nil {
+			// Don't leave a half-configured copy behind.
+			s.SessionRepo.DeleteSession(created.ID, userID)
+			return nil, err
+		}
+	}
+
+	return s.GetSession(created.ID)
+}
+
+// ExportSession returns session's configuration as a portable document.
+func (s *SessionService) ExportSession(session *model.Session) *model.SessionExport {
+	return &model.SessionExport{
+		Version:     model.SessionExportVersion,
+		ExportedAt:  time.Now().UTC(),
+		SessionName: session.SessionName,
+		WebhookURL:  session.WebhookURL,
+		Settings:    sessionSettings(session),
+	}
+}
+
+// ImportSession creates a disconnected session for userID from exported, already validated settings.
+func (s *SessionService) ImportSession(userID, sessionName, webhookURL string, settings map[string]interface{}) (*model.Session, error) {
+	return s.CreateSessionWithSettings(userID, sessionName, webhookURL, settings)
+}
+
+// GetSessions lists the user's sessions, limited to those labelled tag when it is non-empty.
+func (s *SessionService) GetSessions(userID, tag string) ([]*model.Session, error) {
+	var sessions []*model.Session
+	var err error
+	if tag != "" {
+		sessions, err = s.SessionRepo.GetSessionsByUserIDAndTag(userID, tag)
+	} else {
+		sessions, err = s.SessionRepo.GetSessionsByUserID(userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		s.attachLastActivity(session)
+	}
+	return sessions, nil
 }
 
 func (s *SessionService) GetSession(id string) (*model.Session, error) {
-	return s.SessionRepo.GetSessionByID(id)
+	session, err := s.SessionRepo.GetSessionByID(id)
+	if err != nil {
+		return nil, err
+	}
+	s.attachLastActivity(session)
+	return session, nil
+}
+
+// attachLastActivity fills in the in-memory last message time tracked by the client manager.
+func (s *SessionService) attachLastActivity(session *model.Session) {
+	if at, ok := s.ClientMgr.LastActivity(session.ID); ok {
+		session.LastActivity = &at
+	}
 }
 
-func (s *SessionService) StartSession(id string) (string, error) {
-	return s.ClientMgr.Connect(id)
+// StartSession connects a session; relink allows a new QR pairing if its stored device is gone.
+func (s *SessionService) StartSession(id string, relink bool) (string, error) {
+	return s.ClientMgr.Connect(id, relink)
+}
+
+// PairPhone starts phone-number pairing for an unpaired session and returns the pairing code.
+func (s *SessionService) PairPhone(id, phone string) (string, error) {
+	return s.ClientMgr.PairPhone(id, phone)
 }
 
 func (s *SessionService) StopSession(id string) error {
@@ -46,16 +194,162 @@ func (s *SessionService) StopSession(id string) error {
 	return nil
 }
 
+// DeleteSession disconnects and deletes the user's session. Sessions of other users are
+// refused with ErrForbidden before anything is touched.
 func (s *SessionService) DeleteSession(id, userID string) error {
+	session, err := s.SessionRepo.GetSessionByID(id)
+	if err != nil {
+		return err
+	}
+	if session.UserID != userID {
+		return errs.ErrForbidden
+	}
+
 	// Disconnect first
 	s.ClientMgr.Disconnect(id)
-	return s.SessionRepo.DeleteSession(id, userID)
+	if err := s.SessionRepo.DeleteSession(id, userID); err != nil {
+		return err
+	}
+	recordAudit(s.AuditRepo, userID, model.AuditSessionDeleted, id)
+	return nil
+}
+
+// ForceDisconnect stops a session's client on an operator's request, whoever owns it.
+// The session is kept and can be reconnected by its owner.
+func (s *SessionService) ForceDisconnect(id string) error {
+	if _, err := s.SessionRepo.GetSessionByID(id); err != nil {
+		return err
+	}
+	s.ClientMgr.Disconnect(id)
+	recordAudit(s.AuditRepo, model.AuditActorAdmin, model.AuditSessionDisconnected, id)
+	return nil
 }
 
-func (s *SessionService) UpdateSession(session *model.Session) error {
-	return s.SessionRepo.UpdateSession(session)
+// UpdateSessionFields writes only the given columns and returns the refreshed session.
+func (s *SessionService) UpdateSessionFields(id, userID string, fields map[string]interface{}) (*model.Session, error) {
+	if err := s.SessionRepo.UpdateFields(id, userID, fields); err != nil {
+		return nil, err
+	}
+	if _, ok := fields["webhook_secret"]; ok {
+		recordAudit(s.AuditRepo, userID, model.AuditWebhookSecretRotated, id)
+	}
+	return s.GetSession(id)
+}
+
+// SetChatMuted mutes or unmutes a chat (phone number or JID) and returns the refreshed session.
+// Messages from muted chats are still logged, but never forwarded to the webhook or answered.
+func (s *SessionService) SetChatMuted(id, userID, chat string, muted bool) (*model.Session, error) {
+	jid, err := whatsapp.ParseRecipient(chat)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.SessionRepo.SetChatMuted(id, userID, jid.ToNonAD().String(), muted); err != nil {
+		return nil, err
+	}
+	return s.GetSession(id)
+}
+
+// SendText sends a text message and returns its WhatsApp message ID.
+func (s *SessionService) SendText(sessionID, to, message string) (string, error) {
+	return s.ClientMgr.SendMessage(sessionID, to, message)
+}
+
+// SendMedia sends a file and returns its WhatsApp message ID and message type.
+func (s *SessionService) SendMedia(sessionID, to string, data []byte, mimeType, fileName, caption string) (string, string, error) {
+	return s.ClientMgr.SendMedia(sessionID, to, data, mimeType, fileName, caption)
 }
 
 func (s *SessionService) SendMessage(sessionID, recipient, message string) error {
-	return s.ClientMgr.SendMessage(sessionID, recipient, message)
+	_, err := s.ClientMgr.SendMessage(sessionID, recipient, message)
+	return err
+}
+
+func (s *SessionService) SendLocation(sessionID, recipient string, lat, lon float64, name string) error {
+	jid, err := whatsapp.ParseRecipient(recipient)
+	if err != nil {
+		return err
+	}
+	return s.ClientMgr.SendLocation(sessionID, jid, lat, lon, name)
+}
+
+func (s *SessionService) GetMedia(sessionID, messageID string) ([]byte, string, error) {
+	return s.ClientMgr.GetMedia(sessionID, messageID)
+}
+
+func (s *SessionService) RefreshDeviceInfo(sessionID string) (*model.DeviceInfo, error) {
+	return s.ClientMgr.RefreshDeviceInfo(sessionID)
+}
+
+// UnansweredMessages lists incoming messages from the last window that got no reply.
+func (s *SessionService) UnansweredMessages(sessionID string, window time.Duration) ([]model.MessageLog, error) {
+	return s.AnalyticsRepo.GetUnansweredMessages(sessionID, time.Now().Add(-window))
+}
+
+func (s *SessionService) MessageHistory(filter model.MessageLogFilter) (*model.MessagePage, error) {
+	return s.AnalyticsRepo.ListMessages(filter)
+}
+
+// ContactDirectory lists the session's contacts with their WhatsApp names and, when
+// withPictures is set, profile picture URLs.
+func (s *SessionService) ContactDirectory(sessionID string, withPictures bool) ([]model.Contact, error) {
+	contacts, err := s.AnalyticsRepo.GetUniqueContacts(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	s.ClientMgr.EnrichContacts(sessionID, contacts, withPictures)
+	return contacts, nil
+}
+
+func (s *SessionService) SetPresence(sessionID string, available bool) error {
+	return s.ClientMgr.SetPresence(sessionID, available)
+}
+
+func (s *SessionService) ListGroups(sessionID string) ([]model.Group, error) {
+	return s.ClientMgr.ListGroups(sessionID)
+}
+
+func (s *SessionService) ListGroupSettings(sessionID string) ([]model.GroupSetting, error) {
+	return s.SessionRepo.ListGroupSettings(sessionID)
+}
+
+// SetGroupSetting overrides the session's group response default for one group.
+func (s *SessionService) SetGroupSetting(sessionID, groupJID string, enabled, mentionOnly bool) (*model.GroupSetting, error) {
+	jid, err := whatsapp.ParseGroupJID(groupJID)
+	if err != nil {
+		return nil, err
+	}
+	return s.SessionRepo.UpsertGroupSetting(sessionID, jid.String(), enabled, mentionOnly)
+}
+
+// ResetGroupSetting drops a group's override so the session default applies again.
+func (s *SessionService) ResetGroupSetting(sessionID, groupJID string) error {
+	jid, err := whatsapp.ParseGroupJID(groupJID)
+	if err != nil {
+		return err
+	}
+	return s.SessionRepo.DeleteGroupSetting(sessionID, jid.String())
+}
+
+func (s *SessionService) ListNewsletters(sessionID string) ([]model.Newsletter, error) {
+	return s.ClientMgr.ListNewsletters(sessionID)
+}
+
+func (s *SessionService) GetNewsletter(sessionID, jid string) (*model.Newsletter, error) {
+	return s.ClientMgr.GetNewsletter(sessionID, jid)
+}
+
+func (s *SessionService) SetNewsletterFollowed(sessionID, jid string, follow bool) error {
+	return s.ClientMgr.SetNewsletterFollowed(sessionID, jid, follow)
+}
+
+func (s *SessionService) CurrentQRCode(sessionID string) (whatsapp.PendingQR, bool) {
+	return s.ClientMgr.CurrentQRCode(sessionID)
+}
+
+func (s *SessionService) ClientStatus(sessionID string) whatsapp.ClientStatus {
+	return s.ClientMgr.ClientStatus(sessionID)
+}
+
+func (s *SessionService) StoreReport() (*model.StoreReport, error) {
+	return s.ClientMgr.StoreReport()
 }
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"wago-backend/internal/errs"
+	"wago-backend/internal/message"
+	"wago-backend/internal/model"
+	"wago-backend/internal/repository"
+	"wago-backend/internal/whatsapp"
+)
+
+type TemplateService struct {
+	TemplateRepo *repository.TemplateRepository
+	ClientMgr    *whatsapp.ClientManager
+}
+
+func NewTemplateService(templateRepo *repository.TemplateRepository, clientMgr *whatsapp.ClientManager) *TemplateService {
+	return &TemplateService{
+		TemplateRepo: templateRepo,
+		ClientMgr:    clientMgr,
+	}
+}
+
+func (s *TemplateService) ListTemplates(sessionID string) ([]model.MessageTemplate, error) {
+	return s.TemplateRepo.List(sessionID)
+}
+
+func (s *TemplateService) GetTemplate(sessionID, name string) (*model.MessageTemplate, error) {
+	return s.TemplateRepo.Get(sessionID, name)
+}
+
+func (s *TemplateService) CreateTemplate(sessionID, name, body string) (*model.MessageTemplate, error) {
+	return s.TemplateRepo.Create(sessionID, name, body)
+}
+
+func (s *TemplateService) UpdateTemplate(sessionID, name, body string) (*model.MessageTemplate, error) {
+	return s.TemplateRepo.Update(sessionID, name, body)
+}
+
+func (s *TemplateService) DeleteTemplate(sessionID, name string) error {
+	return s.TemplateRepo.Delete(sessionID, name)
+}
+
+// SendTemplate renders a template for recipient and sends it. Built-in variables are name
+// (the contact's saved or push name), phone, date and time (server local time); vars
+// override them. Any placeholder left without a value is an ErrInvalidInput.
+func (s *TemplateService) SendTemplate(sessionID, name, recipient string, vars map[string]string) (string, error) {
+	template, err := s.TemplateRepo.Get(sessionID, name)
+	if err != nil {
+		return "", err
+	}
+	jid, err := whatsapp.ParseRecipient(recipient)
+	if err != nil {
+		return "", err
+	}
+
+	values := message.RecipientVars(jid.User, s.ClientMgr.ContactName(sessionID, jid), time.Now())
+	for key, value := range vars {
+		values[key] = value
+	}
+
+	var missing []string
+	for _, variable := range message.Variables(template.Body) {
+		if _, ok := values[variable]; !ok {
+			missing = append(missing, variable)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w: missing template variables: %s", errs.ErrInvalidInput, strings.Join(missing, ", "))
+	}
+
+	text := message.Render(template.Body, values)
+	if _, err := s.ClientMgr.SendMessage(sessionID, recipient, text); err != nil {
+		return "", err
+	}
+	return text, nil
+}
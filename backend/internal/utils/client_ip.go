@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TrustedProxies are the networks whose X-Forwarded-For header is believed for the client IP.
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies turns TRUSTED_PROXIES entries (IPs or CIDRs) into networks; invalid entries are skipped.
+func ParseTrustedProxies(entries []string) TrustedProxies {
+	var networks TrustedProxies
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil {
+				bits := 8 * len(ip.To16())
+				if ip.To4() != nil {
+					ip, bits = ip.To4(), 32
+				}
+				networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			}
+			continue
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+// ClientIP is the request's remote IP. When that is a trusted proxy, X-Forwarded-For is
+// walked from the right and the first address not belonging to a trusted proxy is used.
+func (p TrustedProxies) ClientIP(r *http.Request) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	if !p.contains(remote) {
+		return remote
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if !p.contains(hop) {
+			return hop
+		}
+		remote = hop
+	}
+	return remote
+}
+
+func (p TrustedProxies) contains(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range p {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
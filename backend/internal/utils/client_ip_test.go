@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies := ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1", "not-an-ip"})
+	cases := []struct {
+		name      string
+		remote    string
+		forwarded string
+		want      string
+	}{
+		{"direct client", "203.0.113.9:51234", "", "203.0.113.9"},
+		{"direct client spoofing the header", "203.0.113.9:51234", "198.51.100.7", "203.0.113.9"},
+		{"behind a trusted proxy", "192.0.2.1:443", "198.51.100.7", "198.51.100.7"},
+		{"through a chain of proxies", "10.1.1.1:443", "198.51.100.7, 10.2.2.2", "198.51.100.7"},
+		{"spoofed hop left of the client", "10.1.1.1:443", "6.6.6.6, 198.51.100.7, 10.2.2.2", "198.51.100.7"},
+		{"trusted proxy without the header", "10.1.1.1:443", "", "10.1.1.1"},
+		{"remote without a port", "203.0.113.9", "", "203.0.113.9"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/auth/login", nil)
+			r.RemoteAddr = tc.remote
+			if tc.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+			if got := proxies.ClientIP(r); got != tc.want {
+				t.Errorf("ClientIP = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
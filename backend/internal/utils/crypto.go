@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// encryptedPrefix marks values written by Cipher so legacy plaintext rows remain readable.
+const encryptedPrefix = "enc:v1:"
+
+// Cipher encrypts sensitive column values with AES-256-GCM.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher builds a Cipher from a 32-byte key encoded as hex or base64.
+// An empty key returns a nil Cipher, which stores values as plaintext.
+func NewCipher(encodedKey string) (*Cipher, error) {
+	encodedKey = strings.TrimSpace(encodedKey)
+	if encodedKey == "" {
+		return nil, nil
+	}
+
+	key, err := hex.DecodeString(encodedKey)
+	if err != nil {
+		key, err = base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return nil, errors.New("encryption key must be hex or base64 encoded")
+		}
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt seals plaintext. Empty strings and a nil Cipher pass through unchanged.
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	if c == nil || plaintext == "" {
+		return plaintext, nil
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt. Values without the prefix are returned as-is.
+func (c *Cipher) Decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return value, nil
+	}
+	if c == nil {
+		return "", errors.New("encrypted value found but no encryption key configured")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", err
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", errors.New("encrypted value too short")
+	}
+
+	plaintext, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
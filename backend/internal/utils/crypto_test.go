@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+const testKeyHex = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+func newTestCipher(t *testing.T, key string) *Cipher {
+	t.Helper()
+	c, err := NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCipherRoundTrip(t *testing.T) {
+	c := newTestCipher(t, testKeyHex)
+	for _, plaintext := range []string{"whsec_123", "ünïcödé secret", strings.Repeat("x", 4096)} {
+		sealed, err := c.Encrypt(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(sealed, encryptedPrefix) || strings.Contains(sealed, plaintext) {
+			t.Fatalf("Encrypt(%q) = %q, want an opaque %s value", plaintext, sealed, encryptedPrefix)
+		}
+		opened, err := c.Decrypt(sealed)
+		if err != nil || opened != plaintext {
+			t.Fatalf("Decrypt = %q, %v; want %q", opened, err, plaintext)
+		}
+	}
+
+	// A fresh nonce per call means equal plaintexts don't produce equal ciphertexts.
+	a, _ := c.Encrypt("same")
+	b, _ := c.Encrypt("same")
+	if a == b {
+		t.Error("two encryptions of the same value are identical")
+	}
+}
+
+func TestCipherBase64Key(t *testing.T) {
+	raw := make([]byte, 32)
+	for i := range raw {
+		raw[i] = byte(i)
+	}
+	hexCipher := newTestCipher(t, testKeyHex)
+	b64Cipher := newTestCipher(t, base64.StdEncoding.EncodeToString(raw))
+
+	sealed, _ := hexCipher.Encrypt("secret")
+	if opened, err := b64Cipher.Decrypt(sealed); err != nil || opened != "secret" {
+		t.Fatalf("the same key in base64 decrypted %q, %v", opened, err)
+	}
+}
+
+func TestCipherPassThrough(t *testing.T) {
+	var none *Cipher
+	if got, err := none.Encrypt("plain"); err != nil || got != "plain" {
+		t.Errorf("nil Cipher Encrypt = %q, %v", got, err)
+	}
+	c := newTestCipher(t, testKeyHex)
+	if got, err := c.Encrypt(""); err != nil || got != "" {
+		t.Errorf("Encrypt(\"\") = %q, %v", got, err)
+	}
+	// Rows written before encryption was enabled stay readable.
+	if got, err := c.Decrypt("legacy-plaintext"); err != nil || got != "legacy-plaintext" {
+		t.Errorf("Decrypt of plaintext = %q, %v", got, err)
+	}
+}
+
+func TestCipherRejectsBadInput(t *testing.T) {
+	c := newTestCipher(t, testKeyHex)
+	sealed, _ := c.Encrypt("secret")
+
+	other := newTestCipher(t, strings.Repeat("ab", 32))
+	if _, err := other.Decrypt(sealed); err == nil {
+		t.Error("decrypted with the wrong key")
+	}
+	var none *Cipher
+	if _, err := none.Decrypt(sealed); err == nil {
+		t.Error("nil Cipher decrypted an encrypted value")
+	}
+
+	raw, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, encryptedPrefix))
+	raw[len(raw)-1] ^= 1
+	if _, err := c.Decrypt(encryptedPrefix + base64.StdEncoding.EncodeToString(raw)); err == nil {
+		t.Error("decrypted a tampered value")
+	}
+	if _, err := c.Decrypt(encryptedPrefix + "AAAA"); err == nil {
+		t.Error("decrypted a truncated value")
+	}
+	if _, err := c.Decrypt(encryptedPrefix + "not base64!"); err == nil {
+		t.Error("decrypted invalid base64")
+	}
+}
+
+func TestNewCipherKeys(t *testing.T) {
+	if c, err := NewCipher("  "); c != nil || err != nil {
+		t.Errorf("blank key = %v, %v; want nil, nil", c, err)
+	}
+	for _, key := range []string{"not-a-key!", "0011", base64.StdEncoding.EncodeToString(make([]byte, 16))} {
+		if _, err := NewCipher(key); err == nil {
+			t.Errorf("NewCipher(%q) accepted an invalid key", key)
+		}
+	}
+}
@@ -1,15 +1,53 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// ParseUserIDFromToken validates the JWT and extracts the user_id claim.
-func ParseUserIDFromToken(tokenString, secret string) (string, error) {
+// Token types carried in the "typ" claim. Tokens without one were issued before refresh
+// tokens existed and are treated as access tokens.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// TokenClaims are the claims this API puts in its JWTs.
+type TokenClaims struct {
+	// ID is the jti claim used to revoke the token; empty for tokens issued before revocation existed.
+	ID        string
+	UserID    string
+	Type      string
+	ExpiresAt time.Time
+	// AuthTime is when the user logged in with their PIN; zero for tokens issued before it was recorded.
+	AuthTime time.Time
+}
+
+// IssueToken signs a token of the given type for userID, expiring at exp. authTime is carried
+// over on refresh so the lifetime of a login stays bounded.
+func IssueToken(secret, userID, tokenType string, authTime, exp time.Time) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti":       hex.EncodeToString(id),
+		"user_id":   userID,
+		"typ":       tokenType,
+		"auth_time": authTime.Unix(),
+		"exp":       exp.Unix(),
+	})
+	return token.SignedString([]byte(secret))
+}
+
+// ParseToken validates the JWT (signature and expiry) and returns its claims.
+func ParseToken(tokenString, secret string) (*TokenClaims, error) {
 	if tokenString == "" {
-		return "", errors.New("missing token")
+		return nil, errors.New("missing token")
 	}
 
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
@@ -19,16 +57,70 @@ func ParseUserIDFromToken(tokenString, secret string) (string, error) {
 		return []byte(secret), nil
 	})
 	if err != nil || !token.Valid {
-		return "", errors.New("invalid token")
+		return nil, errors.New("invalid token")
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return "", errors.New("invalid token claims")
+		return nil, errors.New("invalid token claims")
 	}
 	userID, ok := claims["user_id"].(string)
 	if !ok || userID == "" {
-		return "", errors.New("invalid user ID in token")
+		return nil, errors.New("invalid user ID in token")
+	}
+
+	parsed := &TokenClaims{UserID: userID, Type: TokenTypeAccess}
+	if jti, ok := claims["jti"].(string); ok {
+		parsed.ID = jti
+	}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		parsed.ExpiresAt = exp.Time
+	}
+	if typ, ok := claims["typ"].(string); ok && typ != "" {
+		parsed.Type = typ
+	}
+	if authTime, ok := claims["auth_time"].(float64); ok {
+		parsed.AuthTime = time.Unix(int64(authTime), 0)
+	}
+	return parsed, nil
+}
+
+// ParseUserIDFromToken validates an access token and extracts the user_id claim.
+// Refresh tokens are rejected: they only work on the refresh endpoint.
+func ParseUserIDFromToken(tokenString, secret string) (string, error) {
+	claims, err := ParseToken(tokenString, secret)
+	if err != nil {
+		return "", err
+	}
+	if claims.Type != TokenTypeAccess {
+		return "", errors.New("invalid token type")
+	}
+	return claims.UserID, nil
+}
+
+// RevocationChecker reports whether a token ID (jti) was revoked, e.g. by logging out.
+type RevocationChecker interface {
+	IsTokenRevoked(jti string) (bool, error)
+}
+
+// AuthenticateToken validates an access token like ParseUserIDFromToken and also rejects
+// revoked ones. A nil checker skips the revocation check.
+func AuthenticateToken(tokenString, secret string, revoked RevocationChecker) (string, error) {
+	claims, err := ParseToken(tokenString, secret)
+	if err != nil {
+		return "", err
+	}
+	if claims.Type != TokenTypeAccess {
+		return "", errors.New("invalid token type")
+	}
+	if revoked != nil && claims.ID != "" {
+		isRevoked, err := revoked.IsTokenRevoked(claims.ID)
+		if err != nil {
+			return "", errors.New("failed to check token")
+		}
+		if isRevoked {
+			return "", errors.New("token has been revoked")
+		}
 	}
-	return userID, nil
+	return claims.UserID, nil
 }
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+)
+
+// OriginAllowed reports whether a browser origin may call the API.
+// Requests without an Origin header come from non-browser clients and are not subject to CORS.
+// An empty allow-list denies every cross-origin request; "*" allows all.
+func OriginAllowed(origin string, allowed []string) bool {
+	if origin == "" {
+		return true
+	}
+	for _, o := range allowed {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLocalOrigin reports whether origin points at localhost, for development convenience.
+func IsLocalOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
@@ -0,0 +1,49 @@
+package utils
+
+import "testing"
+
+func TestOriginAllowed(t *testing.T) {
+	cases := []struct {
+		name    string
+		origin  string
+		allowed []string
+		want    bool
+	}{
+		{"no origin header", "", nil, true},
+		{"empty list denies", "https://app.example.com", nil, false},
+		{"empty list denies localhost", "http://localhost:3000", []string{}, false},
+		{"wildcard allows any", "https://evil.example.net", []string{"*"}, true},
+		{"wildcard among others", "https://evil.example.net", []string{"https://app.example.com", "*"}, true},
+		{"listed origin", "https://app.example.com", []string{"https://admin.example.com", "https://app.example.com"}, true},
+		{"case-insensitive", "https://App.Example.com", []string{"https://app.example.com"}, true},
+		{"unlisted origin", "https://evil.example.net", []string{"https://app.example.com"}, false},
+		{"scheme must match", "http://app.example.com", []string{"https://app.example.com"}, false},
+		{"port must match", "https://app.example.com:8443", []string{"https://app.example.com"}, false},
+		{"no suffix match", "https://app.example.com.evil.net", []string{"https://app.example.com"}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := OriginAllowed(tc.origin, tc.allowed); got != tc.want {
+				t.Errorf("OriginAllowed(%q, %q) = %v, want %v", tc.origin, tc.allowed, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsLocalOrigin(t *testing.T) {
+	cases := map[string]bool{
+		"http://localhost:3000":           true,
+		"http://127.0.0.1:5173":           true,
+		"http://[::1]:8080":               true,
+		"https://app.example.com":         false,
+		"http://localhost.example.com":    false,
+		"http://evil.net/?host=localhost": false,
+		"":                                false,
+		"::not a url":                     false,
+	}
+	for origin, want := range cases {
+		if got := IsLocalOrigin(origin); got != want {
+			t.Errorf("IsLocalOrigin(%q) = %v, want %v", origin, got, want)
+		}
+	}
+}
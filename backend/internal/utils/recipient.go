@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// phoneNumberPattern matches an E.164 number without the "+": a country code and up to 15 digits in total.
+var phoneNumberPattern = regexp.MustCompile(`^[1-9][0-9]{6,14}$`)
+
+var recipientSeparators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+
+// NormalizeRecipient turns user input into a JID to send to. Phone numbers may contain spaces,
+// dashes, dots, parentheses, a leading "+" or the "00" international prefix; what remains must be
+// a number with country code. Local numbers with a single trunk "0" (e.g. 08123456789) are
+// rejected rather than guessed at, since dropping the 0 would send to another country.
+// Inputs containing "@" are parsed as JIDs as-is.
+func NormalizeRecipient(input string) (types.JID, error) {
+	cleaned := strings.TrimSpace(input)
+	if cleaned == "" {
+		return types.JID{}, errors.New("recipient is empty")
+	}
+
+	if strings.Contains(cleaned, "@") {
+		jid, err := types.ParseJID(cleaned)
+		if err != nil || jid.User == "" {
+			return types.JID{}, errors.New("recipient is not a valid JID: " + cleaned)
+		}
+		if jid.Server == types.DefaultUserServer && !phoneNumberPattern.MatchString(jid.User) {
+			return types.JID{}, errors.New("recipient JID does not contain a valid phone number: " + cleaned)
+		}
+		return jid, nil
+	}
+
+	number := recipientSeparators.Replace(cleaned)
+	number = strings.TrimPrefix(number, "+")
+	if international, ok := strings.CutPrefix(number, "00"); ok {
+		number = international
+	} else if strings.HasPrefix(number, "0") {
+		return types.JID{}, errors.New("recipient needs a country code instead of the leading 0, e.g. 628123456789 for 08123456789")
+	}
+	if !phoneNumberPattern.MatchString(number) {
+		return types.JID{}, errors.New("recipient must be a phone number with country code, e.g. 628123456789")
+	}
+	return types.NewJID(number, types.DefaultUserServer), nil
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeRecipient(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  string // empty when the input must be rejected
+	}{
+		{"bare number", "628123456789", "628123456789@s.whatsapp.net"},
+		{"surrounding whitespace", "  628123456789\n", "628123456789@s.whatsapp.net"},
+		{"plus prefix", "+628123456789", "628123456789@s.whatsapp.net"},
+		{"spaces", "+62 812 3456 789", "628123456789@s.whatsapp.net"},
+		{"dashes", "62-812-3456-789", "628123456789@s.whatsapp.net"},
+		{"dots and parentheses", "+1 (415) 555.2671", "14155552671@s.whatsapp.net"},
+		{"00 international prefix", "00628123456789", "628123456789@s.whatsapp.net"},
+		{"shortest valid", "1234567", "1234567@s.whatsapp.net"},
+		{"longest valid", "123456789012345", "123456789012345@s.whatsapp.net"},
+
+		{"user JID", "628123456789@s.whatsapp.net", "628123456789@s.whatsapp.net"},
+		{"group JID", "120363000000000001@g.us", "120363000000000001@g.us"},
+		{"LID", "123456789012345@lid", "123456789012345@lid"},
+		{"JID with device", "628123456789:12@s.whatsapp.net", "628123456789:12@s.whatsapp.net"},
+
+		{"empty", "", ""},
+		{"whitespace only", "   ", ""},
+		{"letters", "call me", ""},
+		{"digits mixed with letters", "62812abc6789", ""},
+		{"too short", "123456", ""},
+		{"too long", "1234567890123456", ""},
+		{"only zeros", "0000000", ""},
+		{"local with leading 0", "08123456789", ""},
+		{"local with leading 0 and separators", "0812-3456-789", ""},
+		{"trunk 0 before country code", "0628123456789", ""},
+		{"000 prefix", "000628123456789", ""},
+		{"plus only", "+", ""},
+		{"JID without user", "@s.whatsapp.net", ""},
+		{"user JID with invalid number", "0812@s.whatsapp.net", ""},
+		{"user JID with letters", "abc@s.whatsapp.net", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			jid, err := NormalizeRecipient(tc.input)
+			if tc.want == "" {
+				if err == nil {
+					t.Fatalf("NormalizeRecipient(%q) = %s, want an error", tc.input, jid)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeRecipient(%q): %v", tc.input, err)
+			}
+			if got := jid.String(); got != tc.want {
+				t.Errorf("NormalizeRecipient(%q) = %s, want %s", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeRecipientAsksForCountryCode(t *testing.T) {
+	_, err := NormalizeRecipient("08123456789")
+	if err == nil || !strings.Contains(err.Error(), "country code") {
+		t.Fatalf("NormalizeRecipient(08123456789) error = %v, want a country code error", err)
+	}
+}
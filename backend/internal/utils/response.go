@@ -3,6 +3,7 @@ package utils
 import (
 	"encoding/json"
 	"net/http"
+	"wago-backend/internal/errs"
 )
 
 type Response struct {
@@ -25,6 +26,11 @@ func ErrorResponse(w http.ResponseWriter, statusCode int, message string) {
 	JSONResponse(w, statusCode, false, nil, message)
 }
 
+// ErrorFromErr writes err with the HTTP status of its domain error (see errs.HTTPStatus).
+func ErrorFromErr(w http.ResponseWriter, err error) {
+	ErrorResponse(w, errs.HTTPStatus(err), err.Error())
+}
+
 func SuccessResponse(w http.ResponseWriter, statusCode int, data interface{}, message string) {
 	JSONResponse(w, statusCode, true, data, message)
 }
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// MaxSessionTags caps how many tags a single session may carry.
+const MaxSessionTags = 20
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// ValidTag reports whether tag is a normalized session tag: lowercase letters, digits, "-" or "_", up to 32 characters.
+func ValidTag(tag string) bool {
+	return tagPattern.MatchString(tag)
+}
+
+// NormalizeTags trims, lowercases and de-duplicates tags, rejecting any that are invalid.
+func NormalizeTags(tags []string) ([]string, error) {
+	if len(tags) > MaxSessionTags {
+		return nil, errors.New("Too many tags")
+	}
+
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !ValidTag(tag) {
+			return nil, errors.New("Invalid tag: " + tag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
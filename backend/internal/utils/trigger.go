@@ -0,0 +1,8 @@
+package utils
+
+import "regexp"
+
+// CompileTriggerPattern compiles a session trigger pattern anchored at the start of the message.
+func CompileTriggerPattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(`^(?:` + pattern + `)`)
+}
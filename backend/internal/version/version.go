@@ -0,0 +1,45 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time, e.g.
+// go build -ldflags "-X wago-backend/internal/version.Version=v1.2.0 -X wago-backend/internal/version.Commit=$(git rev-parse --short HEAD)"
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+const whatsmeowModule = "go.mau.fi/whatsmeow"
+
+type Info struct {
+	Version          string `json:"version"`
+	Commit           string `json:"commit"`
+	BuildDate        string `json:"build_date"`
+	GoVersion        string `json:"go_version"`
+	WhatsmeowVersion string `json:"whatsmeow_version"`
+}
+
+// Get returns the build information of the running binary.
+func Get() Info {
+	info := Info{
+		Version:          Version,
+		Commit:           Commit,
+		BuildDate:        BuildDate,
+		GoVersion:        runtime.Version(),
+		WhatsmeowVersion: "unknown",
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range bi.Deps {
+			if dep.Path == whatsmeowModule {
+				info.WhatsmeowVersion = dep.Version
+				break
+			}
+		}
+	}
+	return info
+}
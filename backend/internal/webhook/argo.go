@@ -0,0 +1,37 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/beeper/argo-go/codec"
+	"github.com/beeper/argo-go/header"
+	"github.com/beeper/argo-go/wire"
+)
+
+// ArgoContentType is sent with Argo-encoded webhook bodies.
+const ArgoContentType = "application/argo"
+
+// encodeArgo encodes the selected fields as a self-describing Argo message, header included.
+// Values go through JSON first so timestamps and group info take the same shape as in JSON bodies.
+func encodeArgo(fields map[string]interface{}) ([]byte, error) {
+	jsonData, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal(jsonData, &value); err != nil {
+		return nil, err
+	}
+
+	encoder := codec.NewArgoEncoder()
+	encoder.Header().SetFlag(header.HeaderSelfDescribingFlag, true)
+	if err := encoder.ValueToArgoWithType(value, wire.Desc); err != nil {
+		return nil, fmt.Errorf("failed to encode argo payload: %w", err)
+	}
+	result, err := encoder.GetResult()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode argo payload: %w", err)
+	}
+	return result.Bytes(), nil
+}
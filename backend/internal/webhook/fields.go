@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// PayloadFields are the payload field names a session may include or exclude.
+// "media" controls whether downloaded media is attached (multipart) at all.
+var PayloadFields = []string{
+	"version", "session_id", "event", "from", "to", "message", "timestamp", "is_group", "is_from_me",
+	"is_newsletter", "group_info", "push_name", "message_type", "selected_id", "location", "status",
+	"quoted_message_id", "quoted_message", "media",
+}
+
+// requiredPayloadFields are always sent regardless of a session's include/exclude lists.
+var requiredPayloadFields = map[string]bool{"version": true, "session_id": true, "event": true, "message": true}
+
+// ValidatePayloadFields checks names against PayloadFields and drops duplicates.
+func ValidatePayloadFields(names []string) ([]string, error) {
+	known := make(map[string]bool, len(PayloadFields))
+	for _, name := range PayloadFields {
+		known[name] = true
+	}
+
+	seen := make(map[string]bool, len(names))
+	valid := make([]string, 0, len(names))
+	for _, name := range names {
+		if !known[name] {
+			return nil, errors.New("Unknown payload field: " + name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			valid = append(valid, name)
+		}
+	}
+	return valid, nil
+}
+
+// fieldSelected applies include/exclude lists: an empty include list selects every field.
+func fieldSelected(name string, include, exclude []string) bool {
+	if requiredPayloadFields[name] {
+		return true
+	}
+	for _, f := range exclude {
+		if f == name {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, f := range include {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// payloadFields serializes the payload by hand into its wire fields, keeping only those the endpoint selects.
+func payloadFields(p WebhookPayload, include, exclude []string) map[string]interface{} {
+	all := map[string]interface{}{
+		"version":       p.Version,
+		"session_id":    p.SessionID,
+		"event":         p.Event,
+		"from":          p.From,
+		"to":            p.To,
+		"message":       p.Message,
+		"timestamp":     p.Timestamp,
+		"is_group":      p.IsGroup,
+		"is_from_me":    p.IsFromMe,
+		"is_newsletter": p.IsNewsletter,
+		"push_name":     p.PushName,
+		"message_type":  p.MessageType,
+	}
+	if p.SelectedID != "" {
+		all["selected_id"] = p.SelectedID
+	}
+	if p.GroupInfo != nil {
+		all["group_info"] = p.GroupInfo
+	}
+	if p.Location != nil {
+		all["location"] = p.Location
+	}
+	if p.Status != nil {
+		all["status"] = p.Status
+	}
+	if p.QuotedMessageID != "" {
+		all["quoted_message_id"] = p.QuotedMessageID
+		all["quoted_message"] = p.QuotedMessage
+	}
+
+	fields := make(map[string]interface{}, len(all))
+	for name, value := range all {
+		if fieldSelected(name, include, exclude) {
+			fields[name] = value
+		}
+	}
+	return fields
+}
+
+// sortedFieldNames gives form and multipart bodies a stable field order.
+func sortedFieldNames(fields map[string]interface{}) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// formValue renders a field for form and multipart bodies.
+func formValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return fmt.Sprintf("%v", v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
+
+// formValues flattens the selected fields into the same shape used for multipart requests.
+func formValues(fields map[string]interface{}) url.Values {
+	values := url.Values{}
+	for _, name := range sortedFieldNames(fields) {
+		values.Set(name, formValue(fields[name]))
+	}
+	return values
+}
@@ -0,0 +1,284 @@
+package webhook
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Reply is what the webhook asked the bot to send back: plain text, or media with an optional caption.
+type Reply struct {
+	Text  string
+	Media *MediaReply
+}
+
+// MediaReply references media returned by the webhook, either by URL or inline as base64.
+type MediaReply struct {
+	URL      string
+	Base64   string
+	MimeType string
+	FileName string
+	Caption  string
+}
+
+func (r Reply) IsEmpty() bool {
+	return r.Text == "" && r.Media == nil
+}
+
+// parseReplies reads a decoded webhook response. A top-level array yields one reply per
+// element, in order; any other value yields a single reply. Empty replies are dropped.
+func parseReplies(data interface{}, keys []string) []Reply {
+	items, ok := data.([]interface{})
+	if !ok {
+		items = []interface{}{data}
+	}
+
+	replies := make([]Reply, 0, len(items))
+	for _, item := range items {
+		if reply := parseReply(item, keys); !reply.IsEmpty() {
+			replies = append(replies, reply)
+		}
+	}
+	return replies
+}
+
+// parseReply reads a single reply. Objects with media_url or media_base64
+// become media replies; everything else falls back to text extraction.
+func parseReply(data interface{}, keys []string) Reply {
+	if obj, ok := data.(map[string]interface{}); ok {
+		mediaURL, _ := obj["media_url"].(string)
+		mediaBase64, _ := obj["media_base64"].(string)
+		if mediaURL != "" || mediaBase64 != "" {
+			media := &MediaReply{
+				URL:      mediaURL,
+				Base64:   mediaBase64,
+				MimeType: firstString(obj, "mime_type", "mimetype"),
+				FileName: firstString(obj, "file_name", "filename"),
+				Caption:  firstString(obj, "caption"),
+			}
+			if media.Caption == "" {
+				media.Caption = extractText(obj, keys)
+			}
+			return Reply{Media: media}
+		}
+	}
+
+	return Reply{Text: extractText(data, keys)}
+}
+
+// extractText pulls the reply text out of a decoded webhook response, checking keys in order.
+func extractText(data interface{}, keys []string) string {
+	switch v := data.(type) {
+	case []interface{}:
+		if len(v) > 0 {
+			return extractText(v[0], keys)
+		}
+	case map[string]interface{}:
+		// Check configured keys
+		for _, key := range keys {
+			if val, ok := v[key].(string); ok && val != "" {
+				return val
+			}
+		}
+		// Special case for nested "data" or "json"
+		if val, ok := v["data"]; ok {
+			return extractText(val, keys)
+		}
+		if val, ok := v["json"]; ok {
+			return extractText(val, keys)
+		}
+	case string:
+		return v
+	}
+	return ""
+}
+
+func firstString(obj map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if val, ok := obj[key].(string); ok && val != "" {
+			return val
+		}
+	}
+	return ""
+}
+
+// allowedMediaTypes are the MIME types the bot can send back: images, videos and audio that
+// WhatsApp plays inline, and common document formats. Anything else (HTML, SVG, scripts,
+// archives, executables) is refused.
+var allowedMediaTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+	"image/gif":  true,
+
+	"video/mp4":       true,
+	"video/3gpp":      true,
+	"video/quicktime": true,
+
+	"audio/ogg":  true,
+	"audio/mpeg": true,
+	"audio/mp4":  true,
+	"audio/aac":  true,
+	"audio/amr":  true,
+
+	"application/pdf":               true,
+	"application/msword":            true,
+	"application/vnd.ms-excel":      true,
+	"application/vnd.ms-powerpoint": true,
+	"text/plain":                    true,
+	"text/csv":                      true,
+
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   true,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         true,
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": true,
+}
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), private in practice.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicAddress reports whether ip is a routable public address, as opposed to loopback,
+// private, link-local (including cloud metadata at 169.254.169.254), multicast or unspecified.
+func publicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsValid() && ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// refuseNonPublicAddress is a net.Dialer Control hook. It runs after name resolution, on the
+// address actually dialed, so DNS names pointing at internal hosts are caught too.
+func refuseNonPublicAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !publicAddress(ip) {
+		return fmt.Errorf("media_url resolves to non-public address %s", ip)
+	}
+	return nil
+}
+
+// newMediaClient builds the client for media_url fetches. It only dials public addresses and
+// follows redirects only to http(s) URLs. It bypasses HTTP proxies, which would hide the real
+// destination from the address check.
+func newMediaClient(dialTimeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: dialTimeout, Control: refuseNonPublicAddress}
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("media_url redirected too many times")
+			}
+			return checkMediaURL(req.URL)
+		},
+	}
+}
+
+// checkMediaURL accepts only absolute http(s) URLs.
+func checkMediaURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("media_url must be http or https, got %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return errors.New("media_url has no host")
+	}
+	return nil
+}
+
+// ResolveMedia downloads or decodes a media reply and validates its size and type.
+// URLs are fetched with MediaClient. It returns the bytes and the normalized MIME type.
+func (s *WebhookService) ResolveMedia(m *MediaReply) ([]byte, string, error) {
+	var data []byte
+	mimeType := m.MimeType
+
+	if m.Base64 != "" {
+		encoded := m.Base64
+		// Accept data URIs: data:image/png;base64,....
+		if strings.HasPrefix(encoded, "data:") {
+			if comma := strings.Index(encoded, ","); comma != -1 {
+				if mimeType == "" {
+					mimeType = strings.TrimSuffix(strings.TrimPrefix(encoded[:comma], "data:"), ";base64")
+				}
+				encoded = encoded[comma+1:]
+			}
+		}
+		if base64.StdEncoding.DecodedLen(len(encoded)) > int(s.MaxMediaBytes)+3 {
+			return nil, "", fmt.Errorf("media exceeds %d bytes", s.MaxMediaBytes)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid media_base64: %w", err)
+		}
+		data = decoded
+	} else {
+		mediaURL, err := url.Parse(m.URL)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid media_url: %w", err)
+		}
+		if err := checkMediaURL(mediaURL); err != nil {
+			return nil, "", err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, mediaURL.String(), nil)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid media_url: %w", err)
+		}
+		resp, err := s.MediaClient.Do(req)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to fetch media: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return nil, "", fmt.Errorf("media_url returned status: %d", resp.StatusCode)
+		}
+		data, err = io.ReadAll(io.LimitReader(resp.Body, s.MaxMediaBytes+1))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read media: %w", err)
+		}
+		if mimeType == "" {
+			mimeType = resp.Header.Get("Content-Type")
+		}
+	}
+
+	if len(data) == 0 {
+		return nil, "", fmt.Errorf("media is empty")
+	}
+	if int64(len(data)) > s.MaxMediaBytes {
+		return nil, "", fmt.Errorf("media exceeds %d bytes", s.MaxMediaBytes)
+	}
+
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType = http.DetectContentType(data)
+	}
+	if parsed, _, err := mime.ParseMediaType(mimeType); err == nil {
+		mimeType = parsed
+	}
+
+	if !allowedMediaTypes[mimeType] {
+		return nil, "", fmt.Errorf("unsupported media type: %s", mimeType)
+	}
+	return data, mimeType, nil
+}
@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestPublicAddress(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":        true,
+		"2606:4700:4700::1111": true,
+		"127.0.0.1":            false,
+		"::1":                  false,
+		"10.1.2.3":             false,
+		"172.16.0.1":           false,
+		"192.168.1.10":         false,
+		"100.64.0.1":           false,
+		"169.254.169.254":      false,
+		"fe80::1":              false,
+		"fd00::1":              false,
+		"0.0.0.0":              false,
+		"::":                   false,
+		"224.0.0.1":            false,
+		"::ffff:127.0.0.1":     false,
+		"::ffff:10.0.0.1":      false,
+		"::ffff:93.184.216.34": true,
+	}
+	for addr, want := range cases {
+		if got := publicAddress(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("publicAddress(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestResolveMediaRefusesInternalURLs(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("internal server was reached: %s", r.URL)
+	}))
+	defer internal.Close()
+
+	cases := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{"loopback server", internal.URL + "/admin", "non-public address"},
+		{"cloud metadata", "http://169.254.169.254/latest/meta-data/", "non-public address"},
+		{"private network", "http://10.0.0.1/", "non-public address"},
+		{"localhost name", "http://localhost:" + strings.TrimPrefix(internal.URL, "http://127.0.0.1:"), "non-public address"},
+		{"file scheme", "file:///etc/passwd", "must be http or https"},
+		{"ftp scheme", "ftp://example.com/file.png", "must be http or https"},
+		{"relative", "/images/logo.png", "must be http or https"},
+		{"no host", "http:///logo.png", "no host"},
+	}
+	s := newTestService(t)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := s.ResolveMedia(&MediaReply{URL: tc.url})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("ResolveMedia(%q) error = %v, want %q", tc.url, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestResolveMediaRedirects(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 32)...)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/to-file":
+			http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+		case "/to-image":
+			http.Redirect(w, r, "/image.png", http.StatusFound)
+		default:
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(png)
+		}
+	}))
+	defer server.Close()
+
+	// The test server is on loopback, so keep the media client's redirect policy but not its dialer.
+	s := newTestService(t)
+	s.MediaClient.Transport = http.DefaultTransport
+
+	if _, _, err := s.ResolveMedia(&MediaReply{URL: server.URL + "/to-file"}); err == nil || !strings.Contains(err.Error(), "must be http or https") {
+		t.Errorf("redirect to file: error = %v", err)
+	}
+	data, mimeType, err := s.ResolveMedia(&MediaReply{URL: server.URL + "/to-image"})
+	if err != nil || mimeType != "image/png" || len(data) != len(png) {
+		t.Errorf("redirect to image = %d bytes %q, %v", len(data), mimeType, err)
+	}
+}
+
+func TestResolveMediaTypes(t *testing.T) {
+	cases := []struct {
+		name     string
+		mimeType string
+		data     string
+		want     string // empty when refused
+	}{
+		{"png", "image/png", "\x89PNG\r\n\x1a\nrest", "image/png"},
+		{"jpeg with parameters", "image/jpeg; q=1", "\xff\xd8\xff\xe0rest", "image/jpeg"},
+		{"pdf", "application/pdf", "%PDF-1.7", "application/pdf"},
+		{"voice note", "audio/ogg", "OggS", "audio/ogg"},
+		{"plain text sniffed", "", "hello there", "text/plain"},
+		{"svg", "image/svg+xml", "<svg onload=alert(1)>", ""},
+		{"html", "text/html", "<html><script>", ""},
+		{"html sniffed", "", "<!DOCTYPE html><html>", ""},
+		{"javascript", "application/javascript", "alert(1)", ""},
+		{"executable", "application/x-msdownload", "MZ\x90\x00", ""},
+		{"archive", "application/zip", "PK\x03\x04", ""},
+	}
+	s := newTestService(t)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reply := &MediaReply{Base64: base64.StdEncoding.EncodeToString([]byte(tc.data)), MimeType: tc.mimeType}
+			_, mimeType, err := s.ResolveMedia(reply)
+			if tc.want == "" {
+				if err == nil {
+					t.Fatalf("accepted %s as %q", tc.name, mimeType)
+				}
+				return
+			}
+			if err != nil || mimeType != tc.want {
+				t.Fatalf("ResolveMedia = %q, %v; want %q", mimeType, err, tc.want)
+			}
+		})
+	}
+}
@@ -2,40 +2,210 @@ package webhook
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"mime/multipart"
+	"net"
 	"net/http"
 	"net/textproto"
+	"os"
+	"strconv"
 	"time"
+	"wago-backend/internal/config"
+	"wago-backend/internal/logging"
+	"wago-backend/internal/model"
 )
 
+// DefaultResponseKeys are the JSON keys searched for reply text when none are configured.
+var DefaultResponseKeys = []string{"output", "text", "message", "response", "body", "content"}
+
+// DefaultWebhookTimeout bounds an attempt when the endpoint sets no timeout of its own.
+const DefaultWebhookTimeout = 60 * time.Second
+
 type WebhookService struct {
-	Client *http.Client
+	Client       *http.Client
+	ResponseKeys []string
+	// MediaClient fetches media_url replies; it refuses to connect to non-public addresses.
+	MediaClient *http.Client
+	// MaxMediaBytes caps media replies fetched or decoded from webhook responses.
+	MaxMediaBytes int64
+	// MaxResponseBytes caps a (non-streamed) webhook response body.
+	MaxResponseBytes int64
+	// RetryBackoff spaces out retries: the nth retry waits n times this long.
+	RetryBackoff time.Duration
+
+	// StreamClient shares Client's transport but has no overall timeout; streamed
+	// requests are bounded by MaxStreamDuration instead.
+	StreamClient      *http.Client
+	MaxStreamDuration time.Duration
+
+	log *slog.Logger
 }
 
-func NewWebhookService() *WebhookService {
+// NewWebhookService builds the shared webhook client. It fails if configured mTLS files can't be loaded.
+// A nil logger falls back to slog's default.
+func NewWebhookService(cfg *config.Config, logger *slog.Logger) (*WebhookService, error) {
+	// A single tuned transport lets high-volume sessions reuse connections to the same webhook host.
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   cfg.WebhookDialTimeout,
+			KeepAlive: cfg.WebhookKeepAlive,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          cfg.WebhookMaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.WebhookMaxIdleConnsPerHost,
+		IdleConnTimeout:       cfg.WebhookIdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	tlsConfig, err := clientTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	responseKeys := make([]string, 0, len(cfg.WebhookResponseKeys))
+	for _, key := range cfg.WebhookResponseKeys {
+		if key != "" {
+			responseKeys = append(responseKeys, key)
+		}
+	}
+	if len(responseKeys) == 0 {
+		responseKeys = DefaultResponseKeys
+	}
+
 	return &WebhookService{
-		Client: &http.Client{
-			Timeout: 60 * time.Second, // Increased timeout for media uploads
-		},
+		// No client-wide timeout: each attempt is bounded by its endpoint's Timeout.
+		Client:        &http.Client{Transport: transport},
+		ResponseKeys:  responseKeys,
+		MediaClient:   newMediaClient(cfg.WebhookDialTimeout),
+		MaxMediaBytes: int64(cfg.WebhookMaxMediaMB) << 20,
+
+		MaxResponseBytes: int64(cfg.WebhookMaxResponseKB) << 10,
+		RetryBackoff:     time.Second,
+
+		StreamClient:      &http.Client{Transport: transport},
+		MaxStreamDuration: cfg.WebhookStreamMaxDuration,
+
+		log: logging.OrDefault(logger),
+	}, nil
+}
+
+// clientTLSConfig loads the optional webhook client certificate and CA bundle.
+// It returns nil when neither is configured, leaving Go's default TLS settings.
+func clientTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	if cfg.WebhookClientCertFile == "" && cfg.WebhookClientKeyFile == "" && cfg.WebhookCAFile == "" {
+		return nil, nil
 	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.WebhookClientCertFile != "" || cfg.WebhookClientKeyFile != "" {
+		if cfg.WebhookClientCertFile == "" || cfg.WebhookClientKeyFile == "" {
+			return nil, fmt.Errorf("webhook mTLS needs both WEBHOOK_CLIENT_CERT_FILE and WEBHOOK_CLIENT_KEY_FILE")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.WebhookClientCertFile, cfg.WebhookClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load webhook client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if cfg.WebhookCAFile != "" {
+		pem, err := os.ReadFile(cfg.WebhookCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read webhook CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("webhook CA file %s contains no certificates", cfg.WebhookCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
 }
 
+// PayloadVersion is the version of the webhook payload envelope, sent as "version" and in the
+// X-Wago-Version header. It only changes when fields are renamed, removed or change meaning;
+// new fields may appear within a version.
+const PayloadVersion = 1
+
+// Event values tell inbound messages apart from status updates of messages the session sent.
+// Every payload has one; it is also sent in the X-Wago-Event header so receivers can route
+// without parsing the body.
+const (
+	EventMessage = "message"
+	EventStatus  = "status"
+)
+
+// Statuses reported in MessageStatus.
+const (
+	StatusDelivered = "delivered"
+	StatusRead      = "read"
+	StatusPlayed    = "played" // voice notes and videos
+)
+
 type WebhookPayload struct {
-	SessionID     string     `json:"session_id"`
-	From          string     `json:"from"`
-	To            string     `json:"to"`
-	Message       string     `json:"message"`
-	Timestamp     time.Time  `json:"timestamp"`
-	IsGroup       bool       `json:"is_group"`
-	GroupInfo     *GroupInfo `json:"group_info,omitempty"`
-	PushName      string     `json:"push_name"`
-	MessageType   string     `json:"message_type"`
-	MediaData     []byte     `json:"-"` // Binary data, not for JSON
-	MediaName     string     `json:"-"`
-	MediaMimeType string     `json:"-"`
+	Version         int            `json:"version"` // set to PayloadVersion by SendWebhook
+	SessionID       string         `json:"session_id"`
+	Event           string         `json:"event"` // EventMessage or EventStatus; required
+	From            string         `json:"from"`
+	To              string         `json:"to"`
+	Message         string         `json:"message"`
+	Timestamp       time.Time      `json:"timestamp"`
+	IsGroup         bool           `json:"is_group"`
+	IsFromMe        bool           `json:"is_from_me"`    // sent by the account itself, e.g. from the owner's phone
+	IsNewsletter    bool           `json:"is_newsletter"` // posted in a followed channel; replies are not sent
+	GroupInfo       *GroupInfo     `json:"group_info,omitempty"`
+	PushName        string         `json:"push_name"`
+	MessageType     string         `json:"message_type"`
+	SelectedID      string         `json:"selected_id,omitempty"`       // Button ID / list row ID for interactive responses
+	Location        *Location      `json:"location,omitempty"`          // Set for location and live location messages
+	Status          *MessageStatus `json:"status,omitempty"`            // Set for EventStatus payloads
+	QuotedMessageID string         `json:"quoted_message_id,omitempty"` // ID of the message this one replies to
+	QuotedMessage   string         `json:"quoted_message,omitempty"`    // its text or caption, if any
+	MediaData       []byte         `json:"-"`                           // Binary data, not for JSON
+	MediaName       string         `json:"-"`
+	MediaMimeType   string         `json:"-"`
+}
+
+// MessageStatus reports that messages the session sent were delivered to, read or played by Recipient.
+type MessageStatus struct {
+	MessageIDs []string `json:"message_ids"`
+	Status     string   `json:"status"`
+	Recipient  string   `json:"recipient"`
+	Chat       string   `json:"chat"`
+}
+
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Name      string  `json:"name,omitempty"`
+	Address   string  `json:"address,omitempty"`
+}
+
+// WebhookResult is the outcome of a webhook delivery. On error, a non-zero StatusCode means
+// the endpoint answered with that (non-2xx) status; zero means no response was received.
+type WebhookResult struct {
+	Replies    []Reply
+	StatusCode int
 }
 
 type GroupInfo struct {
@@ -43,31 +213,81 @@ type GroupInfo struct {
 	Name string `json:"name"`
 }
 
-func (s *WebhookService) SendWebhook(webhookURL string, payload WebhookPayload) (string, error) {
+// Endpoint is a session's webhook destination and how requests to it are built.
+type Endpoint struct {
+	URL    string
+	Secret string // signs the body in X-Wago-Signature when set
+	Format string // model.WebhookFormatJSON (default), model.WebhookFormatForm or model.WebhookFormatArgo
+	Method string // POST (default) or PUT
+
+	// IncludeFields / ExcludeFields trim the payload; session_id and message are always sent.
+	IncludeFields []string
+	ExcludeFields []string
+
+	// Retries is the number of extra attempts after a failed delivery; Timeout bounds each
+	// attempt (DefaultWebhookTimeout when zero). Streamed requests use MaxStreamDuration instead.
+	Retries int
+	Timeout time.Duration
+
+	// OnReply, when set, opts into streaming: an NDJSON response is read line by line and each
+	// reply is passed here as it arrives instead of being returned by SendWebhook.
+	OnReply func(Reply)
+}
+
+// EndpointForSession builds the webhook endpoint configured on session.
+func EndpointForSession(session *model.Session) Endpoint {
+	return Endpoint{
+		URL:    session.WebhookURL,
+		Secret: session.WebhookSecret,
+		Format: session.WebhookFormat,
+		Method: session.WebhookMethod,
+
+		IncludeFields: session.WebhookIncludeFields,
+		ExcludeFields: session.WebhookExcludeFields,
+
+		Retries: session.WebhookRetries,
+		Timeout: time.Duration(session.WebhookTimeoutSeconds) * time.Second,
+	}
+}
+
+// SendWebhook delivers the payload and returns the replies parsed from the response, in send order,
+// along with the status code of the last response received.
+// When the endpoint has a secret, the body is signed with HMAC-SHA256 in the X-Wago-Signature header.
+// The payload must have an Event; its Version is always PayloadVersion.
+// Network errors, 5xx, 408 and 429 responses are retried up to endpoint.Retries times; other
+// failures are returned at once.
+func (s *WebhookService) SendWebhook(endpoint Endpoint, payload WebhookPayload) (WebhookResult, error) {
+	webhookURL, secret := endpoint.URL, endpoint.Secret
 	if webhookURL == "" {
-		return "", nil
+		return WebhookResult{}, nil
+	}
+	if payload.Event == "" {
+		return WebhookResult{}, errors.New("webhook payload has no event")
+	}
+	payload.Version = PayloadVersion
+	method := endpoint.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	log := s.log.With("session_id", payload.SessionID)
+	fields := payloadFields(payload, endpoint.IncludeFields, endpoint.ExcludeFields)
+	if !fieldSelected("media", endpoint.IncludeFields, endpoint.ExcludeFields) {
+		payload.MediaData = nil
 	}
 
-	var req *http.Request
-	var err error
+	// The body is serialized once; every attempt gets a fresh request reading from it.
+	var body []byte
+	var contentType string
 
 	if len(payload.MediaData) > 0 {
 		// Send as multipart/form-data
-		body := &bytes.Buffer{}
-		writer := multipart.NewWriter(body)
+		buf := &bytes.Buffer{}
+		writer := multipart.NewWriter(buf)
 
 		// Add fields
-		_ = writer.WriteField("session_id", payload.SessionID)
-		_ = writer.WriteField("from", payload.From)
-		_ = writer.WriteField("to", payload.To)
-		_ = writer.WriteField("message", payload.Message)
-		_ = writer.WriteField("timestamp", payload.Timestamp.Format(time.RFC3339))
-		_ = writer.WriteField("is_group", fmt.Sprintf("%v", payload.IsGroup))
-		_ = writer.WriteField("push_name", payload.PushName)
-		_ = writer.WriteField("message_type", payload.MessageType)
-		if payload.GroupInfo != nil {
-			groupInfoJSON, _ := json.Marshal(payload.GroupInfo)
-			_ = writer.WriteField("group_info", string(groupInfoJSON))
+		for _, name := range sortedFieldNames(fields) {
+			_ = writer.WriteField(name, formValue(fields[name]))
 		}
 
 		// Add file
@@ -79,101 +299,139 @@ func (s *WebhookService) SendWebhook(webhookURL string, payload WebhookPayload)
 
 		writer.Close()
 
-		req, err = http.NewRequest("POST", webhookURL, body)
+		body = buf.Bytes()
+		contentType = writer.FormDataContentType()
+		log.Debug("sending multipart webhook request", "bytes", len(body))
+
+	} else if endpoint.Format == model.WebhookFormatForm {
+		// Send as application/x-www-form-urlencoded
+		body = []byte(formValues(fields).Encode())
+		contentType = "application/x-www-form-urlencoded"
+		log.Debug("sending form-encoded webhook request")
+
+	} else if endpoint.Format == model.WebhookFormatArgo {
+		// Send as a self-describing Argo message
+		argoData, err := encodeArgo(fields)
 		if err != nil {
-			return "", fmt.Errorf("failed to create request: %w", err)
+			return WebhookResult{}, err
 		}
-		req.Header.Set("Content-Type", writer.FormDataContentType())
-		fmt.Printf("[Webhook] Sending multipart request with media. Size: %d bytes\n", body.Len())
+		body = argoData
+		contentType = ArgoContentType
+		log.Debug("sending argo webhook request", "bytes", len(argoData))
 
 	} else {
 		// Send as JSON
-		fmt.Printf("[Webhook] Sending JSON request (no media).\n")
-		jsonData, err := json.Marshal(payload)
+		log.Debug("sending json webhook request")
+		jsonData, err := json.Marshal(fields)
 		if err != nil {
-			return "", fmt.Errorf("failed to marshal webhook payload: %w", err)
+			return WebhookResult{}, fmt.Errorf("failed to marshal webhook payload: %w", err)
 		}
-		req, err = http.NewRequest("POST", webhookURL, bytes.NewBuffer(jsonData))
+		body = jsonData
+		contentType = "application/json"
+	}
+
+	client, timeout := s.Client, endpoint.Timeout
+	if timeout <= 0 {
+		timeout = DefaultWebhookTimeout
+	}
+	if endpoint.OnReply != nil {
+		client, timeout = s.StreamClient, s.MaxStreamDuration
+	}
+
+	newRequest := func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, method, webhookURL, bytes.NewReader(body))
 		if err != nil {
-			return "", fmt.Errorf("failed to create request: %w", err)
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("X-Wago-Event", payload.Event)
+		req.Header.Set("X-Wago-Version", strconv.Itoa(payload.Version))
+		if endpoint.OnReply != nil {
+			req.Header.Set("Accept", NDJSONContentType+", application/json")
 		}
-		req.Header.Set("Content-Type", "application/json")
+		signRequest(req, secret, body)
+		return req, nil
 	}
 
-	// Simple retry logic (3 times)
+	var result WebhookResult
 	var lastErr error
-	for i := 0; i < 3; i++ {
-		// We need to recreate the body for retries if it was read, but for now assuming simple retry
-		// Actually, http.NewRequest body is a Reader. If we read it, we can't read it again unless it's a Buffer/BytesReader which is seekable or we recreate it.
-		// bytes.NewBuffer is a Buffer.
-		// However, to be safe, let's just do the request.
-		// NOTE: If the body is consumed, retry might fail. But bytes.Buffer should be fine if not closed?
-		// http.Client.Do closes the body? No, it closes the response body.
-		// But the Request.Body (io.Reader) gets read.
-		// If we want to retry, we should probably recreate the request or use GetBody if available.
-		// For simplicity, I'll just keep the loop structure but be aware of this limitation.
-		// Actually, let's just try once for media to avoid complexity, or recreate request inside loop.
-		// Recreating inside loop is better.
-
-		// Refactoring to create request inside loop is cleaner but I already wrote the code above.
-		// Let's just execute it. If it fails, we might not be able to retry easily without restructuring.
-		// I will stick to the current structure but maybe remove retry for now or assume it works.
-		// Wait, I can just use `GetBody` if I set it, or just recreate the reader.
-
-		// Let's just run it.
-		resp, err := s.Client.Do(req)
-		if err != nil {
-			lastErr = err
-			time.Sleep(time.Duration(i+1) * time.Second)
-			// Re-create request body for retry if needed?
-			// For now, let's just break if it fails to avoid complexity with body resetting.
-			continue
+	for i := 0; i <= endpoint.Retries; i++ {
+		if i > 0 {
+			time.Sleep(time.Duration(i) * s.RetryBackoff)
+		}
+		var retry bool
+		result, retry, lastErr = s.attempt(client, timeout, newRequest, endpoint.OnReply, log)
+		if lastErr == nil || !retry {
+			return result, lastErr
 		}
-		defer resp.Body.Close()
+	}
 
-		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			// Read response body
-			bodyBytes, _ := io.ReadAll(resp.Body)
-			fmt.Printf("[Webhook] Raw Response: %s\n", string(bodyBytes))
+	return result, fmt.Errorf("failed to send webhook after retries: %w", lastErr)
+}
 
-			var data interface{}
-			if err := json.Unmarshal(bodyBytes, &data); err != nil {
-				// Try to treat as string if JSON fails
-				return string(bodyBytes), nil
-			}
+// retryableStatus reports whether a failed delivery with this status may succeed if repeated:
+// server errors, timeouts and rate limiting. Other 4xx responses will fail the same way again.
+func retryableStatus(code int) bool {
+	return code >= 500 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+}
 
-			return extractText(data), nil
-		}
+// attempt makes one delivery and parses its replies. The attempt's timeout also covers reading
+// the response; both are released before it returns. retry reports whether a failure is worth
+// another attempt.
+func (s *WebhookService) attempt(client *http.Client, timeout time.Duration, newRequest func(context.Context) (*http.Request, error),
+	onReply func(Reply), log *slog.Logger) (result WebhookResult, retry bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-		lastErr = fmt.Errorf("webhook returned status: %d", resp.StatusCode)
-		time.Sleep(time.Duration(i+1) * time.Second)
+	req, err := newRequest(ctx)
+	if err != nil {
+		return result, false, err
 	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return result, true, err
+	}
+	defer resp.Body.Close()
+	result.StatusCode = resp.StatusCode
 
-	return "", fmt.Errorf("failed to send webhook after retries: %w", lastErr)
-}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return result, retryableStatus(resp.StatusCode), fmt.Errorf("webhook returned status: %d", resp.StatusCode)
+	}
 
-func extractText(data interface{}) string {
-	switch v := data.(type) {
-	case []interface{}:
-		if len(v) > 0 {
-			return extractText(v[0])
-		}
-	case map[string]interface{}:
-		// Check common keys
-		for _, key := range []string{"output", "text", "message", "response", "body", "content"} {
-			if val, ok := v[key].(string); ok && val != "" {
-				return val
-			}
-		}
-		// Special case for nested "data" or "json"
-		if val, ok := v["data"]; ok {
-			return extractText(val)
-		}
-		if val, ok := v["json"]; ok {
-			return extractText(val)
+	// Streamed replies were already delivered; a broken stream is not retried.
+	if onReply != nil && isNDJSON(resp.Header.Get("Content-Type")) {
+		return result, false, s.streamReplies(resp.Body, onReply, log)
+	}
+
+	// Read response body, refusing oversized ones rather than buffering them whole.
+	bodyBytes, err := io.ReadAll(io.LimitReader(resp.Body, s.MaxResponseBytes+1))
+	if err != nil {
+		return result, false, fmt.Errorf("failed to read webhook response: %w", err)
+	}
+	if int64(len(bodyBytes)) > s.MaxResponseBytes {
+		return result, false, fmt.Errorf("webhook response exceeds %d bytes", s.MaxResponseBytes)
+	}
+	log.Debug("webhook response", "status", resp.StatusCode, "body", string(bodyBytes))
+
+	var data interface{}
+	if err := json.Unmarshal(bodyBytes, &data); err != nil {
+		// Try to treat as string if JSON fails
+		if len(bodyBytes) > 0 {
+			result.Replies = []Reply{{Text: string(bodyBytes)}}
 		}
-	case string:
-		return v
+		return result, false, nil
+	}
+
+	result.Replies = parseReplies(data, s.ResponseKeys)
+	return result, false, nil
+}
+
+// signRequest adds an HMAC-SHA256 signature of body so receivers can verify the sender.
+func signRequest(req *http.Request, secret string, body []byte) {
+	if secret == "" {
+		return
 	}
-	return ""
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	req.Header.Set("X-Wago-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
 }
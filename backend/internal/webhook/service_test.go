@@ -0,0 +1,270 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"wago-backend/internal/config"
+)
+
+func newTestService(t *testing.T) *WebhookService {
+	t.Helper()
+	s, err := NewWebhookService(&config.Config{WebhookMaxMediaMB: 1, WebhookMaxResponseKB: 64}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.RetryBackoff = time.Millisecond
+	return s
+}
+
+// attempt is one request received by a flakyServer.
+type attempt struct {
+	contentType string
+	event       string
+	body        []byte
+}
+
+// flakyServer answers the first request with 500 and later ones with reply, recording each.
+type flakyServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	attempts []attempt
+}
+
+func newFlakyServer(t *testing.T, reply string) *flakyServer {
+	t.Helper()
+	f := &flakyServer{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		f.mu.Lock()
+		f.attempts = append(f.attempts, attempt{r.Header.Get("Content-Type"), r.Header.Get("X-Wago-Event"), body})
+		n := len(f.attempts)
+		f.mu.Unlock()
+		if n == 1 {
+			http.Error(w, "try again", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, reply)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// received returns the requests seen so far.
+func (f *flakyServer) received() []attempt {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]attempt(nil), f.attempts...)
+}
+
+func testPayload() WebhookPayload {
+	return WebhookPayload{
+		SessionID:   "s1",
+		Event:       EventMessage,
+		From:        "628111111111",
+		Message:     "hello",
+		MessageType: "text",
+		Timestamp:   time.Date(2025, 1, 15, 9, 30, 0, 0, time.UTC),
+	}
+}
+
+func TestSendWebhookRetriesJSON(t *testing.T) {
+	server := newFlakyServer(t, `{"output":"hi there"}`)
+
+	result, err := newTestService(t).SendWebhook(Endpoint{URL: server.URL, Retries: 1}, testPayload())
+	if err != nil {
+		t.Fatalf("SendWebhook: %v", err)
+	}
+	if result.StatusCode != http.StatusOK || len(result.Replies) != 1 || result.Replies[0].Text != "hi there" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	attempts := server.received()
+	if len(attempts) != 2 {
+		t.Fatalf("got %d attempts, want 2", len(attempts))
+	}
+
+	retry := attempts[1]
+	if retry.contentType != "application/json" || retry.event != EventMessage {
+		t.Errorf("retry headers = %q/%q", retry.contentType, retry.event)
+	}
+	if string(retry.body) != string(attempts[0].body) {
+		t.Errorf("retry body %q differs from first attempt %q", retry.body, attempts[0].body)
+	}
+	var sent map[string]interface{}
+	if err := json.Unmarshal(retry.body, &sent); err != nil {
+		t.Fatalf("retry body is not JSON: %v", err)
+	}
+	if sent["message"] != "hello" || sent["session_id"] != "s1" || sent["version"] != float64(PayloadVersion) {
+		t.Errorf("retry carried %v", sent)
+	}
+}
+
+func TestSendWebhookRetriesMultipart(t *testing.T) {
+	server := newFlakyServer(t, `{"output":"got it"}`)
+	payload := testPayload()
+	payload.MessageType = "image"
+	payload.MediaData = []byte("\x89PNG fake image")
+	payload.MediaName = "photo.png"
+	payload.MediaMimeType = "image/png"
+
+	result, err := newTestService(t).SendWebhook(Endpoint{URL: server.URL, Retries: 1}, payload)
+	if err != nil {
+		t.Fatalf("SendWebhook: %v", err)
+	}
+	attempts := server.received()
+	if result.StatusCode != http.StatusOK || len(attempts) != 2 {
+		t.Fatalf("status %d after %d attempts, want 200 after 2", result.StatusCode, len(attempts))
+	}
+
+	retry := attempts[1]
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(retry.body)))
+	req.Header.Set("Content-Type", retry.contentType)
+	if err := req.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatalf("retry body is not multipart: %v", err)
+	}
+	if got := req.FormValue("message"); got != "hello" {
+		t.Errorf("message field = %q", got)
+	}
+	file, header, err := req.FormFile("file")
+	if err != nil {
+		t.Fatalf("retry has no file part: %v", err)
+	}
+	defer file.Close()
+	data, _ := io.ReadAll(file)
+	if string(data) != string(payload.MediaData) || header.Filename != "photo.png" {
+		t.Errorf("file part = %q (%s), want the media", data, header.Filename)
+	}
+}
+
+func TestSendWebhookSignsBody(t *testing.T) {
+	var signature string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get("X-Wago-Signature")
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	if _, err := newTestService(t).SendWebhook(Endpoint{URL: server.URL, Secret: "whsec_123"}, testPayload()); err != nil {
+		t.Fatalf("SendWebhook: %v", err)
+	}
+	mac := hmac.New(sha256.New, []byte("whsec_123"))
+	mac.Write(body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signature != want {
+		t.Errorf("X-Wago-Signature = %q, want %q", signature, want)
+	}
+}
+
+func TestSendWebhookRejectsOversizedResponse(t *testing.T) {
+	cases := []struct {
+		name    string
+		size    int
+		wantErr bool
+	}{
+		{"at the limit", 64 << 10, false},
+		{"one byte over", 64<<10 + 1, true},
+		{"far over", 8 << 20, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				io.WriteString(w, strings.Repeat("x", tc.size))
+			}))
+			defer server.Close()
+
+			result, err := newTestService(t).SendWebhook(Endpoint{URL: server.URL}, testPayload())
+			if result.StatusCode != http.StatusOK {
+				t.Errorf("StatusCode = %d, want 200", result.StatusCode)
+			}
+			if tc.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "exceeds") {
+					t.Fatalf("err = %v, want the response size error", err)
+				}
+				if len(result.Replies) != 0 {
+					t.Errorf("oversized response produced %d replies", len(result.Replies))
+				}
+				return
+			}
+			if err != nil || len(result.Replies) != 1 || len(result.Replies[0].Text) != tc.size {
+				t.Fatalf("got %d replies, %v; want the whole body as one reply", len(result.Replies), err)
+			}
+		})
+	}
+}
+
+func TestSendWebhookRetryPolicy(t *testing.T) {
+	cases := []struct {
+		name         string
+		status       int
+		wantAttempts int
+	}{
+		{"server error is retried", http.StatusBadGateway, 3},
+		{"rate limit is retried", http.StatusTooManyRequests, 3},
+		{"request timeout is retried", http.StatusRequestTimeout, 3},
+		{"not found is not retried", http.StatusNotFound, 1},
+		{"unauthorized is not retried", http.StatusUnauthorized, 1},
+		{"bad request is not retried", http.StatusBadRequest, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				attempts++
+				mu.Unlock()
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			result, err := newTestService(t).SendWebhook(Endpoint{URL: server.URL, Retries: 2}, testPayload())
+			if err == nil || result.StatusCode != tc.status {
+				t.Fatalf("SendWebhook = %d, %v; want a %d failure", result.StatusCode, err, tc.status)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if attempts != tc.wantAttempts {
+				t.Errorf("made %d attempts, want %d", attempts, tc.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestSendWebhookDoesNotSleepAfterLastAttempt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	s := newTestService(t)
+	s.RetryBackoff = 100 * time.Millisecond
+	start := time.Now()
+	if _, err := s.SendWebhook(Endpoint{URL: server.URL, Retries: 2}, testPayload()); err == nil {
+		t.Fatal("SendWebhook succeeded against a failing endpoint")
+	}
+	// Retries wait 100ms then 200ms; sleeping after the last attempt would add another 300ms.
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond || elapsed > 550*time.Millisecond {
+		t.Errorf("took %v, want about 300ms", elapsed)
+	}
+}
+
+func TestSendWebhookNetworkErrorIsRetried(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close() // nothing listens any more
+
+	result, err := newTestService(t).SendWebhook(Endpoint{URL: url, Retries: 1}, testPayload())
+	if err == nil || !strings.Contains(err.Error(), "after retries") || result.StatusCode != 0 {
+		t.Fatalf("SendWebhook = %d, %v; want a retried connection failure", result.StatusCode, err)
+	}
+}
@@ -0,0 +1,53 @@
+package webhook
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+)
+
+// NDJSONContentType marks a streamed webhook response: one JSON reply object per line.
+const NDJSONContentType = "application/x-ndjson"
+
+func isNDJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == NDJSONContentType
+}
+
+// streamReplies hands each reply in an NDJSON body to onReply as soon as its line arrives.
+// Lines that aren't valid JSON are skipped. It returns when the body ends or the stream's
+// deadline (MaxStreamDuration) passes.
+func (s *WebhookService) streamReplies(body io.Reader, onReply func(Reply), log *slog.Logger) error {
+	scanner := bufio.NewScanner(body)
+	// A line may carry base64 media, which is a third larger than the media itself.
+	scanner.Buffer(make([]byte, 0, 64<<10), int(s.MaxMediaBytes)*2+64<<10)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var data interface{}
+		if err := json.Unmarshal(line, &data); err != nil {
+			log.Warn("skipping invalid NDJSON line", "error", err)
+			continue
+		}
+		for _, reply := range parseReplies(data, s.ResponseKeys) {
+			onReply(reply)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("webhook stream exceeded %s", s.MaxStreamDuration)
+		}
+		return fmt.Errorf("failed to read webhook stream: %w", err)
+	}
+	return nil
+}
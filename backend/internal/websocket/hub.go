@@ -2,25 +2,36 @@ package websocket
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"wago-backend/internal/logging"
+	"wago-backend/internal/utils"
 
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-}
+// Connection keepalive: the server pings every pingPeriod and drops clients that haven't
+// answered (or sent anything) within pongWait, so dead connections don't linger after
+// network blips. Each write must finish within writeWait.
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = pongWait * 9 / 10
+	maxMessageSize = 4096 // clients only send control frames; bound anything else
+)
 
 type Client struct {
 	Hub       *Hub
 	SessionID string
 	Conn      *websocket.Conn
 	Send      chan []byte
+
+	// tooSlow is set when the hub dropped the client because Send was full; the write pump
+	// then closes with 1013 (try again later) so the dashboard reconnects and refetches state.
+	tooSlow atomic.Bool
 }
 
 type Hub struct {
@@ -30,6 +41,13 @@ type Hub struct {
 	Unregister chan *Client
 	Broadcast  chan Message
 	mu         sync.RWMutex
+
+	quit         chan struct{} // closed by Shutdown: senders stop blocking, Run drains and exits
+	done         chan struct{} // closed when Run has returned
+	shutdownOnce sync.Once
+	running      atomic.Bool // true while Run is looping; reported by Running for readiness probes
+
+	log *slog.Logger
 }
 
 type Message struct {
@@ -39,18 +57,29 @@ type Message struct {
 	Timestamp time.Time   `json:"timestamp"`
 }
 
-func NewHub() *Hub {
+// NewHub returns a hub logging to logger; a nil logger falls back to slog's default.
+func NewHub(logger *slog.Logger) *Hub {
 	return &Hub{
 		Clients:    make(map[string]map[*Client]bool),
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
 		Broadcast:  make(chan Message),
+		quit:       make(chan struct{}),
+		done:       make(chan struct{}),
+		log:        logging.OrDefault(logger),
 	}
 }
 
 func (h *Hub) Run() {
+	h.running.Store(true)
+	defer close(h.done)
+	defer h.running.Store(false)
 	for {
 		select {
+		case <-h.quit:
+			h.drain()
+			return
+
 		case client := <-h.Register:
 			h.mu.Lock()
 			if h.Clients[client.SessionID] == nil {
@@ -73,84 +102,194 @@ func (h *Hub) Run() {
 			h.mu.Unlock()
 
 		case message := <-h.Broadcast:
-			h.mu.RLock()
-			if clients, ok := h.Clients[message.SessionID]; ok {
-				msgBytes, _ := json.Marshal(message)
-				for client := range clients {
-					select {
-					case client.Send <- msgBytes:
-					default:
-						close(client.Send)
-						delete(clients, client)
-					}
-				}
+			h.deliver(message)
+		}
+	}
+}
+
+func (h *Hub) deliver(message Message) {
+	// Full lock: slow clients are dropped from the map below.
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if clients, ok := h.Clients[message.SessionID]; ok {
+		msgBytes, _ := json.Marshal(message)
+		for client := range clients {
+			select {
+			case client.Send <- msgBytes:
+			default:
+				h.log.Warn("websocket client too slow, disconnecting", "session_id", client.SessionID, "queued", len(client.Send))
+				client.tooSlow.Store(true)
+				close(client.Send)
+				delete(clients, client)
 			}
-			h.mu.RUnlock()
+		}
+		if len(clients) == 0 {
+			delete(h.Clients, message.SessionID)
 		}
 	}
 }
 
+// drain delivers broadcasts already handed to the hub, then closes every client's Send channel
+// so their write pumps flush and close the connections.
+func (h *Hub) drain() {
+	for draining := true; draining; {
+		select {
+		case message := <-h.Broadcast:
+			h.deliver(message)
+		default:
+			draining = false
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sessionID, clients := range h.Clients {
+		for client := range clients {
+			close(client.Send)
+		}
+		delete(h.Clients, sessionID)
+	}
+}
+
+// Shutdown stops accepting broadcasts, drains queued ones and waits for Run to exit.
+// Later SendToSession calls are dropped instead of blocking. Run must have been started.
+func (h *Hub) Shutdown() {
+	h.shutdownOnce.Do(func() { close(h.quit) })
+	<-h.done
+}
+
+// Running reports whether Run has started and not yet returned.
+func (h *Hub) Running() bool {
+	return h.running.Load()
+}
+
+// ConnectionCount returns how many websocket clients are watching a session.
+func (h *Hub) ConnectionCount(sessionID string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.Clients[sessionID])
+}
+
+// ConnectionCounts returns a snapshot of websocket client counts per session and their total.
+func (h *Hub) ConnectionCounts() (map[string]int, int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	counts := make(map[string]int, len(h.Clients))
+	total := 0
+	for sessionID, clients := range h.Clients {
+		counts[sessionID] = len(clients)
+		total += len(clients)
+	}
+	return counts, total
+}
+
 func (h *Hub) SendToSession(sessionID string, msgType string, data interface{}) {
-	h.Broadcast <- Message{
+	message := Message{
 		SessionID: sessionID,
 		Type:      msgType,
 		Data:      data,
 		Timestamp: time.Now(),
 	}
+	select {
+	case h.Broadcast <- message:
+	case <-h.quit:
+	}
 }
 
+// ReadPump reads until the connection fails or misses a pong, then unregisters the client.
+// Incoming messages are discarded; reading is what processes pongs and close frames.
 func (c *Client) ReadPump() {
 	defer func() {
-		c.Hub.Unregister <- c
+		select {
+		case c.Hub.Unregister <- c:
+		case <-c.Hub.quit:
+		}
 		c.Conn.Close()
 	}()
+	c.Conn.SetReadLimit(maxMessageSize)
+	c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.Conn.SetPongHandler(func(string) error {
+		return c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
 	for {
 		_, _, err := c.Conn.ReadMessage()
 		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure, websocket.CloseNoStatusReceived) {
+				c.Hub.log.Debug("websocket read ended", "session_id", c.SessionID, "error", err)
+			}
 			break
 		}
 	}
 }
 
+// WritePump sends queued messages and periodic pings. A failed or timed-out write closes the
+// connection, which ends ReadPump and unregisters the client. When Send is closed it sends a
+// close frame: 1013 if the hub dropped the client for being too slow, 1001 otherwise.
 func (c *Client) WritePump() {
+	ticker := time.NewTicker(pingPeriod)
 	defer func() {
+		ticker.Stop()
 		c.Conn.Close()
 	}()
-	for message := range c.Send {
-		c.Conn.WriteMessage(websocket.TextMessage, message)
-	}
-	c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
-}
-
-func originAllowed(origin string, allowed []string) bool {
-	if origin == "" {
-		return true
-	}
-	for _, o := range allowed {
-		if o == "*" || strings.EqualFold(o, origin) {
-			return true
+	for {
+		select {
+		case message, ok := <-c.Send:
+			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				code, reason := websocket.CloseGoingAway, ""
+				if c.tooSlow.Load() {
+					code, reason = websocket.CloseTryAgainLater, "client too slow"
+				}
+				c.Conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
+				return
+			}
+			if err := c.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
 		}
 	}
-	return false
 }
 
-func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request, sessionID string, allowedOrigins []string) {
-	upgrader.CheckOrigin = func(r *http.Request) bool {
-		origin := r.Header.Get("Origin")
-		// Allow localhost for development
-		if strings.Contains(origin, "localhost") || strings.Contains(origin, "127.0.0.1") {
-			return true
-		}
-		return originAllowed(origin, allowedOrigins)
+// ServeWs upgrades the connection and registers it for sessionID. initial messages are sent to
+// this client alone, ahead of any broadcast, e.g. to replay state it would otherwise have missed.
+// The Origin must be in allowedOrigins; allowLocalhost also admits localhost pages, for development.
+func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request, sessionID string, allowedOrigins []string, allowLocalhost bool, initial ...Message) {
+	// Per-request upgrader: the origin check depends on allowedOrigins, and mutating a
+	// shared upgrader from concurrent requests is a data race.
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			if allowLocalhost && utils.IsLocalOrigin(origin) {
+				return true
+			}
+			return utils.OriginAllowed(origin, allowedOrigins)
+		},
 	}
 
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Println(err)
+		hub.log.Warn("websocket upgrade failed", "session_id", sessionID, "error", err)
 		return
 	}
 	client := &Client{Hub: hub, SessionID: sessionID, Conn: conn, Send: make(chan []byte, 256)}
-	client.Hub.Register <- client
+	for _, message := range initial {
+		msgBytes, _ := json.Marshal(message)
+		client.Send <- msgBytes
+	}
+	select {
+	case client.Hub.Register <- client:
+	case <-client.Hub.quit:
+		conn.Close()
+		return
+	}
 
 	go client.WritePump()
 	go client.ReadPump()
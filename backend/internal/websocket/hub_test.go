@@ -0,0 +1,143 @@
+package websocket
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func newTestHub(t *testing.T) *Hub {
+	t.Helper()
+	hub := NewHub(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	go hub.Run()
+	t.Cleanup(hub.Shutdown)
+	return hub
+}
+
+// dial opens a websocket to server sending origin, and reports whether the upgrade succeeded.
+func dial(t *testing.T, server *httptest.Server, origin string) bool {
+	t.Helper()
+	header := http.Header{}
+	if origin != "" {
+		header.Set("Origin", origin)
+	}
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), header)
+	if err != nil {
+		if resp == nil {
+			t.Fatalf("dial: %v", err)
+		}
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+func TestServeWsOrigins(t *testing.T) {
+	cases := []struct {
+		name           string
+		origin         string
+		allowed        []string
+		allowLocalhost bool
+		want           bool
+	}{
+		{"localhost denied by default", "http://localhost:3000", nil, false, false},
+		{"localhost denied when not listed", "http://127.0.0.1:5173", []string{"https://app.example.com"}, false, false},
+		{"localhost allowed in development", "http://localhost:3000", nil, true, true},
+		{"listed origin", "https://app.example.com", []string{"https://app.example.com"}, false, true},
+		{"unlisted origin", "https://evil.example.net", []string{"https://app.example.com"}, true, false},
+		{"wildcard", "https://evil.example.net", []string{"*"}, false, true},
+		{"non-browser client", "", nil, false, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			hub := newTestHub(t)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ServeWs(hub, w, r, "s1", tc.allowed, tc.allowLocalhost)
+			}))
+			defer server.Close()
+
+			if got := dial(t, server, tc.origin); got != tc.want {
+				t.Errorf("upgrade from %q succeeded = %v, want %v", tc.origin, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestHubShutdownStopsGoroutines connects clients, queues a broadcast and shuts the hub down:
+// queued messages are delivered, every client gets a 1001 close, and the hub's and clients'
+// pump goroutines all exit.
+func TestHubShutdownStopsGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	hub := NewHub(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	go hub.Run()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWs(hub, w, r, r.URL.Query().Get("session"), nil, false)
+	}))
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	sessions := []string{"s1", "s1", "s2"}
+	conns := make([]*websocket.Conn, len(sessions))
+	for i, sessionID := range sessions {
+		conn, _, err := websocket.DefaultDialer.Dial(url+"?session="+sessionID, nil)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		conns[i] = conn
+	}
+	deadline := time.Now().Add(time.Second)
+	for hub.ConnectionCount("s1") != 2 || hub.ConnectionCount("s2") != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("clients never registered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	hub.SendToSession("s1", "status_update", map[string]string{"status": "connected"})
+	hub.Shutdown()
+	if hub.Running() {
+		t.Error("hub still running after Shutdown")
+	}
+
+	sendReturned := make(chan struct{})
+	go func() {
+		hub.SendToSession("s1", "status_update", nil)
+		close(sendReturned)
+	}()
+	select {
+	case <-sendReturned:
+	case <-time.After(time.Second):
+		t.Fatal("SendToSession blocked after Shutdown")
+	}
+
+	for i, conn := range conns {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if sessions[i] == "s1" {
+			if _, data, err := conn.ReadMessage(); err != nil || !strings.Contains(string(data), "status_update") {
+				t.Errorf("client %d: queued broadcast = %q, %v", i, data, err)
+			}
+		}
+		_, _, err := conn.ReadMessage()
+		if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+			t.Errorf("client %d: got %v, want a 1001 close", i, err)
+		}
+		conn.Close()
+	}
+	server.Close()
+
+	deadline = time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("%d goroutines leaked:\n%s", runtime.NumGoroutine()-before, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,86 @@
+package whatsapp
+
+import (
+	"time"
+	"wago-backend/internal/model"
+)
+
+// touchActivity records that the session just sent or received a message.
+func (cm *ClientManager) touchActivity(sessionID string) {
+	cm.lastActivity.Store(sessionID, time.Now())
+}
+
+// LastActivity returns when the session last sent or received a message while connected.
+// Safe for concurrent use; it never takes the client map lock.
+func (cm *ClientManager) LastActivity(sessionID string) (time.Time, bool) {
+	val, ok := cm.lastActivity.Load(sessionID)
+	if !ok {
+		return time.Time{}, false
+	}
+	return val.(time.Time), true
+}
+
+func (cm *ClientManager) clearActivity(sessionID string) {
+	cm.lastActivity.Delete(sessionID)
+}
+
+// recordConnection opens or closes the session's uptime interval; failures are only logged.
+func (cm *ClientManager) recordConnection(sessionID string, connected bool) {
+	at := cm.now().UTC()
+	var err error
+	if connected {
+		err = cm.AnalyticsRepo.RecordConnected(sessionID, at)
+	} else {
+		err = cm.AnalyticsRepo.RecordDisconnected(sessionID, at)
+	}
+	if err != nil {
+		cm.sessionLog(sessionID).Error("failed to record connection state", "connected", connected, "error", err)
+	}
+}
+
+// idleSweeper periodically disconnects sessions that have been idle longer than the
+// configured period. Credentials are kept, so StartSession reconnects them on demand.
+func (cm *ClientManager) idleSweeper(idleAfter time.Duration) {
+	interval := idleAfter / 4
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cm.stopCh:
+			return
+		case <-ticker.C:
+			cm.disconnectIdle(idleAfter)
+		}
+	}
+}
+
+func (cm *ClientManager) disconnectIdle(idleAfter time.Duration) {
+	cm.mu.RLock()
+	ids := make([]string, 0, len(cm.Clients))
+	for id := range cm.Clients {
+		ids = append(ids, id)
+	}
+	cm.mu.RUnlock()
+
+	now := time.Now()
+	for _, id := range ids {
+		val, ok := cm.lastActivity.Load(id)
+		if !ok {
+			// No activity recorded yet; start the idle clock now.
+			cm.touchActivity(id)
+			continue
+		}
+		if idle := now.Sub(val.(time.Time)); idle >= idleAfter {
+			cm.sessionLog(id).Info("disconnecting idle session", "idle", idle.Round(time.Second).String())
+			cm.disconnect(id, true)
+			cm.WSHub.SendToSession(id, "status_update", map[string]interface{}{
+				"status": model.SessionStatusDisconnected,
+				"reason": "idle",
+			})
+		}
+	}
+}
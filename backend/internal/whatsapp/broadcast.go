@@ -0,0 +1,150 @@
+package whatsapp
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+	"wago-backend/internal/errs"
+	"wago-backend/internal/message"
+	"wago-backend/internal/model"
+)
+
+// broadcastResumeInterval is how often running jobs without a sender (after a restart or a
+// disconnect) are picked up again for connected sessions.
+const broadcastResumeInterval = 30 * time.Second
+
+// runBroadcastResumer resumes running broadcast jobs until Shutdown.
+func (cm *ClientManager) runBroadcastResumer() {
+	ticker := time.NewTicker(broadcastResumeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cm.stopCh:
+			return
+		case <-ticker.C:
+			connected := cm.connectedSessionIDs()
+			if len(connected) == 0 {
+				continue
+			}
+			jobs, err := cm.BroadcastRepo.RunningJobs(connected)
+			if err != nil {
+				cm.log.Error("failed to load running broadcasts", "error", err)
+				continue
+			}
+			for _, job := range jobs {
+				cm.StartBroadcast(job)
+			}
+		}
+	}
+}
+
+// StartBroadcast sends a running job's remaining recipients in the background, unless it
+// is already being sent. Progress goes to the session's websocket as broadcast_progress
+// events, followed by broadcast_completed. If the session disconnects or its send queue
+// is full, the job pauses and runBroadcastResumer picks it up later.
+func (cm *ClientManager) StartBroadcast(job model.BroadcastJob) {
+	if _, running := cm.broadcasts.LoadOrStore(job.ID, struct{}{}); running {
+		return
+	}
+	go func() {
+		defer cm.broadcasts.Delete(job.ID)
+		cm.runBroadcast(job)
+	}()
+}
+
+func (cm *ClientManager) runBroadcast(job model.BroadcastJob) {
+	log := cm.sessionLog(job.SessionID).With("broadcast_id", job.ID)
+
+	// Nothing else in this process sends this job, so a recipient still marked sending
+	// was cut off by a restart.
+	if err := cm.BroadcastRepo.FailInterrupted(job.ID); err != nil {
+		log.Error("failed to recover interrupted broadcast recipients", "error", err)
+		return
+	}
+
+	for {
+		recipient, err := cm.BroadcastRepo.NextRecipient(job.ID)
+		if err != nil {
+			log.Error("failed to load next broadcast recipient", "error", err)
+			return
+		}
+		if recipient == nil {
+			cm.finishBroadcast(log, job.ID)
+			return
+		}
+
+		claimed, err := cm.BroadcastRepo.ClaimRecipient(job.ID, recipient.Position)
+		if err != nil {
+			log.Error("failed to claim broadcast recipient", "position", recipient.Position, "error", err)
+			return
+		}
+		if !claimed {
+			return // cancelled
+		}
+
+		messageID, err := cm.SendMessage(job.SessionID, recipient.Recipient, cm.broadcastText(job, *recipient))
+		if errors.Is(err, errs.ErrNotConnected) || errors.Is(err, errs.ErrRateLimited) {
+			if err := cm.BroadcastRepo.ReleaseRecipient(job.ID, recipient.Position); err != nil {
+				log.Error("failed to release broadcast recipient", "position", recipient.Position, "error", err)
+			}
+			log.Info("broadcast paused", "reason", err)
+			return
+		}
+
+		status, reason := model.RecipientStatusSent, ""
+		if err != nil {
+			status, reason = model.RecipientStatusFailed, err.Error()
+			log.Warn("broadcast message failed", "to", recipient.Recipient, "error", err)
+		}
+		updated, err := cm.BroadcastRepo.RecordResult(job.ID, recipient.Position, status, string(messageID), reason)
+		if err != nil {
+			log.Error("failed to record broadcast result", "position", recipient.Position, "error", err)
+			return
+		}
+
+		cm.WSHub.SendToSession(job.SessionID, "broadcast_progress", map[string]interface{}{
+			"broadcast_id": job.ID,
+			"position":     recipient.Position,
+			"to":           recipient.Recipient,
+			"status":       status,
+			"message_id":   string(messageID),
+			"error":        reason,
+			"sent":         updated.Sent,
+			"failed":       updated.Failed,
+			"total":        updated.Total,
+		})
+	}
+}
+
+// broadcastText renders the job's message for one recipient: the built-in name, phone, date
+// and time variables, overridden by the recipient's own.
+func (cm *ClientManager) broadcastText(job model.BroadcastJob, recipient model.BroadcastRecipient) string {
+	if len(message.Variables(job.Message)) == 0 {
+		return job.Message
+	}
+	jid, _ := ParseRecipient(recipient.Recipient) // validated when the job was created
+	vars := message.RecipientVars(jid.User, cm.ContactName(job.SessionID, jid), cm.now())
+	for key, value := range recipient.Variables {
+		vars[key] = value
+	}
+	return message.Render(job.Message, vars)
+}
+
+func (cm *ClientManager) finishBroadcast(log *slog.Logger, jobID int64) {
+	job, err := cm.BroadcastRepo.Complete(jobID)
+	if err != nil {
+		log.Error("failed to complete broadcast", "error", err)
+		return
+	}
+	if job == nil {
+		return // cancelled
+	}
+	cm.WSHub.SendToSession(job.SessionID, "broadcast_completed", map[string]interface{}{
+		"broadcast_id": job.ID,
+		"status":       job.Status,
+		"sent":         job.Sent,
+		"failed":       job.Failed,
+		"total":        job.Total,
+	})
+}
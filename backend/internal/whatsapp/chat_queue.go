@@ -0,0 +1,71 @@
+package whatsapp
+
+import "sync"
+
+// chatQueue runs jobs that share a key one at a time, in submission order.
+// Different keys are drained independently, but at most `workers` jobs run at once
+// and at most `maxPending` jobs may wait, so a message flood can't grow without bound.
+type chatQueue struct {
+	mu         sync.Mutex
+	pending    map[string][]func()
+	queued     int
+	maxPending int
+	workers    chan struct{}
+}
+
+func newChatQueue(workers, maxPending int) *chatQueue {
+	if workers < 1 {
+		workers = 1
+	}
+	return &chatQueue{
+		pending:    make(map[string][]func()),
+		maxPending: maxPending,
+		workers:    make(chan struct{}, workers),
+	}
+}
+
+// Submit enqueues job for key. It must be called in the order the jobs should run.
+// It returns false, dropping the job, when the queue is full.
+func (q *chatQueue) Submit(key string, job func()) bool {
+	q.mu.Lock()
+	if q.maxPending > 0 && q.queued >= q.maxPending {
+		q.mu.Unlock()
+		return false
+	}
+	jobs, running := q.pending[key]
+	q.pending[key] = append(jobs, job)
+	q.queued++
+	q.mu.Unlock()
+
+	if !running {
+		go q.drain(key)
+	}
+	return true
+}
+
+func (q *chatQueue) drain(key string) {
+	for {
+		q.mu.Lock()
+		jobs := q.pending[key]
+		if len(jobs) == 0 {
+			delete(q.pending, key)
+			q.mu.Unlock()
+			return
+		}
+		job := jobs[0]
+		q.pending[key] = jobs[1:]
+		q.mu.Unlock()
+
+		q.workers <- struct{}{}
+		job()
+		<-q.workers
+
+		q.mu.Lock()
+		q.queued--
+		q.mu.Unlock()
+	}
+}
+
+func chatQueueKey(sessionID, chat string) string {
+	return sessionID + "|" + chat
+}
@@ -3,11 +3,18 @@ package whatsapp
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"regexp"
 	"strings"
 	"sync"
+	"time"
 	"wago-backend/internal/config"
+	"wago-backend/internal/errs"
+	"wago-backend/internal/logging"
+	"wago-backend/internal/media"
 	"wago-backend/internal/model"
 	"wago-backend/internal/repository"
+	"wago-backend/internal/utils"
 	"wago-backend/internal/webhook"
 	"wago-backend/internal/websocket"
 
@@ -21,36 +28,202 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+// WebhookSender is the part of the webhook service the message flow depends on,
+// so handleEvent can be driven with a fake sender instead of real HTTP calls.
+type WebhookSender interface {
+	SendWebhook(endpoint webhook.Endpoint, payload webhook.WebhookPayload) (webhook.WebhookResult, error)
+	ResolveMedia(m *webhook.MediaReply) ([]byte, string, error)
+}
+
+// SessionStore is the session persistence the manager depends on. *repository.SessionRepository
+// implements it; tests substitute an in-memory fake.
+type SessionStore interface {
+	GetSessionByID(id string) (*model.Session, error)
+	GetSessionsWithPhoneNumber() ([]*model.Session, error)
+	GetGroupSetting(sessionID, groupJID string) (*model.GroupSetting, error)
+	UpdateSessionStatus(id string, status model.SessionStatus, phoneNumber *string, deviceInfo *model.DeviceInfo) error
+	UpdatePhoneNumber(id, phoneNumber string) error
+	UpdateDeviceInfo(id string, deviceInfo *model.DeviceInfo) error
+}
+
+// AnalyticsStore records message logs, webhook analytics and connection uptime.
+// *repository.AnalyticsRepository implements it.
+type AnalyticsStore interface {
+	LogMessage(log *model.MessageLog) error
+	LogAnalytics(a *model.Analytics) error
+	RecordConnected(sessionID string, at time.Time) error
+	RecordDisconnected(sessionID string, at time.Time) error
+	CloseOpenConnections(at time.Time) error
+}
+
 type ClientManager struct {
 	Clients        map[string]*whatsmeow.Client
 	Config         *config.Config
-	SessionRepo    *repository.SessionRepository
-	AnalyticsRepo  *repository.AnalyticsRepository
+	SessionRepo    SessionStore
+	AnalyticsRepo  AnalyticsStore
+	ScheduleRepo   *repository.ScheduleRepository
+	BroadcastRepo  *repository.BroadcastRepository
 	WSHub          *websocket.Hub
-	WebhookService *webhook.WebhookService
+	WebhookService WebhookSender
+	MediaStore     media.MediaStore
 	Container      *sqlstore.Container
 	mu             sync.RWMutex
+
+	// log is the manager's structured logger; whatsmeow keeps its own waLog loggers.
+	log *slog.Logger
+
+	// now is the clock used for webhook latency and timestamps; replaceable in tests.
+	now func() time.Time
+
+	// chatQueue serializes webhook dispatch and replies per chat.
+	chatQueue *chatQueue
+
+	// triggers caches each session's compiled trigger pattern.
+	triggers   map[string]compiledTrigger
+	triggersMu sync.Mutex
+
+	// lastActivity maps session ID -> time.Time of the last inbound/outbound message.
+	// Kept outside mu so message handling never contends with the client map lock.
+	lastActivity sync.Map
+
+	// lastEvent maps session ID -> time.Time of the last whatsmeow event of any kind.
+	lastEvent sync.Map
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+
+	// lastConnectAttempt records when Connect last dialed each session; guarded by mu.
+	lastConnectAttempt map[string]time.Time
+
+	// groupNames caches group subjects for payloads and logs, so busy groups don't cost a
+	// WhatsApp query per message.
+	groupNames ttlCache
+
+	// groupInfo queries WhatsApp for a group on a cache miss; replaceable in tests.
+	groupInfo func(client *whatsmeow.Client, ctx context.Context, group types.JID) (*types.GroupInfo, error)
+
+	// profilePictures caches contact profile picture URLs for the contact directory.
+	profilePictures ttlCache
+
+	// pictureInfo queries WhatsApp for a contact's profile picture; replaceable in tests.
+	pictureInfo func(client *whatsmeow.Client, ctx context.Context, jid types.JID, params *whatsmeow.GetProfilePictureParams) (*types.ProfilePictureInfo, error)
+
+	// sendQueue throttles outgoing messages per session.
+	sendQueue *sendQueue
+
+	// scheduling marks sessions whose due scheduled messages are being sent.
+	scheduling sync.Map
+
+	// broadcasts marks broadcast job IDs that are being sent.
+	broadcasts sync.Map
+
+	// reconnects holds the cancel channel of each session's pending reconnect loop.
+	reconnects  map[string]chan struct{}
+	reconnectMu sync.Mutex
+
+	// qrCodes holds the latest unscanned QR code per session.
+	qrCodes map[string]PendingQR
+	qrMu    sync.RWMutex
 }
 
-func NewClientManager(cfg *config.Config, sessionRepo *repository.SessionRepository, analyticsRepo *repository.AnalyticsRepository, wsHub *websocket.Hub, webhookService *webhook.WebhookService) *ClientManager {
+// NewClientManager initializes the whatsmeow SQL store and returns a manager for it.
+// Store initialization errors are returned so the caller can exit cleanly.
+// A nil logger falls back to slog's default.
+func NewClientManager(cfg *config.Config, sessionRepo *repository.SessionRepository, analyticsRepo *repository.AnalyticsRepository, scheduleRepo *repository.ScheduleRepository, broadcastRepo *repository.BroadcastRepository, wsHub *websocket.Hub, webhookService *webhook.WebhookService, mediaStore media.MediaStore, logger *slog.Logger) (*ClientManager, error) {
 	// Initialize whatsmeow SQL store
 	dbLog := waLog.Stdout("Database", cfg.LogLevel, true)
 	container, err := sqlstore.New(context.Background(), "postgres", cfg.DatabaseURL, dbLog)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to initialize whatsapp store: %w", err)
 	}
 
-	return &ClientManager{
+	cm := &ClientManager{
 		Clients:        make(map[string]*whatsmeow.Client),
 		Config:         cfg,
 		SessionRepo:    sessionRepo,
 		AnalyticsRepo:  analyticsRepo,
+		ScheduleRepo:   scheduleRepo,
+		BroadcastRepo:  broadcastRepo,
 		WSHub:          wsHub,
 		WebhookService: webhookService,
+		MediaStore:     mediaStore,
 		Container:      container,
+		qrCodes:        make(map[string]PendingQR),
+		reconnects:     make(map[string]chan struct{}),
+		chatQueue:      newChatQueue(cfg.MessageWorkers, cfg.MessageQueueSize),
+		triggers:       make(map[string]compiledTrigger),
+		stopCh:         make(chan struct{}),
+		now:            time.Now,
+		groupInfo:      (*whatsmeow.Client).GetGroupInfo,
+		pictureInfo:    (*whatsmeow.Client).GetProfilePictureInfo,
+		log:            logging.OrDefault(logger),
+
+		lastConnectAttempt: make(map[string]time.Time),
+	}
+	cm.sendQueue = newSendQueue(cfg.SendRatePerMinute, cfg.SendQueueSize, cm.stopCh)
+
+	if cfg.IdleDisconnectAfter > 0 {
+		go cm.idleSweeper(cfg.IdleDisconnectAfter)
+	}
+	if scheduleRepo != nil {
+		go cm.runScheduler()
+	}
+	if broadcastRepo != nil {
+		go cm.runBroadcastResumer()
+	}
+
+	return cm, nil
+}
+
+// watchQRChannel relays QR codes to the websocket until pairing succeeds or the channel ends.
+// Pairing success itself is handled by handleEvent (PairSuccess).
+func (cm *ClientManager) watchQRChannel(sessionID string, qrChan <-chan whatsmeow.QRChannelItem) {
+	for evt := range qrChan {
+		cm.handleQRItem(sessionID, evt)
+	}
+}
+
+func (cm *ClientManager) handleQRItem(sessionID string, evt whatsmeow.QRChannelItem) {
+	switch evt.Event {
+	case whatsmeow.QRChannelEventCode:
+		cm.setQRCode(sessionID, evt.Code, evt.Timeout)
+
+		// Send QR to WebSocket
+		cm.WSHub.SendToSession(sessionID, "qr_update", map[string]interface{}{
+			"qr_code":    evt.Code,
+			"expires_in": int(evt.Timeout.Seconds()),
+		})
+
+		// Update DB status to 'qr'
+		cm.SessionRepo.UpdateSessionStatus(sessionID, model.SessionStatusQR, nil, nil)
+	case whatsmeow.QRChannelSuccess.Event:
+		cm.clearQRCode(sessionID)
+	case whatsmeow.QRChannelEventError:
+		cm.reportQRError(sessionID, evt.Event, evt.Error)
+	default:
+		// timeout, err-client-outdated, err-scanned-without-multidevice, err-unexpected-state
+		cm.reportQRError(sessionID, evt.Event, nil)
 	}
 }
 
+// reportQRError clears the pending QR, marks the session disconnected and tells the
+// frontend why, so the user isn't left waiting on a QR that will never arrive.
+func (cm *ClientManager) reportQRError(sessionID, event string, err error) {
+	cm.clearQRCode(sessionID)
+	cm.SessionRepo.UpdateSessionStatus(sessionID, model.SessionStatusDisconnected, nil, nil)
+
+	message := event
+	if err != nil {
+		message = err.Error()
+	}
+	cm.sessionLog(sessionID).Info("QR flow ended", "reason", message)
+
+	cm.WSHub.SendToSession(sessionID, "qr_error", map[string]interface{}{
+		"event": event,
+		"error": message,
+	})
+}
+
 // normalizeSessionJID tries to turn whatever is stored in the DB into a valid JID that includes server (and device if present).
 // types.ParseJID doesn't error on plain numbers, so we additionally ensure the user part is present.
 func normalizeSessionJID(raw string) (types.JID, error) {
@@ -83,26 +256,149 @@ func normalizeSessionJID(raw string) (types.JID, error) {
 	return jid, nil
 }
 
+// PendingQR is a QR code waiting to be scanned and when WhatsApp rotates it.
+type PendingQR struct {
+	Code      string
+	ExpiresAt time.Time
+}
+
+// ExpiresIn is the whole number of seconds left before the code rotates.
+func (q PendingQR) ExpiresIn(now time.Time) int {
+	return int(q.ExpiresAt.Sub(now).Seconds())
+}
+
+// CurrentQRCode returns the latest QR code generated for the session, if pairing is pending
+// and the code hasn't expired yet.
+func (cm *ClientManager) CurrentQRCode(sessionID string) (PendingQR, bool) {
+	cm.qrMu.RLock()
+	defer cm.qrMu.RUnlock()
+	qr, ok := cm.qrCodes[sessionID]
+	if !ok || !cm.now().Before(qr.ExpiresAt) {
+		return PendingQR{}, false
+	}
+	return qr, true
+}
+
+func (cm *ClientManager) setQRCode(sessionID, code string, timeout time.Duration) {
+	cm.qrMu.Lock()
+	cm.qrCodes[sessionID] = PendingQR{Code: code, ExpiresAt: cm.now().Add(timeout)}
+	cm.qrMu.Unlock()
+}
+
+func (cm *ClientManager) clearQRCode(sessionID string) {
+	cm.qrMu.Lock()
+	delete(cm.qrCodes, sessionID)
+	cm.qrMu.Unlock()
+}
+
+type compiledTrigger struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// triggerRegexp returns the compiled trigger for a session, recompiling only when the pattern changed.
+func (cm *ClientManager) triggerRegexp(sessionID, pattern string) (*regexp.Regexp, error) {
+	cm.triggersMu.Lock()
+	defer cm.triggersMu.Unlock()
+
+	if t, ok := cm.triggers[sessionID]; ok && t.pattern == pattern {
+		return t.re, nil
+	}
+
+	re, err := utils.CompileTriggerPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	cm.triggers[sessionID] = compiledTrigger{pattern: pattern, re: re}
+	return re, nil
+}
+
+// sessionLog returns the manager's logger with the session ID attached.
+func (cm *ClientManager) sessionLog(sessionID string) *slog.Logger {
+	return cm.log.With("session_id", sessionID)
+}
+
 func (cm *ClientManager) GetClient(sessionID string) *whatsmeow.Client {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
 	return cm.Clients[sessionID]
 }
 
-func (cm *ClientManager) Connect(sessionID string) (string, error) {
+// connectedClient returns the session's client only if it is connected and logged in.
+func (cm *ClientManager) connectedClient(sessionID string) (*whatsmeow.Client, error) {
+	client := cm.GetClient(sessionID)
+	if client == nil || !client.IsConnected() || !client.IsLoggedIn() {
+		return nil, errs.ErrNotConnected
+	}
+	return client, nil
+}
+
+// StatusCoolingDown is returned by Connect when the session was dialed less than
+// ReconnectCooldown ago; the caller should retry later.
+const StatusCoolingDown = "cooling_down"
+
+// coolingDown reports whether a connect attempt for sessionID is too soon, and otherwise
+// records this attempt. Callers must hold cm.mu.
+func (cm *ClientManager) coolingDown(sessionID string) bool {
+	cooldown := cm.Config.ReconnectCooldown
+	now := time.Now()
+	if last, ok := cm.lastConnectAttempt[sessionID]; ok && cooldown > 0 && now.Sub(last) < cooldown {
+		cm.sessionLog(sessionID).Info("connect skipped: cooling down", "since_last_attempt", now.Sub(last).Round(time.Second).String(), "cooldown", cooldown.String())
+		return true
+	}
+	cm.lastConnectAttempt[sessionID] = now
+	return false
+}
+
+// rememberDeviceJID persists the full JID (with device) found for a session whose stored JID
+// lacked it, so the next reconnect uses the exact match. Only the phone number is written:
+// the status read alongside stored may already be stale, e.g. overtaken by a Connected event.
+func (cm *ClientManager) rememberDeviceJID(sessionID, stored string, device types.JID) {
+	if device.String() == stored {
+		return
+	}
+	if err := cm.SessionRepo.UpdatePhoneNumber(sessionID, device.String()); err != nil {
+		cm.sessionLog(sessionID).Error("failed to persist full JID", "error", err)
+	}
+}
+
+// StatusNeedsRelink is returned by Connect when the session's stored device no longer exists
+// and no QR flow was requested; see config.MissingDeviceFallback.
+const StatusNeedsRelink = string(model.SessionStatusNeedsRelink)
+
+// Connect starts or resumes a session. allowQR lets it fall back to a new QR pairing when the
+// session has a stored JID whose device is missing from the store; sessions that were never
+// paired always get a QR.
+func (cm *ClientManager) Connect(sessionID string, allowQR bool) (string, error) {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
+	if client, ok := cm.Clients[sessionID]; ok && client.IsConnected() {
+		if client.Store.ID == nil {
+			// Still waiting for the QR to be scanned.
+			return "qr", nil
+		}
+		return "connected", nil
+	}
+
+	// Reconnecting in a tight loop can get the number banned; space attempts out.
+	if cm.coolingDown(sessionID) {
+		return StatusCoolingDown, nil
+	}
+
 	if client, ok := cm.Clients[sessionID]; ok {
-		if client.IsConnected() {
+		// Cached but dropped. A paired client can simply reconnect; an unpaired one
+		// (stale QR flow) is discarded so a fresh client and QR channel are created below.
+		if client.Store.ID != nil {
+			if err := client.Connect(); err != nil {
+				return "", fmt.Errorf("failed to reconnect cached client: %w", err)
+			}
+			cm.touchActivity(sessionID)
 			return "connected", nil
 		}
-		// If client exists but not connected, we might want to reconnect?
-		// But for now, let's assume if it's in the map, it's being handled.
-		// However, if it's in the map but disconnected, we should probably let it proceed to reconnect logic?
-		// But NewClient creates a new instance.
-		// Let's just return "connected" if it's in the map for simplicity, or "connecting".
-		return "connected", nil
+		client.Disconnect()
+		delete(cm.Clients, sessionID)
+		cm.clearQRCode(sessionID)
 	}
 
 	// Get device store
@@ -113,38 +409,29 @@ func (cm *ClientManager) Connect(sessionID string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	if session == nil {
-		return "", fmt.Errorf("session not found")
-	}
 
 	ctx := context.Background()
 
 	if session.PhoneNumber != "" {
 		jid, err := normalizeSessionJID(session.PhoneNumber)
 		if err != nil {
-			fmt.Printf("Invalid stored JID for session %s (%s): %v\n", sessionID, session.PhoneNumber, err)
+			cm.sessionLog(sessionID).Warn("invalid stored JID", "jid", session.PhoneNumber, "error", err)
 		} else {
 			deviceStore, err = cm.Container.GetDevice(ctx, jid)
 			if err != nil {
-				fmt.Printf("Device lookup failed for %s: %v\n", jid.String(), err)
+				cm.sessionLog(sessionID).Warn("device lookup failed", "jid", jid.String(), "error", err)
 			}
 
 			// If direct lookup failed (e.g. stored JID missing device ID), search by user/server.
 			if deviceStore == nil {
 				devices, listErr := cm.Container.GetAllDevices(ctx)
 				if listErr != nil {
-					fmt.Printf("Failed to list devices for session %s: %v\n", sessionID, listErr)
+					cm.sessionLog(sessionID).Error("failed to list devices", "error", listErr)
 				} else {
 					for _, dev := range devices {
 						if dev.ID.User == jid.User && dev.ID.Server == jid.Server {
 							deviceStore = dev
-							// Persist the full JID (with device) so next reconnect uses the exact match.
-							if dev.ID.String() != session.PhoneNumber {
-								if dev.ID.String() != session.PhoneNumber {
-									ph := dev.ID.String()
-									cm.SessionRepo.UpdateSessionStatus(sessionID, session.Status, &ph, session.DeviceInfo)
-								}
-							}
+							cm.rememberDeviceJID(sessionID, session.PhoneNumber, *dev.ID)
 							break
 						}
 					}
@@ -154,6 +441,11 @@ func (cm *ClientManager) Connect(sessionID string) (string, error) {
 	}
 
 	if deviceStore == nil {
+		if session.PhoneNumber != "" && !allowQR && cm.Config.MissingDeviceFallback != config.MissingDeviceQR {
+			// Don't silently swap an expected reconnect for a QR flow.
+			cm.markNeedsRelink(session)
+			return StatusNeedsRelink, nil
+		}
 		// New device (QR mode)
 		deviceStore = cm.Container.NewDevice()
 	}
@@ -167,55 +459,66 @@ func (cm *ClientManager) Connect(sessionID string) (string, error) {
 	})
 
 	cm.Clients[sessionID] = client
+	cm.touchActivity(sessionID)
+	return cm.startClient(sessionID, client)
+}
 
-	// Connect
-	if client.Store.ID == nil {
-		// No ID means not logged in.
-		// Get QR Channel
-		qrChan, _ := client.GetQRChannel(context.Background())
-		err = client.Connect()
-		if err != nil {
-			return "", err
-		}
-
-		// Listen for QR
-		go func() {
-			for evt := range qrChan {
-				if evt.Event == "code" {
-					// Send QR to WebSocket
-					cm.WSHub.SendToSession(sessionID, "qr_update", map[string]interface{}{
-						"qr_code":    evt.Code,
-						"expires_in": 60, // approximate
-					})
-
-					// Update DB status to 'qr'
-					cm.SessionRepo.UpdateSessionStatus(sessionID, model.SessionStatusQR, nil, nil)
-				} else {
-					// Timeout or success?
-					// Success is handled by EventHandler
-				}
-			}
-		}()
-		return "qr", nil
-	} else {
-		// Already logged in
-		err = client.Connect()
-		if err != nil {
+// startClient connects a newly registered client: paired devices connect directly, unpaired
+// ones start the QR flow. Called with cm.mu held.
+func (cm *ClientManager) startClient(sessionID string, client *whatsmeow.Client) (string, error) {
+	if client.Store.ID != nil {
+		// Already paired: no QR needed.
+		if err := client.Connect(); err != nil {
 			return "", err
 		}
-		// Update status just in case
-		// cm.SessionRepo.UpdateSessionStatus(sessionID, model.SessionStatusConnected, client.Store.ID.User, nil)
 		return "connected", nil
 	}
+
+	// The client is new and unpaired, so GetQRChannel should not fail; if it does, the
+	// dashboard is told rather than left waiting for a QR code.
+	qrChan, err := client.GetQRChannel(context.Background())
+	if err != nil {
+		delete(cm.Clients, sessionID)
+		cm.reportQRError(sessionID, "error", err)
+		return "", fmt.Errorf("failed to get QR channel: %w", err)
+	}
+	if err := client.Connect(); err != nil {
+		return "", err
+	}
+
+	// Listen for QR
+	go cm.watchQRChannel(sessionID, qrChan)
+	return "qr", nil
+}
+
+// markNeedsRelink records that the session's device is gone and tells its dashboards.
+// The stored JID is kept so the report endpoints still show which account it was.
+func (cm *ClientManager) markNeedsRelink(session *model.Session) {
+	log := cm.sessionLog(session.ID)
+	log.Warn("device not found in store; session needs relinking", "jid", session.PhoneNumber)
+	if err := cm.SessionRepo.UpdateSessionStatus(session.ID, model.SessionStatusNeedsRelink, nil, session.DeviceInfo); err != nil {
+		log.Error("failed to mark session as needs_relink", "error", err)
+	}
+	cm.WSHub.SendToSession(session.ID, "needs_relink", map[string]interface{}{
+		"session_id":   session.ID,
+		"phone_number": session.PhoneNumber,
+		"message":      "Linked device not found; start the session with relink=true to scan a new QR code",
+	})
 }
 
 func (cm *ClientManager) disconnect(sessionID string, updateStatus bool) {
+	cm.cancelReconnect(sessionID)
+
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
+	cm.clearQRCode(sessionID)
+	cm.clearActivity(sessionID)
+
 	if client, ok := cm.Clients[sessionID]; ok {
 		client.Disconnect()
 		delete(cm.Clients, sessionID)
+		cm.recordConnection(sessionID, false)
 		if updateStatus {
 			cm.SessionRepo.UpdateSessionStatus(sessionID, model.SessionStatusDisconnected, nil, nil)
 		}
@@ -227,8 +530,10 @@ func (cm *ClientManager) Disconnect(sessionID string) {
 	cm.disconnect(sessionID, true)
 }
 
-// Shutdown disconnects all active clients gracefully.
+// Shutdown disconnects all active clients gracefully, then drains and stops the websocket hub.
 func (cm *ClientManager) Shutdown() {
+	cm.stopOnce.Do(func() { close(cm.stopCh) })
+
 	cm.mu.RLock()
 	ids := make([]string, 0, len(cm.Clients))
 	for id := range cm.Clients {
@@ -240,51 +545,101 @@ func (cm *ClientManager) Shutdown() {
 		// Do not overwrite status/phone_number during shutdown so auto-reconnect still works
 		cm.disconnect(id, false)
 	}
+
+	// Last, so status updates from the disconnects above still reach the dashboards.
+	if cm.WSHub != nil {
+		cm.WSHub.Shutdown()
+	}
 }
 
 // ReconnectAllSessions reconnects all sessions that are marked as connected in the DB
 func (cm *ClientManager) ReconnectAllSessions() {
+	cm.logStoreReport()
+
+	// Connections still open in the uptime log were cut by an unclean shutdown.
+	if err := cm.AnalyticsRepo.CloseOpenConnections(cm.now().UTC()); err != nil {
+		cm.log.Error("failed to close stale connection records", "error", err)
+	}
+
 	// Try reconnecting any session that has a stored JID (phone_number),
 	// even if status wasn't left as "connected" due to an unclean shutdown.
 	sessions, err := cm.SessionRepo.GetSessionsWithPhoneNumber()
 	if err != nil {
-		fmt.Printf("Failed to fetch connected sessions for reconnect: %v\n", err)
+		cm.log.Error("failed to fetch sessions to reconnect", "error", err)
 		return
 	}
 
 	if len(sessions) == 0 {
-		fmt.Println("ReconnectAllSessions: no sessions with stored JID found")
+		cm.log.Info("no sessions with stored JID to reconnect")
 		return
 	}
 
-	fmt.Printf("ReconnectAllSessions: found %d session(s) with stored JID\n", len(sessions))
+	cm.log.Info("reconnecting sessions with stored JID", "count", len(sessions))
 
 	for _, session := range sessions {
-		fmt.Printf("Reconnecting session: %s (%s) [status=%s, jid=%s]\n", session.SessionName, session.ID, session.Status, session.PhoneNumber)
+		cm.sessionLog(session.ID).Info("reconnecting session", "name", session.SessionName, "status", session.Status, "jid", session.PhoneNumber)
 		go func(id string) {
-			if _, err := cm.Connect(id); err != nil {
-				fmt.Printf("Failed to reconnect session %s: %v\n", id, err)
-				// Optional: Update status to disconnected if reconnect fails repeatedly
+			status, err := cm.Connect(id, false)
+			if err != nil {
+				cm.sessionLog(id).Error("failed to reconnect session", "error", err)
+			}
+			if err != nil || status == StatusCoolingDown {
+				cm.scheduleReconnect(id)
 			}
 		}(session.ID)
 	}
 }
 
 // SendMessage sends a text message from a specific session to a recipient
-func (cm *ClientManager) SendMessage(sessionID string, recipient string, message string) error {
-	client := cm.GetClient(sessionID)
-	if client == nil {
-		return fmt.Errorf("client not found or not connected")
+// mediaKey is where a message's media is stored.
+func mediaKey(sessionID, messageID string) string {
+	return sessionID + "/" + messageID
+}
+
+// GetMedia returns media persisted for one of the session's messages.
+func (cm *ClientManager) GetMedia(sessionID, messageID string) ([]byte, string, error) {
+	if cm.MediaStore == nil {
+		return nil, "", media.ErrNotFound
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return cm.MediaStore.Get(ctx, mediaKey(sessionID, messageID))
+}
+
+// storeMedia persists downloaded media; failures are logged and don't affect message handling.
+func (cm *ClientManager) storeMedia(sessionID, messageID, contentType string, data []byte) {
+	if cm.MediaStore == nil {
+		return
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	if err := cm.MediaStore.Put(ctx, mediaKey(sessionID, messageID), data, contentType); err != nil {
+		cm.sessionLog(sessionID).Error("failed to store media", "message_id", messageID, "error", err)
+	}
+}
 
-	if !client.IsConnected() {
-		return fmt.Errorf("client is not connected")
+// ParseRecipient turns a phone number or JID string into a JID, wrapping errs.ErrInvalidInput on failure.
+// See utils.NormalizeRecipient for the accepted formats.
+func ParseRecipient(recipient string) (types.JID, error) {
+	jid, err := utils.NormalizeRecipient(recipient)
+	if err != nil {
+		return types.JID{}, fmt.Errorf("%w: %v", errs.ErrInvalidInput, err)
+	}
+	return jid, nil
+}
+
+// SendMessage sends a text message and logs it as outgoing. It returns the WhatsApp message ID.
+// A session that is not connected and logged in yields errs.ErrNotConnected.
+func (cm *ClientManager) SendMessage(sessionID string, recipient string, message string) (types.MessageID, error) {
+	// Validate the recipient first so malformed input is a 400 even when the session is offline.
+	jid, err := ParseRecipient(recipient)
+	if err != nil {
+		return "", err
 	}
 
-	// Parse recipient JID
-	jid, err := normalizeSessionJID(recipient)
+	client, err := cm.connectedClient(sessionID)
 	if err != nil {
-		return fmt.Errorf("invalid recipient number: %v", err)
+		return "", err
 	}
 
 	// Construct message
@@ -292,6 +647,244 @@ func (cm *ClientManager) SendMessage(sessionID string, recipient string, message
 		Conversation: proto.String(message),
 	}
 
-	_, err = client.SendMessage(context.Background(), jid, msg)
+	resp, err := cm.send(context.Background(), sessionID, client, jid, msg)
+	if err != nil {
+		return "", fmt.Errorf("failed to send message: %w", err)
+	}
+	cm.touchActivity(sessionID)
+	go cm.logOutgoing(sessionID, resp.ID, jid, jid.Server == types.GroupServer, "", "text", message)
+	return resp.ID, nil
+}
+
+// SendMedia uploads data and sends it as an image, video, audio or document depending on
+// mimeType, logging it as outgoing. An empty fileName is derived from the type and time.
+// It returns the WhatsApp message ID and the message type sent.
+func (cm *ClientManager) SendMedia(sessionID, recipient string, data []byte, mimeType, fileName, caption string) (types.MessageID, string, error) {
+	jid, err := ParseRecipient(recipient)
+	if err != nil {
+		return "", "", err
+	}
+
+	client, err := cm.connectedClient(sessionID)
+	if err != nil {
+		return "", "", err
+	}
+
+	if fileName == "" {
+		_, kind := mediaKind(mimeType)
+		fileName = mediaFileName(kind, mimeType, cm.now())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	msg, messageType, err := buildMediaMessage(ctx, client, data, mimeType, fileName, caption)
+	if err != nil {
+		return "", "", err
+	}
+
+	resp, err := cm.send(ctx, sessionID, client, jid, msg)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to send %s: %w", messageType, err)
+	}
+	cm.touchActivity(sessionID)
+	go cm.logOutgoing(sessionID, resp.ID, jid, jid.Server == types.GroupServer, "", messageType, caption)
+	return resp.ID, messageType, nil
+}
+
+// SendLocation shares a map pin. Coordinates must be valid WGS84 degrees.
+func (cm *ClientManager) SendLocation(sessionID string, to types.JID, lat, lon float64, name string) error {
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return fmt.Errorf("%w: coordinates out of range", errs.ErrInvalidInput)
+	}
+
+	client, err := cm.connectedClient(sessionID)
+	if err != nil {
+		return err
+	}
+
+	msg := &waE2E.Message{
+		LocationMessage: &waE2E.LocationMessage{
+			DegreesLatitude:  proto.Float64(lat),
+			DegreesLongitude: proto.Float64(lon),
+			Name:             proto.String(name),
+		},
+	}
+
+	_, err = cm.send(context.Background(), sessionID, client, to, msg)
+	if err == nil {
+		cm.touchActivity(sessionID)
+	}
 	return err
 }
+
+// RefreshDeviceInfo re-reads the device details WhatsApp keeps in the client store
+// and persists them, so the dashboard reflects changes made after pairing.
+func (cm *ClientManager) RefreshDeviceInfo(sessionID string) (*model.DeviceInfo, error) {
+	client, err := cm.connectedClient(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceInfo := &model.DeviceInfo{
+		Platform:     client.Store.Platform,
+		DeviceModel:  client.Store.BusinessName,
+		PushName:     client.Store.PushName,
+		BusinessName: client.Store.BusinessName,
+	}
+
+	if err := cm.SessionRepo.UpdateDeviceInfo(sessionID, deviceInfo); err != nil {
+		return nil, err
+	}
+
+	cm.WSHub.SendToSession(sessionID, "device_info_update", map[string]interface{}{
+		"device_info": deviceInfo,
+	})
+
+	return deviceInfo, nil
+}
+
+// SetPresence marks the session's account as globally available or unavailable,
+// which controls the "online" and last-seen status contacts see.
+func (cm *ClientManager) SetPresence(sessionID string, available bool) error {
+	client, err := cm.connectedClient(sessionID)
+	if err != nil {
+		return err
+	}
+
+	presence := types.PresenceUnavailable
+	if available {
+		presence = types.PresenceAvailable
+	}
+	if err := client.SendPresence(context.Background(), presence); err != nil {
+		return fmt.Errorf("failed to send presence: %w", err)
+	}
+	return nil
+}
+
+// ParseGroupJID accepts only group JIDs such as "120363012345678901@g.us",
+// wrapping errs.ErrInvalidInput on failure.
+func ParseGroupJID(raw string) (types.JID, error) {
+	jid, err := types.ParseJID(strings.TrimSpace(raw))
+	if err != nil || jid.User == "" || jid.Server != types.GroupServer {
+		return types.JID{}, fmt.Errorf("%w: invalid group JID, expected <id>@%s", errs.ErrInvalidInput, types.GroupServer)
+	}
+	return jid.ToNonAD(), nil
+}
+
+// ListGroups returns the groups the session's account is currently a member of.
+func (cm *ClientManager) ListGroups(sessionID string) ([]model.Group, error) {
+	client, err := cm.connectedClient(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	joined, err := client.GetJoinedGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch joined groups: %w", err)
+	}
+
+	groups := make([]model.Group, 0, len(joined))
+	for _, g := range joined {
+		groups = append(groups, model.Group{
+			JID:              g.JID.String(),
+			Name:             g.Name,
+			ParticipantCount: len(g.Participants),
+		})
+	}
+	return groups, nil
+}
+
+// StoreReport matches stored whatsmeow devices against sessions with a saved JID, flagging
+// devices no session points at and sessions whose device is gone.
+func (cm *ClientManager) StoreReport() (*model.StoreReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	devices, err := cm.Container.GetAllDevices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+
+	sessions, err := cm.SessionRepo.GetSessionsWithPhoneNumber()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list paired sessions: %w", err)
+	}
+
+	report := &model.StoreReport{
+		DeviceCount:            len(devices),
+		PairedSessionCount:     len(sessions),
+		OrphanedDevices:        []string{},
+		SessionsMissingDevices: []string{},
+	}
+
+	// Match on user/server only; older rows may lack the device part of the JID.
+	referenced := make(map[string]bool, len(sessions))
+	for _, session := range sessions {
+		jid, err := normalizeSessionJID(session.PhoneNumber)
+		if err != nil {
+			report.SessionsMissingDevices = append(report.SessionsMissingDevices, session.ID)
+			continue
+		}
+		referenced[jid.ToNonAD().String()] = true
+	}
+
+	stored := make(map[string]bool, len(devices))
+	for _, dev := range devices {
+		if dev.ID == nil {
+			continue
+		}
+		key := dev.ID.ToNonAD().String()
+		stored[key] = true
+		if !referenced[key] {
+			report.OrphanedDevices = append(report.OrphanedDevices, dev.ID.String())
+		}
+	}
+
+	for _, session := range sessions {
+		jid, err := normalizeSessionJID(session.PhoneNumber)
+		if err == nil && !stored[jid.ToNonAD().String()] {
+			report.SessionsMissingDevices = append(report.SessionsMissingDevices, session.ID)
+		}
+	}
+
+	return report, nil
+}
+
+// logStoreReport runs the store self-test and logs a summary; used at boot.
+func (cm *ClientManager) logStoreReport() {
+	report, err := cm.StoreReport()
+	if err != nil {
+		cm.log.Error("store self-test failed", "error", err)
+		return
+	}
+
+	cm.log.Info("store self-test", "devices", report.DeviceCount, "paired_sessions", report.PairedSessionCount)
+	for _, jid := range report.OrphanedDevices {
+		cm.log.Warn("store self-test: orphaned device (no session references it)", "jid", jid)
+	}
+	for _, id := range report.SessionsMissingDevices {
+		cm.sessionLog(id).Warn("store self-test: session points at a missing device")
+	}
+}
+
+// ContactName returns the best known display name for jid from the session's contact store,
+// or "" when the contact is unknown.
+func (cm *ClientManager) ContactName(sessionID string, jid types.JID) string {
+	client := cm.GetClient(sessionID)
+	if client == nil || client.Store == nil || client.Store.Contacts == nil {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	contact, err := client.Store.Contacts.GetContact(ctx, jid.ToNonAD())
+	if err != nil || !contact.Found {
+		return ""
+	}
+	return contactDisplayName(contact)
+}
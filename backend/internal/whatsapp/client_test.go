@@ -0,0 +1,143 @@
+package whatsapp
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wago-backend/internal/errs"
+	"wago-backend/internal/model"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/store"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// offlineTransport fails every request, counting them, so connects never reach WhatsApp.
+type offlineTransport struct{ dials atomic.Int32 }
+
+func (o *offlineTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	o.dials.Add(1)
+	return nil, errors.New("offline")
+}
+
+func TestConnectReconnectsDisconnectedPairedClient(t *testing.T) {
+	h := newTestHarness(t, testSession("s1"))
+	cached := h.addClient("s1", testOwnJID)
+	transport := &offlineTransport{}
+	cached.SetWebsocketHTTPClient(&http.Client{Transport: transport})
+
+	status, err := h.cm.Connect("s1", false)
+
+	// The cached client is reconnected in place rather than rebuilt from the device store.
+	if err == nil || !strings.Contains(err.Error(), "reconnect cached client") {
+		t.Fatalf("Connect = %q, %v; want the cached client's reconnect error", status, err)
+	}
+	if transport.dials.Load() == 0 {
+		t.Error("cached client never tried to reconnect")
+	}
+	if got := h.cm.GetClient("s1"); got != cached {
+		t.Error("paired client was dropped from the cache after a failed reconnect")
+	}
+}
+
+func TestConnectDiscardsDisconnectedUnpairedClient(t *testing.T) {
+	// The session row is gone, so Connect stops right after dealing with the cached client.
+	h := newTestHarness(t)
+	stale := whatsmeow.NewClient(&store.Device{}, nil)
+	h.cm.mu.Lock()
+	h.cm.Clients["s1"] = stale
+	h.cm.mu.Unlock()
+	h.cm.setQRCode("s1", "stale-qr", time.Minute)
+
+	if _, err := h.cm.Connect("s1", true); !errors.Is(err, errs.ErrSessionNotFound) {
+		t.Fatalf("Connect error = %v, want ErrSessionNotFound", err)
+	}
+	if h.cm.GetClient("s1") != nil {
+		t.Error("stale unpaired client is still cached")
+	}
+	if _, ok := h.cm.CurrentQRCode("s1"); ok {
+		t.Error("stale QR code is still served")
+	}
+}
+
+func TestConnectCoolsDownBetweenAttempts(t *testing.T) {
+	h := newTestHarness(t, testSession("s1"))
+	h.cm.Config.ReconnectCooldown = time.Minute
+	cached := h.addClient("s1", testOwnJID)
+	cached.SetWebsocketHTTPClient(&http.Client{Transport: &offlineTransport{}})
+
+	if _, err := h.cm.Connect("s1", false); err == nil {
+		t.Fatal("first Connect unexpectedly succeeded offline")
+	}
+	if status, err := h.cm.Connect("s1", false); err != nil || status != StatusCoolingDown {
+		t.Fatalf("second Connect = %q, %v; want %q", status, err, StatusCoolingDown)
+	}
+}
+
+// TestRememberDeviceJIDInterleavesWithConnected races Connect's write of the full device JID
+// against the status write of a Connected event. Whatever the order, the session must end up
+// connected with the full JID and its device info intact.
+func TestRememberDeviceJIDInterleavesWithConnected(t *testing.T) {
+	const bare, full = "628999999999@s.whatsapp.net", "628999999999:7@s.whatsapp.net"
+	device := types.NewADJID("628999999999", 0, 7)
+
+	for i := 0; i < 50; i++ {
+		session := testSession("s1")
+		session.Status = model.SessionStatusDisconnected // as Connect read it, before Connected arrived
+		session.PhoneNumber = bare
+		session.DeviceInfo = &model.DeviceInfo{Platform: "android", PushName: "Shop"}
+		h := newTestHarness(t, session)
+		if i%2 == 1 {
+			h.addClient("s1", device) // Connected then reads the JID from the client instead of the row
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			h.cm.rememberDeviceJID("s1", bare, device)
+		}()
+		go func() {
+			defer wg.Done()
+			h.cm.handleEvent("s1", &events.Connected{})
+		}()
+		wg.Wait()
+
+		got := h.sessions.session("s1")
+		if got.Status != model.SessionStatusConnected || got.PhoneNumber != full {
+			t.Fatalf("run %d: session is %s with %q, want connected with %q", i, got.Status, got.PhoneNumber, full)
+		}
+		if got.DeviceInfo == nil || got.DeviceInfo.Platform != "android" {
+			t.Fatalf("run %d: device info lost: %+v", i, got.DeviceInfo)
+		}
+	}
+}
+
+// TestStartClientConnectsPairedDeviceDirectly checks that a paired device dials straight away
+// and never enters the QR flow, even when the connect fails.
+func TestStartClientConnectsPairedDeviceDirectly(t *testing.T) {
+	h := newTestHarness(t, testSession("s1"))
+
+	paired := h.addClient("s1", testOwnJID)
+	pairedTransport := &offlineTransport{}
+	paired.SetWebsocketHTTPClient(&http.Client{Transport: pairedTransport})
+	h.cm.mu.Lock()
+	status, err := h.cm.startClient("s1", paired)
+	h.cm.mu.Unlock()
+	if err == nil || status != "" || pairedTransport.dials.Load() == 0 {
+		t.Fatalf("paired startClient = %q, %v after %d dials; want a failed direct connect", status, err, pairedTransport.dials.Load())
+	}
+
+	h.sessions.mu.Lock()
+	defer h.sessions.mu.Unlock()
+	for _, update := range h.sessions.statusUpdates {
+		if update.Status == model.SessionStatusQR {
+			t.Errorf("paired device reported a QR status: %+v", h.sessions.statusUpdates)
+		}
+	}
+}
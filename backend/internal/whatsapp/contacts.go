@@ -0,0 +1,130 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"wago-backend/internal/model"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+const (
+	profilePictureTTL       = time.Hour
+	profilePictureFailedTTL = 10 * time.Minute
+
+	// maxPictureLookups bounds uncached profile picture queries per directory request;
+	// the rest fill in on later requests as the cache warms.
+	maxPictureLookups = 50
+
+	// Up to pictureLookupWorkers queries run at once, and all of them together get
+	// pictureLookupBudget, so a directory request never waits longer than that for pictures.
+	pictureLookupWorkers = 8
+	pictureLookupBudget  = 5 * time.Second
+)
+
+// contactDisplayName picks the most descriptive name WhatsApp knows for a contact.
+func contactDisplayName(info types.ContactInfo) string {
+	for _, name := range []string{info.FullName, info.PushName, info.BusinessName, info.FirstName} {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// EnrichContacts fills in Name from the session's contact store (which also records push names
+// seen while messaging) and, with withPictures, ProfilePictureURL. Lookups that fail leave the
+// fields empty; contacts of a session without a loaded client are left as they are.
+func (cm *ClientManager) EnrichContacts(sessionID string, contacts []model.Contact, withPictures bool) {
+	client := cm.GetClient(sessionID)
+	if client == nil || client.Store == nil || client.Store.Contacts == nil || len(contacts) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	all, err := client.Store.Contacts.GetAllContacts(ctx)
+	cancel()
+	if err != nil {
+		cm.sessionLog(sessionID).Warn("failed to load contact store", "error", err)
+	}
+
+	connected := client.IsConnected() && client.IsLoggedIn()
+	var uncached []int
+	for i := range contacts {
+		jid := types.NewJID(contacts[i].PhoneNumber, types.DefaultUserServer)
+		if info, ok := all[jid]; ok {
+			contacts[i].Name = contactDisplayName(info)
+		}
+		if !withPictures {
+			continue
+		}
+
+		if url, ok := cm.profilePictures.get(sessionJIDKey(sessionID, jid), cm.now()); ok {
+			contacts[i].ProfilePictureURL = url
+			continue
+		}
+		if connected && len(uncached) < maxPictureLookups {
+			uncached = append(uncached, i)
+		}
+	}
+	if len(uncached) == 0 {
+		return
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), pictureLookupBudget)
+	defer cancel()
+	cm.fetchProfilePictures(ctx, client, sessionID, contacts, uncached)
+}
+
+// fetchProfilePictures fills in ProfilePictureURL of contacts[i] for each i in indexes, running
+// up to pictureLookupWorkers queries at once. Contacts not looked up before ctx ends stay empty.
+func (cm *ClientManager) fetchProfilePictures(ctx context.Context, client *whatsmeow.Client, sessionID string, contacts []model.Contact, indexes []int) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(pictureLookupWorkers, len(indexes)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				jid := types.NewJID(contacts[i].PhoneNumber, types.DefaultUserServer)
+				contacts[i].ProfilePictureURL = cm.fetchProfilePicture(ctx, client, sessionID, jid)
+			}
+		}()
+	}
+feed:
+	for _, i := range indexes {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// fetchProfilePicture queries the contact's profile picture URL and caches the result.
+// Contacts without a picture, or who hide it, cache as "". A query cut short by ctx is not cached.
+func (cm *ClientManager) fetchProfilePicture(ctx context.Context, client *whatsmeow.Client, sessionID string, jid types.JID) string {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	key, now := sessionJIDKey(sessionID, jid), cm.now()
+	info, err := cm.pictureInfo(client, ctx, jid, &whatsmeow.GetProfilePictureParams{})
+	switch {
+	case err == nil && info != nil:
+		cm.profilePictures.set(key, info.URL, now, profilePictureTTL)
+		return info.URL
+	case err == nil, errors.Is(err, whatsmeow.ErrProfilePictureNotSet), errors.Is(err, whatsmeow.ErrProfilePictureUnauthorized):
+		cm.profilePictures.set(key, "", now, profilePictureTTL)
+	case ctx.Err() != nil:
+		cm.sessionLog(sessionID).Debug("profile picture lookup cut short", "jid", jid.String(), "error", err)
+	default:
+		cm.sessionLog(sessionID).Warn("failed to look up profile picture", "jid", jid.String(), "error", err)
+		cm.profilePictures.set(key, "", now, profilePictureFailedTTL)
+	}
+	return ""
+}
@@ -0,0 +1,128 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wago-backend/internal/model"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func testContacts(n int) ([]model.Contact, []int) {
+	contacts := make([]model.Contact, n)
+	indexes := make([]int, n)
+	for i := range contacts {
+		contacts[i].PhoneNumber = fmt.Sprintf("6281234%05d", i)
+		indexes[i] = i
+	}
+	return contacts, indexes
+}
+
+func TestFetchProfilePicturesRunsConcurrently(t *testing.T) {
+	h := newTestHarness(t)
+	client := h.addClient("s1", testOwnJID)
+	var running, peak atomic.Int32
+	h.cm.pictureInfo = func(_ *whatsmeow.Client, ctx context.Context, jid types.JID, _ *whatsmeow.GetProfilePictureParams) (*types.ProfilePictureInfo, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			if p := peak.Load(); n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		return &types.ProfilePictureInfo{URL: "https://pps.whatsapp.net/" + jid.User}, nil
+	}
+	contacts, indexes := testContacts(40)
+
+	start := time.Now()
+	h.cm.fetchProfilePictures(context.Background(), client, "s1", contacts, indexes)
+
+	// 40 lookups of 50ms take 2s one after another, 250ms eight at a time.
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("lookups took %v", elapsed)
+	}
+	if got := peak.Load(); got > pictureLookupWorkers {
+		t.Errorf("%d lookups ran at once, want at most %d", got, pictureLookupWorkers)
+	}
+	for _, c := range contacts {
+		want := "https://pps.whatsapp.net/" + c.PhoneNumber
+		if c.ProfilePictureURL != want {
+			t.Fatalf("contact %s has picture %q", c.PhoneNumber, c.ProfilePictureURL)
+		}
+		jid := types.NewJID(c.PhoneNumber, types.DefaultUserServer)
+		if url, ok := h.cm.profilePictures.get(sessionJIDKey("s1", jid), testNow); !ok || url != want {
+			t.Fatalf("contact %s cached as %q, %v", c.PhoneNumber, url, ok)
+		}
+	}
+}
+
+func TestFetchProfilePicturesStopsAtDeadline(t *testing.T) {
+	h := newTestHarness(t)
+	client := h.addClient("s1", testOwnJID)
+	h.cm.pictureInfo = func(_ *whatsmeow.Client, ctx context.Context, _ types.JID, _ *whatsmeow.GetProfilePictureParams) (*types.ProfilePictureInfo, error) {
+		<-ctx.Done() // WhatsApp never answers
+		return nil, ctx.Err()
+	}
+	contacts, indexes := testContacts(maxPictureLookups)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	h.cm.fetchProfilePictures(ctx, client, "s1", contacts, indexes)
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("lookups took %v past a 100ms budget", elapsed)
+	}
+	for _, c := range contacts {
+		jid := types.NewJID(c.PhoneNumber, types.DefaultUserServer)
+		if c.ProfilePictureURL != "" {
+			t.Errorf("contact %s has picture %q", c.PhoneNumber, c.ProfilePictureURL)
+		}
+		if _, ok := h.cm.profilePictures.get(sessionJIDKey("s1", jid), testNow); ok {
+			t.Errorf("unanswered lookup for %s was cached", c.PhoneNumber)
+		}
+	}
+}
+
+func TestTTLCache(t *testing.T) {
+	var c ttlCache
+	key := sessionJIDKey("s1", types.NewADJID("628111111111", 0, 3))
+	if key != sessionJIDKey("s1", types.NewJID("628111111111", types.DefaultUserServer)) {
+		t.Error("device part of the JID is part of the key")
+	}
+	if _, ok := c.get(key, testNow); ok {
+		t.Error("empty cache returned a value")
+	}
+	c.set(key, "Team", testNow, time.Minute)
+	if v, ok := c.get(key, testNow); !ok || v != "Team" {
+		t.Errorf("get = %q, %v", v, ok)
+	}
+	if _, ok := c.get(key, testNow.Add(time.Minute)); ok {
+		t.Error("entry served at its expiry time")
+	}
+	if _, ok := c.entries[key]; ok {
+		t.Error("expired entry kept after get")
+	}
+}
+
+func TestTTLCacheSweepsExpiredEntries(t *testing.T) {
+	var c ttlCache
+	for i := range 3 {
+		c.set(fmt.Sprintf("s1|%d", i), "", testNow, time.Minute)
+	}
+	// The first set swept an empty cache, so the next sweep is due an interval later.
+	later := testNow.Add(ttlSweepInterval / 2)
+	c.set("s1|fresh", "", later, time.Hour)
+	if len(c.entries) != 4 {
+		t.Fatalf("swept before the interval: %d entries", len(c.entries))
+	}
+	c.set("s1|new", "", testNow.Add(ttlSweepInterval), time.Hour)
+	if len(c.entries) != 2 {
+		t.Errorf("cache holds %d entries after a sweep, want the 2 unexpired ones", len(c.entries))
+	}
+}
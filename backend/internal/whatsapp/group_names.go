@@ -0,0 +1,43 @@
+package whatsapp
+
+import (
+	"context"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+const (
+	groupNameTTL       = 10 * time.Minute
+	groupNameFailedTTL = time.Minute // retry failed lookups sooner, but not on every message
+)
+
+// GroupName returns the subject of a group the session is in, or "" when it can't be looked up.
+// A cache miss queries WhatsApp, so it must not be called from the event dispatch goroutine.
+func (cm *ClientManager) GroupName(sessionID string, group types.JID) string {
+	key := sessionJIDKey(sessionID, group)
+	now := cm.now()
+	if name, ok := cm.groupNames.get(key, now); ok {
+		return name
+	}
+
+	client := cm.GetClient(sessionID)
+	if client == nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	info, err := cm.groupInfo(client, ctx, group)
+	if err != nil {
+		cm.sessionLog(sessionID).Warn("failed to look up group", "group", group.String(), "error", err)
+		cm.groupNames.set(key, "", now, groupNameFailedTTL)
+		return ""
+	}
+	cm.groupNames.set(key, info.Name, now, groupNameTTL)
+	return info.Name
+}
+
+// setGroupName records a subject change seen in a group info event.
+func (cm *ClientManager) setGroupName(sessionID string, group types.JID, name string) {
+	cm.groupNames.set(sessionJIDKey(sessionID, group), name, cm.now(), groupNameTTL)
+}
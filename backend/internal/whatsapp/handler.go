@@ -5,10 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
+	"wago-backend/internal/mention"
+	"wago-backend/internal/message"
 	"wago-backend/internal/model"
 	"wago-backend/internal/webhook"
 
+	"go.mau.fi/whatsmeow"
 	waProto "go.mau.fi/whatsmeow/binary/proto"
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
@@ -42,54 +46,244 @@ func collectContextInfos(msg *waProto.Message) []*waProto.ContextInfo {
 	if msg.GetLiveLocationMessage() != nil {
 		contexts = append(contexts, msg.GetLiveLocationMessage().GetContextInfo())
 	}
+	if msg.GetButtonsResponseMessage() != nil {
+		contexts = append(contexts, msg.GetButtonsResponseMessage().GetContextInfo())
+	}
+	if msg.GetTemplateButtonReplyMessage() != nil {
+		contexts = append(contexts, msg.GetTemplateButtonReplyMessage().GetContextInfo())
+	}
+	if msg.GetListResponseMessage() != nil {
+		contexts = append(contexts, msg.GetListResponseMessage().GetContextInfo())
+	}
 	return contexts
 }
 
-// isMentioned checks both explicit mention lists and raw text for any of our JIDs (regular or LID).
-func isMentioned(msg *waProto.Message, rawText string, targets []types.JID) bool {
-	var searchTokens []string
-	for _, jid := range targets {
-		if jid.User == "" && jid.Server == "" {
+// quotedMessage returns the ID and text (or caption) of the message msg replies to, if any.
+func quotedMessage(msg *waProto.Message) (id, text string) {
+	for _, ctx := range collectContextInfos(msg) {
+		if ctx.GetStanzaID() == "" {
 			continue
 		}
-		// Base user
-		searchTokens = append(searchTokens, jid.User)
-		// Full JIDs
-		searchTokens = append(searchTokens, jid.String())
-		searchTokens = append(searchTokens, jid.ToNonAD().String())
-
-		// Also include LID server form to catch mentions that use @lid even if our main JID is s.whatsapp.net
-		if jid.Server != types.HiddenUserServer && jid.User != "" {
-			lidJID := types.NewJID(jid.User, types.HiddenUserServer)
-			searchTokens = append(searchTokens, lidJID.User, lidJID.String())
+		quoted := ctx.GetQuotedMessage()
+		switch {
+		case quoted.GetConversation() != "":
+			text = quoted.GetConversation()
+		case quoted.GetExtendedTextMessage() != nil:
+			text = quoted.GetExtendedTextMessage().GetText()
+		case quoted.GetImageMessage() != nil:
+			text = quoted.GetImageMessage().GetCaption()
+		case quoted.GetVideoMessage() != nil:
+			text = quoted.GetVideoMessage().GetCaption()
+		case quoted.GetDocumentMessage() != nil:
+			text = quoted.GetDocumentMessage().GetCaption()
 		}
+		return ctx.GetStanzaID(), text
 	}
+	return "", ""
+}
 
-	// Check explicit mention lists in context infos.
-	for _, ctx := range collectContextInfos(msg) {
-		if ctx == nil {
-			continue
-		}
-		for _, mentioned := range ctx.GetMentionedJID() {
-			for _, t := range searchTokens {
-				if strings.Contains(mentioned, t) {
-					return true
-				}
-			}
+// logIncoming stores an incoming message. payload is passed by value so later edits
+// (e.g. trigger prefix stripping) don't race with the write. It looks up the group name,
+// so it runs on its own goroutine.
+func (cm *ClientManager) logIncoming(sessionID string, info types.MessageInfo, payload webhook.WebhookPayload) {
+	msgLog := &model.MessageLog{
+		SessionID:   sessionID,
+		MessageID:   info.ID,
+		Direction:   "incoming",
+		FromNumber:  payload.From,
+		ToNumber:    "", // We don't have our own number easily accessible here without querying
+		MessageType: payload.MessageType,
+		Content:     payload.Message,
+		IsGroup:     payload.IsGroup,
+		Timestamp:   payload.Timestamp,
+
+		QuotedMessageID: payload.QuotedMessageID,
+	}
+	if payload.IsGroup {
+		msgLog.GroupID = info.Chat.User
+		msgLog.GroupName = cm.GroupName(sessionID, info.Chat)
+	}
+	if err := cm.AnalyticsRepo.LogMessage(msgLog); err != nil {
+		cm.sessionLog(sessionID).Error("failed to log incoming message", "message_id", info.ID, "error", err)
+	}
+}
+
+// replyInterval spaces out consecutive replies from one webhook response so they arrive in order.
+const replyInterval = 700 * time.Millisecond
+
+// replyTarget picks where the bot's reply goes: the originating chat, or the sender's DM
+// when the session answers group messages privately. The bool reports whether it's a group chat.
+func replyTarget(info types.MessageInfo, session *model.Session) (types.JID, bool) {
+	if info.IsGroup && session.ReplyPrivatelyInGroups {
+		return info.Sender.ToNonAD(), false
+	}
+	return info.Chat, info.IsGroup
+}
+
+// sendBusyReply sends the busy acknowledgement once grace elapses, unless done is closed first.
+func (cm *ClientManager) sendBusyReply(client *whatsmeow.Client, sessionID string, chat types.JID, text string, grace time.Duration, done <-chan struct{}) {
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return
+	case <-timer.C:
+	}
+
+	if _, err := cm.send(context.Background(), sessionID, client, chat, &waProto.Message{
+		Conversation: proto.String(text),
+	}); err != nil {
+		cm.sessionLog(sessionID).Error("failed to send busy reply", "to", chat.String(), "error", err)
+		return
+	}
+	cm.sessionLog(sessionID).Info("busy reply sent", "to", chat.String())
+}
+
+// buildPayload converts an incoming message into the webhook payload. It does no I/O,
+// so it can be exercised with synthetic events; ok is false for messages with nothing to forward.
+func buildPayload(sessionID string, v *events.Message) (payload webhook.WebhookPayload, ok bool) {
+	// Construct Payload
+	payload = webhook.WebhookPayload{
+		SessionID:    sessionID,
+		From:         v.Info.Sender.User, // Phone number
+		To:           "",                 // v.Info.Receiver is not available in MessageInfo. It's usually the connected user.
+		Message:      v.Message.GetConversation(),
+		Event:        webhook.EventMessage,
+		Timestamp:    v.Info.Timestamp,
+		IsGroup:      v.Info.IsGroup,
+		IsFromMe:     v.Info.IsFromMe,
+		IsNewsletter: v.Info.Chat.Server == types.NewsletterServer,
+		PushName:     v.Info.PushName,
+		MessageType:  "text", // Simplify for now
+	}
+
+	// Handle extended text message (if conversation is empty)
+	if payload.Message == "" {
+		payload.Message = v.Message.GetExtendedTextMessage().GetText()
+	}
+
+	// Handle image message
+	if imgMsg := v.Message.GetImageMessage(); imgMsg != nil {
+		payload.MessageType = "image"
+		if payload.Message == "" {
+			payload.Message = imgMsg.GetCaption()
 		}
 	}
 
-	// Fallback: check plain text for @<number>
-	text := strings.ToLower(rawText)
-	for _, t := range searchTokens {
-		if strings.Contains(text, "@"+strings.ToLower(t)) {
-			return true
+	// Other non-text types are forwarded with their caption, or a bracketed placeholder
+	// describing them when there is none; only images carry media.
+	switch msg := v.Message; {
+	case msg.GetVideoMessage() != nil:
+		payload.MessageType = "video"
+		payload.Message = placeholder(msg.GetVideoMessage().GetCaption(), "[video]")
+	case msg.GetAudioMessage() != nil:
+		payload.MessageType = "audio"
+		if msg.GetAudioMessage().GetPTT() {
+			payload.Message = "[voice note]"
+		} else {
+			payload.Message = "[audio]"
 		}
+	case msg.GetDocumentMessage() != nil:
+		doc := msg.GetDocumentMessage()
+		payload.MessageType = "document"
+		payload.Message = placeholder(doc.GetCaption(), labelled("document", doc.GetFileName()))
+	case msg.GetStickerMessage() != nil:
+		payload.MessageType = "sticker"
+		payload.Message = "[sticker]"
+	case msg.GetLocationMessage() != nil:
+		loc := msg.GetLocationMessage()
+		payload.MessageType = "location"
+		payload.Message = labelled("location", loc.GetName())
+		payload.Location = &webhook.Location{
+			Latitude:  loc.GetDegreesLatitude(),
+			Longitude: loc.GetDegreesLongitude(),
+			Name:      loc.GetName(),
+			Address:   loc.GetAddress(),
+		}
+	case msg.GetLiveLocationMessage() != nil:
+		loc := msg.GetLiveLocationMessage()
+		payload.MessageType = "location"
+		payload.Message = placeholder(loc.GetCaption(), "[live location]")
+		payload.Location = &webhook.Location{
+			Latitude:  loc.GetDegreesLatitude(),
+			Longitude: loc.GetDegreesLongitude(),
+		}
+	case msg.GetContactMessage() != nil:
+		payload.MessageType = "contact"
+		payload.Message = labelled("contact", msg.GetContactMessage().GetDisplayName())
+	case msg.GetContactsArrayMessage() != nil:
+		payload.MessageType = "contact"
+		payload.Message = fmt.Sprintf("[%d contacts]", len(msg.GetContactsArrayMessage().GetContacts()))
+	case msg.GetReactionMessage() != nil:
+		payload.MessageType = "reaction"
+		payload.Message = msg.GetReactionMessage().GetText()
+	case msg.GetPollCreationMessage() != nil:
+		payload.MessageType = "poll"
+		payload.Message = msg.GetPollCreationMessage().GetName()
+	case msg.GetPollCreationMessageV3() != nil:
+		payload.MessageType = "poll"
+		payload.Message = msg.GetPollCreationMessageV3().GetName()
+	}
+
+	// Interactive responses: forward the selected button / list row ID.
+	if btn := v.Message.GetButtonsResponseMessage(); btn != nil {
+		payload.MessageType = "button_response"
+		payload.SelectedID = btn.GetSelectedButtonID()
+		payload.Message = btn.GetSelectedDisplayText()
+	} else if tpl := v.Message.GetTemplateButtonReplyMessage(); tpl != nil {
+		payload.MessageType = "button_response"
+		payload.SelectedID = tpl.GetSelectedID()
+		payload.Message = tpl.GetSelectedDisplayText()
+	} else if list := v.Message.GetListResponseMessage(); list != nil {
+		payload.MessageType = "list_response"
+		payload.SelectedID = list.GetSingleSelectReply().GetSelectedRowID()
+		payload.Message = list.GetTitle()
+	}
+	if payload.Message == "" && payload.SelectedID != "" {
+		payload.Message = payload.SelectedID
+	}
+
+	payload.QuotedMessageID, payload.QuotedMessage = quotedMessage(v.Message)
+
+	// Filter out empty messages (e.g. status updates, protocol messages)
+	return payload, hasForwardableContent(payload)
+}
+
+// placeholder returns text, or fallback when text is empty.
+func placeholder(text, fallback string) string {
+	if text != "" {
+		return text
 	}
-	return false
+	return fallback
 }
 
+// labelled describes a message without text, e.g. "[document: invoice.pdf]" or "[document]".
+func labelled(kind, label string) string {
+	if label == "" {
+		return "[" + kind + "]"
+	}
+	return fmt.Sprintf("[%s: %s]", kind, label)
+}
+
+// emptyTextMessageTypes are the message types forwarded even when they carry no text.
+var emptyTextMessageTypes = map[string]bool{
+	"image": true, "video": true, "audio": true, "document": true, "sticker": true,
+	"location": true, "contact": true, "reaction": true, "poll": true,
+}
+
+// hasForwardableContent reports whether a payload is worth sending to the webhook:
+// it has text, or is of a type that is meaningful without any.
+func hasForwardableContent(payload webhook.WebhookPayload) bool {
+	return payload.Message != "" || emptyTextMessageTypes[payload.MessageType]
+}
+
+// handleEvent may run concurrently for the same session. Everything it shares across
+// events lives on ClientManager behind its own lock (clients, QR codes, triggers,
+// activity, chat queue); per-message state such as the session row and payload is
+// loaded fresh for each event and only copied into the background jobs.
 func (cm *ClientManager) handleEvent(sessionID string, evt interface{}) {
+	cm.touchEvent(sessionID)
 	switch v := evt.(type) {
 	case *events.PairSuccess:
 		// Update DB
@@ -97,18 +291,20 @@ func (cm *ClientManager) handleEvent(sessionID string, evt interface{}) {
 		// Save FULL JID string (User@Server:DeviceID) to ensure we get the correct device later
 		phoneNumber := jid.String()
 		deviceInfo := &model.DeviceInfo{
-			Platform:    v.Platform,
-			DeviceModel: v.BusinessName, // Sometimes business name is here
+			Platform:     v.Platform,
+			DeviceModel:  v.BusinessName, // Sometimes business name is here
+			BusinessName: v.BusinessName,
 		}
 
-		fmt.Printf("PairSuccess: Saving session %s with JID %s\n", sessionID, phoneNumber)
+		cm.sessionLog(sessionID).Info("paired, saving session", "jid", phoneNumber)
+		cm.clearQRCode(sessionID)
 
 		err := cm.SessionRepo.UpdateSessionStatus(sessionID, model.SessionStatusConnected, &phoneNumber, deviceInfo)
 		if err != nil {
-			fmt.Printf("Failed to update session status: %v\n", err)
+			cm.sessionLog(sessionID).Error("failed to update session status after pairing", "error", err)
 		} else {
 			if updated, fetchErr := cm.SessionRepo.GetSessionByID(sessionID); fetchErr == nil && updated != nil {
-				fmt.Printf("PairSuccess: session %s saved with phone_number=%s status=%s\n", sessionID, updated.PhoneNumber, updated.Status)
+				cm.sessionLog(sessionID).Debug("paired session saved", "phone_number", updated.PhoneNumber, "status", updated.Status)
 			}
 		}
 
@@ -120,15 +316,22 @@ func (cm *ClientManager) handleEvent(sessionID string, evt interface{}) {
 		})
 
 	case *events.Connected:
-		// Ensure DB reflects connected status (covers reconnects where PairSuccess is not fired)
+		cm.cancelReconnect(sessionID)
+		cm.clearQRCode(sessionID)
+		cm.recordConnection(sessionID, true)
+
+		// Ensure DB reflects connected status (covers reconnects where PairSuccess is not fired).
+		// Only a JID read from the in-memory client store is written; nil keeps the stored phone
+		// number and device info, so a concurrent rememberDeviceJID is never overwritten.
 		var phoneNumber string
-		// Try to get the JID from the in-memory client store
+		var phoneArg *string
 		client := cm.GetClient(sessionID)
 		if client != nil && client.Store != nil && client.Store.ID != nil {
 			phoneNumber = client.Store.ID.String()
+			phoneArg = &phoneNumber
 		}
 
-		// Fallback to existing DB value if we couldn't read from client
+		// Fallback to existing DB value for the notification if we couldn't read from client
 		if phoneNumber == "" {
 			session, err := cm.SessionRepo.GetSessionByID(sessionID)
 			if err == nil && session != nil {
@@ -136,12 +339,11 @@ func (cm *ClientManager) handleEvent(sessionID string, evt interface{}) {
 			}
 		}
 
-		// Persist connected status + phone (if available)
-		if err := cm.SessionRepo.UpdateSessionStatus(sessionID, model.SessionStatusConnected, &phoneNumber, nil); err != nil {
-			fmt.Printf("Failed to update session status on reconnect: %v\n", err)
+		if err := cm.SessionRepo.UpdateSessionStatus(sessionID, model.SessionStatusConnected, phoneArg, nil); err != nil {
+			cm.sessionLog(sessionID).Error("failed to update session status on connect", "error", err)
 		} else {
 			if updated, fetchErr := cm.SessionRepo.GetSessionByID(sessionID); fetchErr == nil && updated != nil {
-				fmt.Printf("Connected: session %s saved with phone_number=%s status=%s\n", sessionID, updated.PhoneNumber, updated.Status)
+				cm.sessionLog(sessionID).Debug("connected session saved", "phone_number", updated.PhoneNumber, "status", updated.Status)
 			}
 		}
 
@@ -151,7 +353,29 @@ func (cm *ClientManager) handleEvent(sessionID string, evt interface{}) {
 			"phone_number": phoneNumber,
 		})
 
+	case *events.HistorySync:
+		session, err := cm.SessionRepo.GetSessionByID(sessionID)
+		if err != nil || !session.IngestHistory {
+			return
+		}
+		go cm.ingestHistory(sessionID, v.Data)
+
+	case *events.GroupInfo:
+		if v.Name != nil {
+			cm.setGroupName(sessionID, v.JID, v.Name.Name)
+		}
+
+	case *events.Receipt:
+		go cm.handleReceipt(sessionID, v)
+
+	case *events.Disconnected:
+		cm.recordConnection(sessionID, false)
+		cm.scheduleReconnect(sessionID)
+
 	case *events.LoggedOut:
+		cm.cancelReconnect(sessionID)
+		cm.clearQRCode(sessionID)
+		cm.recordConnection(sessionID, false)
 		empty := ""
 		cm.SessionRepo.UpdateSessionStatus(sessionID, model.SessionStatusDisconnected, &empty, nil)
 		cm.WSHub.SendToSession(sessionID, "status_update", map[string]interface{}{
@@ -164,51 +388,54 @@ func (cm *ClientManager) handleEvent(sessionID string, evt interface{}) {
 		cm.mu.Unlock()
 
 	case *events.Message:
+		// Some protocol events (revokes, ephemeral settings, app state keys) carry no user content.
+		log := cm.sessionLog(sessionID).With("message_id", v.Info.ID)
+		if v.Message == nil || v.Message.GetProtocolMessage() != nil {
+			log.Debug("skipping message: no user content")
+			return
+		}
+		cm.touchActivity(sessionID)
+
 		// Handle incoming message
-		fmt.Printf("Received message in session %s: %s\n", sessionID, v.Message.GetConversation())
+		log.Info("received message", "from", v.Info.Sender.User, "chat", v.Info.Chat.String())
 
 		// Get Session to find Webhook URL
 		session, err := cm.SessionRepo.GetSessionByID(sessionID)
 		if err != nil {
-			fmt.Printf("Error getting session for webhook: %v\n", err)
+			log.Error("failed to load session for webhook", "error", err)
 			return
 		}
 
-		// Construct Payload
-		payload := webhook.WebhookPayload{
-			SessionID:   sessionID,
-			From:        v.Info.Sender.User, // Phone number
-			To:          "",                 // v.Info.Receiver is not available in MessageInfo. It's usually the connected user.
-			Message:     v.Message.GetConversation(),
-			Timestamp:   v.Info.Timestamp,
-			IsGroup:     v.Info.IsGroup,
-			PushName:    v.Info.PushName,
-			MessageType: "text", // Simplify for now
+		payload, ok := buildPayload(sessionID, v)
+		if !ok {
+			return
 		}
 
-		// Handle extended text message (if conversation is empty)
-		if payload.Message == "" {
-			payload.Message = v.Message.GetExtendedTextMessage().GetText()
+		// Messages the account sent itself (e.g. from the owner's phone) are skipped by default to avoid reply loops.
+		if v.Info.IsFromMe && !session.ProcessOwnMessages {
+			return
 		}
 
-		// Handle image message
-		if imgMsg := v.Message.GetImageMessage(); imgMsg != nil {
-			payload.MessageType = "image"
-			if payload.Message == "" {
-				payload.Message = imgMsg.GetCaption()
-			}
-		}
+		// Every incoming message is logged; the gates below only decide whether it is answered.
+		go cm.logIncoming(sessionID, v.Info, payload)
 
-		// Filter out empty messages (e.g. status updates, protocol messages)
-		if payload.Message == "" && payload.MessageType != "image" {
+		if session.IsChatMuted(v.Info.Chat.ToNonAD().String()) {
+			log.Debug("ignoring message: chat is muted", "chat", v.Info.Chat.String())
 			return
 		}
 
-		// Group Message Handling: Only respond if mentioned
+		// Group Message Handling: a per-group setting wins over the session default, which
+		// answers only when mentioned. Gating runs before any download or webhook work is scheduled.
 		isMention := false
 		if v.Info.IsGroup {
-			if !session.IsGroupResponseEnabled {
-				fmt.Printf("Ignoring group message from %s: group response disabled.\n", v.Info.Sender.User)
+			enabled, mentionOnly := session.IsGroupResponseEnabled, true
+			if setting, err := cm.SessionRepo.GetGroupSetting(sessionID, v.Info.Chat.ToNonAD().String()); err != nil {
+				log.Error("failed to load group setting", "group", v.Info.Chat.String(), "error", err)
+			} else if setting != nil {
+				enabled, mentionOnly = setting.Enabled, setting.MentionOnly
+			}
+			if !enabled {
+				log.Debug("ignoring group message: group response disabled", "group", v.Info.Chat.String())
 				return
 			}
 
@@ -219,43 +446,56 @@ func (cm *ClientManager) handleEvent(sessionID string, evt interface{}) {
 					targets = append(targets, client.Store.LID)
 				}
 
-				if !isMentioned(v.Message, payload.Message, targets) {
-					fmt.Printf("Ignoring group message from %s: not mentioned. My JIDs: %v\n", v.Info.Sender.User, targets)
+				isMention = mention.Mentioned(collectContextInfos(v.Message), payload.Message, targets)
+				if mentionOnly && !isMention {
+					log.Debug("ignoring group message: not mentioned", "group", v.Info.Chat.String(), "own_jids", targets)
 					return
 				}
-				isMention = true
 			} else {
-				fmt.Println("[GroupMsg] Client or Store ID is nil")
+				log.Warn("cannot check group mention: client or store ID is nil")
 			}
 		}
 
-		// Log Message to DB
-		go func() {
-			msgLog := &model.MessageLog{
-				SessionID:   sessionID,
-				Direction:   "incoming",
-				FromNumber:  payload.From,
-				ToNumber:    "", // We don't have our own number easily accessible here without querying
-				MessageType: payload.MessageType,
-				Content:     payload.Message,
-				IsGroup:     payload.IsGroup,
-				Timestamp:   payload.Timestamp,
+		// Trigger pattern: only forward messages starting with the pattern, minus the matched prefix.
+		if session.TriggerPattern != "" {
+			re, err := cm.triggerRegexp(sessionID, session.TriggerPattern)
+			if err != nil {
+				log.Error("invalid trigger pattern", "error", err)
+				return
 			}
-			if payload.IsGroup {
-				msgLog.GroupID = v.Info.Chat.User
-				msgLog.GroupName = v.Info.PushName // Not accurate for group name, but PushName is sender name
+			loc := re.FindStringIndex(payload.Message)
+			if loc == nil {
+				log.Debug("ignoring message: trigger pattern not matched")
+				return
 			}
-			if err := cm.AnalyticsRepo.LogMessage(msgLog); err != nil {
-				fmt.Printf("Failed to log message: %v\n", err)
+			payload.Message = strings.TrimSpace(payload.Message[loc[1]:])
+		}
+
+		// The message will be processed: show blue ticks now rather than after the webhook answers.
+		if session.AutoMarkRead && !session.DryRun && !v.Info.IsFromMe && !payload.IsNewsletter {
+			if client := cm.GetClient(sessionID); client != nil {
+				if err := client.MarkRead(context.Background(), []types.MessageID{v.Info.ID}, cm.now(), v.Info.Chat, v.Info.Sender); err != nil {
+					log.Warn("failed to mark message as read", "error", err)
+				}
+			}
+		}
+
+		// Send Webhook and Handle Response.
+		// Jobs are serialized per chat so replies keep the order of the incoming messages.
+		jobPayload, jobSession := payload, *session
+		queued := cm.chatQueue.Submit(chatQueueKey(sessionID, v.Info.Chat.String()), func() {
+			payload, session := jobPayload, &jobSession
+
+			// The group name may need a WhatsApp query, so it is resolved here rather than on the event goroutine.
+			var groupName string
+			if v.Info.IsGroup {
+				groupName = cm.GroupName(sessionID, v.Info.Chat)
+				payload.GroupInfo = &webhook.GroupInfo{ID: v.Info.Chat.ToNonAD().String(), Name: groupName}
 			}
-		}()
 
-		// Send Webhook and Handle Response
-		// Send Webhook and Handle Response
-		go func(payload webhook.WebhookPayload) {
 			// Check for image and download here
 			if imgMsg := v.Message.GetImageMessage(); imgMsg != nil {
-				fmt.Printf("[Handler] Found image message. Attempting to download...\n")
+				log.Debug("downloading image")
 				client := cm.GetClient(sessionID)
 				if client != nil {
 					// Use timeout for download
@@ -264,42 +504,71 @@ func (cm *ClientManager) handleEvent(sessionID string, evt interface{}) {
 
 					data, err := client.Download(ctx, imgMsg)
 					if err != nil {
-						fmt.Printf("[Handler] Failed to download image: %v\n", err)
+						log.Error("failed to download image", "error", err)
 						payload.Message += fmt.Sprintf(" [Image Download Failed: %v]", err)
 					} else {
 						payload.MediaData = data
 						payload.MediaMimeType = imgMsg.GetMimetype()
-						// Determine extension from mimetype
-						ext := "jpg" // default
-						if strings.Contains(payload.MediaMimeType, "png") {
-							ext = "png"
-						} else if strings.Contains(payload.MediaMimeType, "jpeg") {
-							ext = "jpg"
-						} else if strings.Contains(payload.MediaMimeType, "webp") {
-							ext = "webp"
-						}
-						payload.MediaName = fmt.Sprintf("image_%d.%s", v.Info.Timestamp.Unix(), ext)
-						fmt.Printf("[Handler] Downloaded image successfully. Size: %d bytes, Mime: %s\n", len(data), payload.MediaMimeType)
+						payload.MediaName = mediaFileName("image", payload.MediaMimeType, v.Info.Timestamp)
+						go cm.storeMedia(sessionID, v.Info.ID, payload.MediaMimeType, data)
+						log.Debug("downloaded image", "bytes", len(data), "mime_type", payload.MediaMimeType)
 					}
 				} else {
-					fmt.Printf("[Handler] Client is nil, cannot download image.\n")
+					log.Error("client is nil, cannot download image")
 					payload.Message += " [Image Download Failed: Client not found]"
 				}
 			}
 
-			start := time.Now()
-			// Send Typing Indicator
+			start := cm.now()
+			replyJID, replyInGroup := replyTarget(v.Info, session)
+
+			// Channel posts are forwarded for information only: there is no one to type to or answer.
 			client := cm.GetClient(sessionID)
-			if client != nil {
-				// We need the JID of the sender (chat)
-				chatJID := v.Info.Chat
-				client.SendChatPresence(context.Background(), chatJID, types.ChatPresenceComposing, types.ChatPresenceMediaText)
+			answerable := client != nil && !session.DryRun && !payload.IsNewsletter
+
+			// Send Typing Indicator
+			if answerable {
+				client.SendChatPresence(context.Background(), replyJID, types.ChatPresenceComposing, types.ChatPresenceMediaText)
+			}
+
+			// The busy reply and footer are session-configured texts and may use these placeholders.
+			replyVars := autoReplyVars(v.Info, groupName, cm.now())
+
+			// Acknowledge slow webhooks with the session's busy reply; skipped if the webhook answers within the grace period.
+			webhookDone := make(chan struct{})
+			if answerable && session.BusyReplyText != "" {
+				grace := time.Duration(session.BusyReplyGraceMs) * time.Millisecond
+				go cm.sendBusyReply(client, sessionID, replyJID, message.Render(session.BusyReplyText, replyVars), grace, webhookDone)
+			}
+
+			// Streaming sessions send each reply as it arrives; the first one also stops the busy reply.
+			// The footer is not applied to streamed replies since there is no known last message.
+			endpoint := webhook.EndpointForSession(session)
+			stopBusy := sync.OnceFunc(func() { close(webhookDone) })
+			streamOK := true
+
+			// Only the first reply quotes the incoming message; follow-ups read as a continuation.
+			var quote *waProto.ContextInfo
+			if session.QuoteReplies {
+				quote = quoteContext(v.Info, v.Message, replyJID)
+			}
+
+			if session.WebhookStream && answerable {
+				endpoint.OnReply = func(reply webhook.Reply) {
+					stopBusy()
+					if streamOK {
+						streamOK = cm.sendReply(log, client, sessionID, session, replyJID, replyInGroup, groupName, reply, quote)
+						quote = nil
+					}
+				}
 			}
 
-			response, err := cm.WebhookService.SendWebhook(session.WebhookURL, payload)
+			result, err := cm.WebhookService.SendWebhook(endpoint, payload)
+			replies := result.Replies
+			stopBusy()
 
 			// Calculate response time
-			duration := time.Since(start).Milliseconds()
+			duration := cm.now().Sub(start).Milliseconds()
 
 			// Log Analytics
 			go func() {
@@ -313,72 +582,64 @@ func (cm *ClientManager) handleEvent(sessionID string, evt interface{}) {
 					WebhookSent:         true,
 					WebhookSuccess:      err == nil,
 					WebhookResponseTime: int(duration),
-					WebhookStatusCode:   200, // Simplify for now, WebhookService should return status
+					WebhookStatusCode:   result.StatusCode, // 0 when the webhook could not be reached
+					DryRun:              session.DryRun,
 				}
 				if err != nil {
 					analytics.ErrorMessage = err.Error()
-					analytics.WebhookStatusCode = 500
 				}
 				if logErr := cm.AnalyticsRepo.LogAnalytics(analytics); logErr != nil {
-					fmt.Printf("Failed to log analytics: %v\n", logErr)
+					log.Error("failed to log analytics", "error", logErr)
 				}
 			}()
 
 			// Stop Typing Indicator
-			if client != nil {
-				chatJID := v.Info.Chat
-				client.SendChatPresence(context.Background(), chatJID, types.ChatPresencePaused, types.ChatPresenceMediaText)
+			if answerable {
+				client.SendChatPresence(context.Background(), replyJID, types.ChatPresencePaused, types.ChatPresenceMediaText)
 			}
 
 			if err != nil {
-				fmt.Printf("Failed to send webhook: %v\n", err)
+				log.Error("failed to send webhook", "error", err)
+				return
+			}
+			if !streamOK {
+				return
+			}
+			if payload.IsNewsletter {
+				if len(replies) > 0 {
+					log.Info("ignoring webhook reply to newsletter message")
+				}
 				return
 			}
 
 			// Send Response if available
-			if response != "" {
-				fmt.Printf("[Handler] Got response from webhook: %s\n", response)
-				if client != nil {
-					chatJID := v.Info.Chat
-					fmt.Printf("[Handler] Sending message to %s\n", chatJID)
+			if len(replies) == 0 {
+				log.Debug("webhook response is empty, nothing to send")
+			}
+			for i, reply := range replies {
+				if i > 0 {
+					time.Sleep(replyInterval)
+				}
+				// The footer goes on the last message only, so multi-part replies aren't branded repeatedly.
+				if i == len(replies)-1 {
+					reply = withFooter(reply, message.Render(session.ReplyFooter, replyVars))
+				}
+				if !cm.sendReply(log, client, sessionID, session, replyJID, replyInGroup, groupName, reply, quote) {
+					return
+				}
+				quote = nil
+			}
 
-					// Send text message
-					resp, err := client.SendMessage(context.Background(), chatJID, &waProto.Message{
-						Conversation: proto.String(response),
-					})
-					if err != nil {
-						fmt.Printf("[Handler] Failed to send response: %v\n", err)
-					} else {
-						fmt.Printf("[Handler] Response sent successfully. ID: %s\n", resp.ID)
-
-						// Log Outgoing Message (AI Reply)
-						go func() {
-							msgLog := &model.MessageLog{
-								SessionID:   sessionID,
-								Direction:   "outgoing",
-								FromNumber:  "", // It's us
-								ToNumber:    chatJID.User,
-								MessageType: "text",
-								Content:     response,
-								IsGroup:     v.Info.IsGroup,
-								Timestamp:   time.Now(),
-							}
-							if v.Info.IsGroup {
-								msgLog.GroupID = chatJID.User
-								msgLog.GroupName = v.Info.PushName
-							}
-							if err := cm.AnalyticsRepo.LogMessage(msgLog); err != nil {
-								fmt.Printf("Failed to log outgoing message: %v\n", err)
-							}
-						}()
-					}
-				} else {
-					fmt.Println("[Handler] Client is nil, cannot send response")
+			// Only now is the message handled; leaving failures unread keeps them visibly pending.
+			if session.MarkReadOnSuccess && !session.DryRun && client != nil {
+				if err := client.MarkRead(context.Background(), []types.MessageID{v.Info.ID}, cm.now(), v.Info.Chat, v.Info.Sender); err != nil {
+					log.Warn("failed to mark message as read", "error", err)
 				}
-			} else {
-				fmt.Println("[Handler] Webhook response is empty, nothing to send.")
 			}
-		}(payload)
+		})
+		if !queued {
+			log.Warn("message queue full, dropping message", "from", payload.From)
+		}
 
 		// Notify WS (optional, for debugging)
 		msgBytes, _ := json.Marshal(v.Message)
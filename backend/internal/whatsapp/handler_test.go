@@ -0,0 +1,288 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"wago-backend/internal/model"
+	"wago-backend/internal/webhook"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHandleEventForwardsDirectMessage(t *testing.T) {
+	h := newTestHarness(t, testSession("s1"))
+	h.webhook.result = webhook.WebhookResult{StatusCode: 200}
+
+	h.cm.handleEvent("s1", messageEvent("m1", types.EmptyJID, textMessage("hello")))
+
+	waitFor(t, "webhook delivery", func() bool { return len(h.webhook.sent()) == 1 })
+	payload := h.webhook.sent()[0]
+	if payload.Event != webhook.EventMessage || payload.SessionID != "s1" {
+		t.Errorf("envelope = %q/%q, want message/s1", payload.Event, payload.SessionID)
+	}
+	if payload.From != testSender.User || payload.Message != "hello" || payload.MessageType != "text" || payload.PushName != "Ann" {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+	if payload.IsGroup || payload.GroupInfo != nil {
+		t.Errorf("direct message marked as group: %+v", payload)
+	}
+
+	waitFor(t, "analytics row", func() bool { return len(h.analytics.loggedAnalytics()) == 1 })
+	analytics := h.analytics.loggedAnalytics()[0]
+	if !analytics.WebhookSent || !analytics.WebhookSuccess || analytics.WebhookStatusCode != 200 || analytics.MessageID != "m1" {
+		t.Errorf("unexpected analytics %+v", analytics)
+	}
+	if analytics.IsMention {
+		t.Error("direct message counted as a mention")
+	}
+
+	waitFor(t, "message log", func() bool { return len(h.analytics.loggedMessages()) == 1 })
+	logged := h.analytics.loggedMessages()[0]
+	if logged.Direction != "incoming" || logged.Content != "hello" || logged.FromNumber != testSender.User {
+		t.Errorf("unexpected message log %+v", logged)
+	}
+}
+
+func TestHandleEventDetectsMessageType(t *testing.T) {
+	cases := []struct {
+		name     string
+		msg      *waE2E.Message
+		wantType string
+		wantText string
+	}{
+		{"extended text", &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{Text: proto.String("link https://x.test")}}, "text", "link https://x.test"},
+		{"video with caption", &waE2E.Message{VideoMessage: &waE2E.VideoMessage{Caption: proto.String("look")}}, "video", "look"},
+		{"voice note", &waE2E.Message{AudioMessage: &waE2E.AudioMessage{PTT: proto.Bool(true)}}, "audio", "[voice note]"},
+		{"document", &waE2E.Message{DocumentMessage: &waE2E.DocumentMessage{FileName: proto.String("invoice.pdf")}}, "document", "[document: invoice.pdf]"},
+		{"location", &waE2E.Message{LocationMessage: &waE2E.LocationMessage{DegreesLatitude: proto.Float64(-6.2), DegreesLongitude: proto.Float64(106.8)}}, "location", "[location]"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHarness(t, testSession("s1"))
+
+			h.cm.handleEvent("s1", messageEvent("m1", types.EmptyJID, tc.msg))
+
+			waitFor(t, "webhook delivery", func() bool { return len(h.webhook.sent()) == 1 })
+			payload := h.webhook.sent()[0]
+			if payload.MessageType != tc.wantType || payload.Message != tc.wantText {
+				t.Errorf("got %q %q, want %q %q", payload.MessageType, payload.Message, tc.wantType, tc.wantText)
+			}
+		})
+	}
+}
+
+func TestHandleEventGroupGating(t *testing.T) {
+	mentioned := &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+		Text:        proto.String("@628999999999 status?"),
+		ContextInfo: &waE2E.ContextInfo{MentionedJID: []string{testOwnJID.String()}},
+	}}
+
+	cases := []struct {
+		name        string
+		sessionOn   bool
+		setting     *model.GroupSetting
+		msg         *waE2E.Message
+		wantWebhook bool
+		wantMention bool
+	}{
+		{"group responses off", false, nil, mentioned, false, false},
+		{"mentioned", true, nil, mentioned, true, true},
+		{"not mentioned", true, nil, textMessage("just chatting"), false, false},
+		{"group disabled by setting", true, &model.GroupSetting{Enabled: false}, mentioned, false, false},
+		{"setting answers everything", false, &model.GroupSetting{Enabled: true, MentionOnly: false}, textMessage("just chatting"), true, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			session := testSession("s1")
+			session.IsGroupResponseEnabled = tc.sessionOn
+			h := newTestHarness(t, session)
+			h.addClient("s1", testOwnJID)
+			h.cm.setGroupName("s1", testGroup, "Team")
+			if tc.setting != nil {
+				setting := *tc.setting
+				setting.SessionID, setting.GroupJID = "s1", testGroup.String()
+				h.sessions.setGroupSetting(setting)
+			}
+
+			h.cm.handleEvent("s1", messageEvent("m1", testGroup, tc.msg))
+
+			// Every group message is logged, answered or not.
+			waitFor(t, "message log", func() bool { return len(h.analytics.loggedMessages()) == 1 })
+			if logged := h.analytics.loggedMessages()[0]; logged.GroupName != "Team" || !logged.IsGroup || logged.MessageID != "m1" {
+				t.Errorf("unexpected message log %+v", logged)
+			}
+
+			if !tc.wantWebhook {
+				settle()
+				if sent := h.webhook.sent(); len(sent) != 0 {
+					t.Fatalf("webhook called for gated message: %+v", sent)
+				}
+				return
+			}
+			waitFor(t, "analytics row", func() bool { return len(h.analytics.loggedAnalytics()) == 1 })
+			payload := h.webhook.sent()[0]
+			if payload.GroupInfo == nil || payload.GroupInfo.Name != "Team" || payload.GroupInfo.ID != testGroup.String() {
+				t.Errorf("unexpected group info %+v", payload.GroupInfo)
+			}
+			if got := h.analytics.loggedAnalytics()[0].IsMention; got != tc.wantMention {
+				t.Errorf("IsMention = %v, want %v", got, tc.wantMention)
+			}
+		})
+	}
+}
+
+func TestHandleEventSkipsOwnMessages(t *testing.T) {
+	h := newTestHarness(t, testSession("s1"))
+	evt := messageEvent("m1", types.EmptyJID, textMessage("sent from my phone"))
+	evt.Info.IsFromMe = true
+
+	h.cm.handleEvent("s1", evt)
+
+	settle()
+	if sent := h.webhook.sent(); len(sent) != 0 {
+		t.Fatalf("own message forwarded: %+v", sent)
+	}
+}
+
+// TestHandleEventInterleaved feeds messages from several chats, group renames and connection
+// events to handleEvent concurrently, as whatsmeow does. Run with -race: every job must see
+// its own message, and each chat's deliveries must keep their arrival order.
+func TestHandleEventInterleaved(t *testing.T) {
+	const chats, perChat = 4, 25
+	h := newTestHarness(t, testSession("s1"))
+	h.addClient("s1", testOwnJID)
+	h.sessions.setGroupSetting(model.GroupSetting{SessionID: "s1", GroupJID: testGroup.String(), Enabled: true})
+	h.cm.setGroupName("s1", testGroup, "Team")
+
+	senders := make([]types.JID, chats)
+	for c := range senders {
+		senders[c] = types.NewJID(fmt.Sprintf("62811111111%d", c), types.DefaultUserServer)
+	}
+
+	var wg sync.WaitGroup
+	for c, sender := range senders {
+		wg.Add(1)
+		go func(c int, sender types.JID) {
+			defer wg.Done()
+			for i := 0; i < perChat; i++ {
+				evt := messageEvent(fmt.Sprintf("m%d-%d", c, i), types.EmptyJID, textMessage(fmt.Sprintf("%s #%d", sender.User, i)))
+				evt.Info.Chat, evt.Info.Sender = sender, sender
+				h.cm.handleEvent("s1", evt)
+			}
+		}(c, sender)
+	}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < perChat; i++ {
+			h.cm.handleEvent("s1", messageEvent(fmt.Sprintf("g-%d", i), testGroup, textMessage(fmt.Sprintf("group #%d", i))))
+			h.cm.handleEvent("s1", &events.GroupInfo{JID: testGroup, Name: &types.GroupName{Name: fmt.Sprintf("Team %d", i)}})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < perChat; i++ {
+			h.cm.handleEvent("s1", &events.Connected{})
+		}
+	}()
+	wg.Wait()
+
+	total := (chats + 1) * perChat
+	waitFor(t, "all deliveries", func() bool { return len(h.webhook.sent()) == total })
+	waitFor(t, "all analytics rows", func() bool { return len(h.analytics.loggedAnalytics()) == total })
+
+	next := map[string]int{}
+	for _, payload := range h.webhook.sent() {
+		chat := payload.From
+		if payload.IsGroup {
+			chat = "group"
+			if payload.GroupInfo == nil || !strings.HasPrefix(payload.GroupInfo.Name, "Team") {
+				t.Errorf("group payload has group info %+v", payload.GroupInfo)
+			}
+		}
+		want := fmt.Sprintf("%s #%d", chat, next[chat])
+		if payload.Message != want {
+			t.Fatalf("chat %s delivered %q, want %q", chat, payload.Message, want)
+		}
+		next[chat]++
+	}
+	if got := h.sessions.session("s1").Status; got != model.SessionStatusConnected {
+		t.Errorf("session status = %s, want connected", got)
+	}
+}
+
+func TestHandleEventSkipsEventsWithoutContent(t *testing.T) {
+	cases := []struct {
+		name string
+		msg  *waE2E.Message
+	}{
+		{"nil message", nil},
+		{"protocol message", &waE2E.Message{ProtocolMessage: &waE2E.ProtocolMessage{Type: waE2E.ProtocolMessage_REVOKE.Enum()}}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHarness(t, testSession("s1"))
+
+			h.cm.handleEvent("s1", messageEvent("m1", types.EmptyJID, tc.msg))
+			h.cm.handleEvent("s1", messageEvent("m2", testGroup, tc.msg))
+
+			settle()
+			if sent := h.webhook.sent(); len(sent) != 0 {
+				t.Errorf("webhook called for an event without content: %+v", sent)
+			}
+			if logged := h.analytics.loggedMessages(); len(logged) != 0 {
+				t.Errorf("event without content logged: %+v", logged)
+			}
+			if _, active := h.cm.LastActivity("s1"); active {
+				t.Error("event without content counted as activity")
+			}
+		})
+	}
+}
+
+// TestHandleEventDoesNotWaitForGroupLookup holds the group info query open: handleEvent runs on
+// whatsmeow's dispatch goroutine and must return without it, while the queued job and the
+// message log still get the name once the query answers.
+func TestHandleEventDoesNotWaitForGroupLookup(t *testing.T) {
+	h := newTestHarness(t, testSession("s1"))
+	h.addClient("s1", testOwnJID)
+	h.sessions.setGroupSetting(model.GroupSetting{SessionID: "s1", GroupJID: testGroup.String(), Enabled: true})
+	release := make(chan struct{})
+	h.cm.groupInfo = func(_ *whatsmeow.Client, ctx context.Context, group types.JID) (*types.GroupInfo, error) {
+		<-release
+		return &types.GroupInfo{JID: group, GroupName: types.GroupName{Name: "Team"}}, nil
+	}
+
+	returned := make(chan struct{})
+	go func() {
+		h.cm.handleEvent("s1", messageEvent("m1", testGroup, textMessage("hello")))
+		close(returned)
+	}()
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		close(release)
+		t.Fatal("handleEvent blocked on the group lookup")
+	}
+	if sent := h.webhook.sent(); len(sent) != 0 {
+		t.Fatalf("webhook sent before the group name was known: %+v", sent)
+	}
+
+	close(release)
+	waitFor(t, "webhook delivery", func() bool { return len(h.webhook.sent()) == 1 })
+	if info := h.webhook.sent()[0].GroupInfo; info == nil || info.Name != "Team" {
+		t.Errorf("group info = %+v, want Team", info)
+	}
+	waitFor(t, "message log", func() bool { return len(h.analytics.loggedMessages()) == 1 })
+	if name := h.analytics.loggedMessages()[0].GroupName; name != "Team" {
+		t.Errorf("logged group name = %q, want Team", name)
+	}
+}
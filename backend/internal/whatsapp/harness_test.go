@@ -0,0 +1,312 @@
+package whatsapp
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+	"wago-backend/internal/config"
+	"wago-backend/internal/errs"
+	"wago-backend/internal/model"
+	"wago-backend/internal/webhook"
+	"wago-backend/internal/websocket"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/store"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// This file is the harness for driving handleEvent without WhatsApp or a database:
+// in-memory fakes for the session and analytics stores and the webhook sender, a
+// manager wired to them, and builders for synthetic events.
+
+type statusUpdate struct {
+	Status      model.SessionStatus
+	PhoneNumber *string
+}
+
+type fakeSessionStore struct {
+	mu            sync.Mutex
+	sessions      map[string]*model.Session
+	groupSettings map[string]*model.GroupSetting
+	statusUpdates []statusUpdate
+}
+
+func newFakeSessionStore(sessions ...*model.Session) *fakeSessionStore {
+	s := &fakeSessionStore{
+		sessions:      make(map[string]*model.Session),
+		groupSettings: make(map[string]*model.GroupSetting),
+	}
+	for _, session := range sessions {
+		s.sessions[session.ID] = session
+	}
+	return s
+}
+
+func (s *fakeSessionStore) GetSessionByID(id string) (*model.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, errs.ErrSessionNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (s *fakeSessionStore) GetSessionsWithPhoneNumber() ([]*model.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sessions []*model.Session
+	for _, session := range s.sessions {
+		if session.PhoneNumber != "" {
+			copied := *session
+			sessions = append(sessions, &copied)
+		}
+	}
+	return sessions, nil
+}
+
+func (s *fakeSessionStore) GetGroupSetting(sessionID, groupJID string) (*model.GroupSetting, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.groupSettings[sessionID+"|"+groupJID], nil
+}
+
+func (s *fakeSessionStore) setGroupSetting(setting model.GroupSetting) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.groupSettings[setting.SessionID+"|"+setting.GroupJID] = &setting
+}
+
+// UpdateSessionStatus mirrors the repository: phone_number and device_info are only written when given.
+func (s *fakeSessionStore) UpdateSessionStatus(id string, status model.SessionStatus, phoneNumber *string, deviceInfo *model.DeviceInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return errs.ErrSessionNotFound
+	}
+	session.Status = status
+	if phoneNumber != nil {
+		session.PhoneNumber = *phoneNumber
+	}
+	if deviceInfo != nil {
+		session.DeviceInfo = deviceInfo
+	}
+	s.statusUpdates = append(s.statusUpdates, statusUpdate{Status: status, PhoneNumber: phoneNumber})
+	return nil
+}
+
+func (s *fakeSessionStore) UpdatePhoneNumber(id, phoneNumber string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return errs.ErrSessionNotFound
+	}
+	session.PhoneNumber = phoneNumber
+	return nil
+}
+
+func (s *fakeSessionStore) UpdateDeviceInfo(id string, deviceInfo *model.DeviceInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return errs.ErrSessionNotFound
+	}
+	session.DeviceInfo = deviceInfo
+	return nil
+}
+
+func (s *fakeSessionStore) session(id string) model.Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.sessions[id]
+}
+
+type fakeAnalyticsStore struct {
+	mu        sync.Mutex
+	messages  []model.MessageLog
+	analytics []model.Analytics
+}
+
+func (a *fakeAnalyticsStore) LogMessage(log *model.MessageLog) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.messages = append(a.messages, *log)
+	return nil
+}
+
+func (a *fakeAnalyticsStore) LogAnalytics(analytics *model.Analytics) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.analytics = append(a.analytics, *analytics)
+	return nil
+}
+
+func (a *fakeAnalyticsStore) RecordConnected(string, time.Time) error    { return nil }
+func (a *fakeAnalyticsStore) RecordDisconnected(string, time.Time) error { return nil }
+func (a *fakeAnalyticsStore) CloseOpenConnections(time.Time) error       { return nil }
+
+func (a *fakeAnalyticsStore) loggedMessages() []model.MessageLog {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]model.MessageLog(nil), a.messages...)
+}
+
+func (a *fakeAnalyticsStore) loggedAnalytics() []model.Analytics {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]model.Analytics(nil), a.analytics...)
+}
+
+// fakeWebhook records payloads and answers every delivery with result and err.
+type fakeWebhook struct {
+	mu       sync.Mutex
+	payloads []webhook.WebhookPayload
+	result   webhook.WebhookResult
+	err      error
+}
+
+func (w *fakeWebhook) SendWebhook(endpoint webhook.Endpoint, payload webhook.WebhookPayload) (webhook.WebhookResult, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.payloads = append(w.payloads, payload)
+	return w.result, w.err
+}
+
+func (w *fakeWebhook) ResolveMedia(m *webhook.MediaReply) ([]byte, string, error) {
+	return nil, "", errors.New("media not supported by fake webhook")
+}
+
+func (w *fakeWebhook) sent() []webhook.WebhookPayload {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]webhook.WebhookPayload(nil), w.payloads...)
+}
+
+// testHarness is a ClientManager wired to fakes, with a fixed clock.
+type testHarness struct {
+	cm        *ClientManager
+	sessions  *fakeSessionStore
+	analytics *fakeAnalyticsStore
+	webhook   *fakeWebhook
+}
+
+var testNow = time.Date(2025, 1, 15, 9, 30, 0, 0, time.UTC)
+
+func newTestHarness(t *testing.T, sessions ...*model.Session) *testHarness {
+	t.Helper()
+
+	hub := websocket.NewHub(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	go hub.Run()
+
+	h := &testHarness{
+		sessions:  newFakeSessionStore(sessions...),
+		analytics: &fakeAnalyticsStore{},
+		webhook:   &fakeWebhook{},
+	}
+	cfg := &config.Config{MessageWorkers: 4, MessageQueueSize: 100, SendQueueSize: 10}
+	stopCh := make(chan struct{})
+	h.cm = &ClientManager{
+		Clients:        make(map[string]*whatsmeow.Client),
+		Config:         cfg,
+		SessionRepo:    h.sessions,
+		AnalyticsRepo:  h.analytics,
+		WSHub:          hub,
+		WebhookService: h.webhook,
+		qrCodes:        make(map[string]PendingQR),
+		reconnects:     make(map[string]chan struct{}),
+		chatQueue:      newChatQueue(cfg.MessageWorkers, cfg.MessageQueueSize),
+		triggers:       make(map[string]compiledTrigger),
+		stopCh:         stopCh,
+		now:            func() time.Time { return testNow },
+		groupInfo:      (*whatsmeow.Client).GetGroupInfo,
+		pictureInfo:    (*whatsmeow.Client).GetProfilePictureInfo,
+		log:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+
+		lastConnectAttempt: make(map[string]time.Time),
+	}
+	h.cm.sendQueue = newSendQueue(cfg.SendRatePerMinute, cfg.SendQueueSize, stopCh)
+
+	t.Cleanup(func() {
+		close(stopCh)
+		hub.Shutdown()
+	})
+	return h
+}
+
+// addClient registers an offline whatsmeow client for sessionID whose account is own.
+// It is enough for code that reads the store ID, such as mention gating.
+func (h *testHarness) addClient(sessionID string, own types.JID) *whatsmeow.Client {
+	client := whatsmeow.NewClient(&store.Device{ID: &own}, nil)
+	h.cm.mu.Lock()
+	h.cm.Clients[sessionID] = client
+	h.cm.mu.Unlock()
+	return client
+}
+
+// waitFor polls cond until it holds or a second passes; handleEvent hands work to goroutines.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// settle gives goroutines started by handleEvent time to act, for asserting that nothing happened.
+func settle() {
+	time.Sleep(50 * time.Millisecond)
+}
+
+func testSession(id string) *model.Session {
+	return &model.Session{
+		ID:          id,
+		UserID:      "user-1",
+		SessionName: "test",
+		WebhookURL:  "http://webhook.test/hook",
+		Status:      model.SessionStatusConnected,
+	}
+}
+
+var (
+	testSender = types.NewJID("628111111111", types.DefaultUserServer)
+	testOwnJID = types.NewJID("628999999999", types.DefaultUserServer)
+	testGroup  = types.NewJID("120363000000000001", types.GroupServer)
+)
+
+// messageEvent builds an incoming message from testSender, in group when it is non-empty.
+func messageEvent(id string, group types.JID, msg *waE2E.Message) *events.Message {
+	chat := testSender
+	if !group.IsEmpty() {
+		chat = group
+	}
+	return &events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{
+				Chat:    chat,
+				Sender:  testSender,
+				IsGroup: !group.IsEmpty(),
+			},
+			ID:        types.MessageID(id),
+			PushName:  "Ann",
+			Timestamp: testNow.Add(-time.Second),
+		},
+		Message: msg,
+	}
+}
+
+func textMessage(text string) *waE2E.Message {
+	return &waE2E.Message{Conversation: proto.String(text)}
+}
@@ -0,0 +1,67 @@
+package whatsapp
+
+import (
+	"wago-backend/internal/model"
+
+	"go.mau.fi/whatsmeow/proto/waHistorySync"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// ingestHistory writes messages from a history sync blob into messages_log so the
+// dashboard has context from before the bot connected. Nothing is sent to the webhook.
+func (cm *ClientManager) ingestHistory(sessionID string, data *waHistorySync.HistorySync) {
+	client := cm.GetClient(sessionID)
+	if client == nil {
+		return
+	}
+
+	stored := 0
+	for _, conv := range data.GetConversations() {
+		chatJID, err := types.ParseJID(conv.GetID())
+		if err != nil {
+			cm.sessionLog(sessionID).Warn("skipping history conversation with invalid JID", "jid", conv.GetID(), "error", err)
+			continue
+		}
+
+		for _, histMsg := range conv.GetMessages() {
+			evt, err := client.ParseWebMessage(chatJID, histMsg.GetMessage())
+			if err != nil {
+				continue
+			}
+			payload, ok := buildPayload(sessionID, evt)
+			if !ok {
+				continue
+			}
+
+			msgLog := &model.MessageLog{
+				SessionID:   sessionID,
+				MessageID:   evt.Info.ID,
+				Direction:   "incoming",
+				FromNumber:  evt.Info.Sender.User,
+				MessageType: payload.MessageType,
+				Content:     payload.Message,
+				IsGroup:     evt.Info.IsGroup,
+				Timestamp:   evt.Info.Timestamp,
+
+				QuotedMessageID: payload.QuotedMessageID,
+			}
+			if evt.Info.IsFromMe {
+				msgLog.Direction = "outgoing"
+				msgLog.FromNumber = ""
+				msgLog.ToNumber = chatJID.User
+			}
+			if evt.Info.IsGroup {
+				msgLog.GroupID = chatJID.User
+				msgLog.GroupName = conv.GetName()
+			}
+
+			if err := cm.AnalyticsRepo.LogMessage(msgLog); err != nil {
+				cm.sessionLog(sessionID).Error("failed to store history message", "message_id", evt.Info.ID, "error", err)
+				continue
+			}
+			stored++
+		}
+	}
+
+	cm.sessionLog(sessionID).Info("stored history sync messages", "count", stored, "sync_type", data.GetSyncType().String())
+}
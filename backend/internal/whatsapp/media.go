@@ -0,0 +1,119 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+// mediaKind maps a MIME type to the WhatsApp media category used for upload and message type.
+func mediaKind(mimeType string) (whatsmeow.MediaType, string) {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return whatsmeow.MediaImage, "image"
+	case strings.HasPrefix(mimeType, "video/"):
+		return whatsmeow.MediaVideo, "video"
+	case strings.HasPrefix(mimeType, "audio/"):
+		return whatsmeow.MediaAudio, "audio"
+	default:
+		return whatsmeow.MediaDocument, "document"
+	}
+}
+
+// commonMediaExtensions covers types whose first registered extension is not the usual one.
+var commonMediaExtensions = map[string]string{
+	"image/jpeg":      "jpg",
+	"image/png":       "png",
+	"image/webp":      "webp",
+	"image/gif":       "gif",
+	"video/mp4":       "mp4",
+	"audio/mpeg":      "mp3",
+	"audio/ogg":       "ogg",
+	"audio/mp4":       "m4a",
+	"application/pdf": "pdf",
+}
+
+// mediaFileName names media that arrived without a file name, e.g. "image_1700000000.jpg".
+// Unknown image types default to jpg, anything else unknown to bin.
+func mediaFileName(kind, mimeType string, at time.Time) string {
+	mediaType, _, _ := mime.ParseMediaType(mimeType)
+	ext, ok := commonMediaExtensions[mediaType]
+	if !ok {
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			ext = strings.TrimPrefix(exts[0], ".")
+		} else if kind == "image" {
+			ext = "jpg"
+		} else {
+			ext = "bin"
+		}
+	}
+	return fmt.Sprintf("%s_%d.%s", kind, at.Unix(), ext)
+}
+
+// buildMediaMessage uploads data and wraps it in the message type matching its MIME type.
+// It also returns the message type name used for logging ("image", "video", ...).
+func buildMediaMessage(ctx context.Context, client *whatsmeow.Client, data []byte, mimeType, fileName, caption string) (*waE2E.Message, string, error) {
+	appInfo, kind := mediaKind(mimeType)
+
+	up, err := client.Upload(ctx, data, appInfo)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to upload media: %w", err)
+	}
+
+	switch kind {
+	case "image":
+		return &waE2E.Message{ImageMessage: &waE2E.ImageMessage{
+			URL:           proto.String(up.URL),
+			DirectPath:    proto.String(up.DirectPath),
+			MediaKey:      up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256,
+			FileSHA256:    up.FileSHA256,
+			FileLength:    proto.Uint64(up.FileLength),
+			Mimetype:      proto.String(mimeType),
+			Caption:       proto.String(caption),
+		}}, kind, nil
+	case "video":
+		return &waE2E.Message{VideoMessage: &waE2E.VideoMessage{
+			URL:           proto.String(up.URL),
+			DirectPath:    proto.String(up.DirectPath),
+			MediaKey:      up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256,
+			FileSHA256:    up.FileSHA256,
+			FileLength:    proto.Uint64(up.FileLength),
+			Mimetype:      proto.String(mimeType),
+			Caption:       proto.String(caption),
+		}}, kind, nil
+	case "audio":
+		return &waE2E.Message{AudioMessage: &waE2E.AudioMessage{
+			URL:           proto.String(up.URL),
+			DirectPath:    proto.String(up.DirectPath),
+			MediaKey:      up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256,
+			FileSHA256:    up.FileSHA256,
+			FileLength:    proto.Uint64(up.FileLength),
+			Mimetype:      proto.String(mimeType),
+		}}, kind, nil
+	default:
+		if fileName == "" {
+			fileName = "document"
+		}
+		return &waE2E.Message{DocumentMessage: &waE2E.DocumentMessage{
+			URL:           proto.String(up.URL),
+			DirectPath:    proto.String(up.DirectPath),
+			MediaKey:      up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256,
+			FileSHA256:    up.FileSHA256,
+			FileLength:    proto.Uint64(up.FileLength),
+			Mimetype:      proto.String(mimeType),
+			FileName:      proto.String(fileName),
+			Title:         proto.String(fileName),
+			Caption:       proto.String(caption),
+		}}, kind, nil
+	}
+}
@@ -0,0 +1,106 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"wago-backend/internal/errs"
+	"wago-backend/internal/model"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// parseNewsletterJID accepts only channel JIDs such as "120363012345678901@newsletter".
+func parseNewsletterJID(raw string) (types.JID, error) {
+	jid, err := types.ParseJID(strings.TrimSpace(raw))
+	if err != nil || jid.User == "" || jid.Server != types.NewsletterServer {
+		return types.JID{}, fmt.Errorf("%w: invalid newsletter JID, expected <id>@%s", errs.ErrInvalidInput, types.NewsletterServer)
+	}
+	return jid, nil
+}
+
+func toNewsletter(meta *types.NewsletterMetadata) model.Newsletter {
+	newsletter := model.Newsletter{
+		JID:             meta.ID.String(),
+		Name:            meta.ThreadMeta.Name.Text,
+		Description:     meta.ThreadMeta.Description.Text,
+		SubscriberCount: meta.ThreadMeta.SubscriberCount,
+		State:           string(meta.State.Type),
+	}
+	if meta.ViewerMeta != nil {
+		newsletter.Role = string(meta.ViewerMeta.Role)
+		newsletter.Muted = meta.ViewerMeta.Mute == types.NewsletterMuteOn
+	}
+	return newsletter
+}
+
+// ListNewsletters returns the channels the session's account follows.
+func (cm *ClientManager) ListNewsletters(sessionID string) ([]model.Newsletter, error) {
+	client, err := cm.connectedClient(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	subscribed, err := client.GetSubscribedNewsletters(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch newsletters: %w", err)
+	}
+
+	newsletters := make([]model.Newsletter, 0, len(subscribed))
+	for _, meta := range subscribed {
+		newsletters = append(newsletters, toNewsletter(meta))
+	}
+	return newsletters, nil
+}
+
+// GetNewsletter fetches a channel's metadata.
+func (cm *ClientManager) GetNewsletter(sessionID, rawJID string) (*model.Newsletter, error) {
+	jid, err := parseNewsletterJID(rawJID)
+	if err != nil {
+		return nil, err
+	}
+	client, err := cm.connectedClient(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	meta, err := client.GetNewsletterInfo(ctx, jid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch newsletter info: %w", err)
+	}
+	newsletter := toNewsletter(meta)
+	return &newsletter, nil
+}
+
+// SetNewsletterFollowed follows or unfollows a channel. Posts in followed channels reach the
+// webhook with is_newsletter set.
+func (cm *ClientManager) SetNewsletterFollowed(sessionID, rawJID string, follow bool) error {
+	jid, err := parseNewsletterJID(rawJID)
+	if err != nil {
+		return err
+	}
+	client, err := cm.connectedClient(sessionID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if follow {
+		err = client.FollowNewsletter(ctx, jid)
+	} else {
+		err = client.UnfollowNewsletter(ctx, jid)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update newsletter subscription: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"wago-backend/internal/errs"
+
+	"go.mau.fi/whatsmeow"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// pairCodeWait bounds how long PairPhone waits for the login websocket to be ready.
+const pairCodeWait = 30 * time.Second
+
+// PairPhone links the session by phone number instead of a QR scan and returns the
+// 8-character code the user enters under Linked devices > Link with phone number.
+// A fresh client is created and registered like Connect does; its QR codes keep being
+// published, so either method can complete the pairing.
+func (cm *ClientManager) PairPhone(sessionID, phone string) (string, error) {
+	client, qrChan, err := cm.newPairingClient(sessionID)
+	if err != nil {
+		return "", err
+	}
+
+	// whatsmeow needs the first QR event before a pairing code can be requested.
+	var first whatsmeow.QRChannelItem
+	select {
+	case first = <-qrChan:
+	case <-time.After(pairCodeWait):
+		cm.discardPairingClient(sessionID, client)
+		return "", errors.New("timed out waiting for WhatsApp to start pairing")
+	}
+	cm.handleQRItem(sessionID, first)
+	if first.Event != whatsmeow.QRChannelEventCode {
+		cm.discardPairingClient(sessionID, client)
+		return "", fmt.Errorf("pairing failed: %s", first.Event)
+	}
+	go cm.watchQRChannel(sessionID, qrChan)
+
+	ctx, cancel := context.WithTimeout(context.Background(), pairCodeWait)
+	defer cancel()
+	code, err := client.PairPhone(ctx, phone, true, whatsmeow.PairClientChrome, "Chrome (Linux)")
+	if err != nil {
+		return "", fmt.Errorf("failed to request pairing code: %w", err)
+	}
+
+	cm.WSHub.SendToSession(sessionID, "pair_code", map[string]interface{}{
+		"pair_code": code,
+	})
+	return code, nil
+}
+
+// newPairingClient replaces any unpaired cached client with a new device, wires its events
+// the same way Connect does and opens the login websocket.
+func (cm *ClientManager) newPairingClient(sessionID string) (*whatsmeow.Client, <-chan whatsmeow.QRChannelItem, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cached, ok := cm.Clients[sessionID]
+	if ok && cached.Store.ID != nil {
+		return nil, nil, errs.ErrAlreadyPaired
+	}
+
+	session, err := cm.SessionRepo.GetSessionByID(sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if session.PhoneNumber != "" {
+		if jid, err := normalizeSessionJID(session.PhoneNumber); err == nil {
+			if device, _ := cm.Container.GetDevice(context.Background(), jid); device != nil {
+				return nil, nil, errs.ErrAlreadyPaired
+			}
+		}
+	}
+
+	if cm.coolingDown(sessionID) {
+		return nil, nil, fmt.Errorf("%w: connect attempted too soon", errs.ErrRateLimited)
+	}
+
+	// A stale QR flow is replaced by the pairing client.
+	if ok {
+		cached.Disconnect()
+		delete(cm.Clients, sessionID)
+		cm.clearQRCode(sessionID)
+	}
+
+	clientLog := waLog.Stdout("Client", cm.Config.LogLevel, true)
+	client := whatsmeow.NewClient(cm.Container.NewDevice(), clientLog)
+	client.AddEventHandler(func(evt interface{}) {
+		cm.handleEvent(sessionID, evt)
+	})
+
+	qrChan, err := client.GetQRChannel(context.Background())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get QR channel: %w", err)
+	}
+	if err := client.Connect(); err != nil {
+		return nil, nil, err
+	}
+
+	cm.Clients[sessionID] = client
+	cm.touchActivity(sessionID)
+	return client, qrChan, nil
+}
+
+// discardPairingClient drops client if it is still the session's cached client.
+func (cm *ClientManager) discardPairingClient(sessionID string, client *whatsmeow.Client) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.Clients[sessionID] == client {
+		delete(cm.Clients, sessionID)
+	}
+	client.Disconnect()
+}
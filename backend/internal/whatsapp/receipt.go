@@ -0,0 +1,64 @@
+package whatsapp
+
+import (
+	"wago-backend/internal/webhook"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// receiptStatuses maps the receipt types reported to webhooks to their status names.
+// Receipts from our own devices, retries and sender receipts are not forwarded.
+var receiptStatuses = map[types.ReceiptType]string{
+	types.ReceiptTypeDelivered: webhook.StatusDelivered,
+	types.ReceiptTypeRead:      webhook.StatusRead,
+	types.ReceiptTypePlayed:    webhook.StatusPlayed,
+}
+
+// buildStatusPayload converts a receipt for messages the session sent into a status webhook payload.
+func buildStatusPayload(sessionID string, v *events.Receipt) (payload webhook.WebhookPayload, ok bool) {
+	// IsFromMe receipts are our own devices reading or playing messages sent to us.
+	status, ok := receiptStatuses[v.Type]
+	if !ok || v.IsFromMe || len(v.MessageIDs) == 0 {
+		return payload, false
+	}
+
+	ids := make([]string, len(v.MessageIDs))
+	for i, id := range v.MessageIDs {
+		ids[i] = string(id)
+	}
+
+	return webhook.WebhookPayload{
+		SessionID:    sessionID,
+		Event:        webhook.EventStatus,
+		From:         v.Sender.User,
+		Timestamp:    v.Timestamp,
+		IsGroup:      v.IsGroup,
+		IsNewsletter: v.Chat.Server == types.NewsletterServer,
+		MessageType:  "status",
+		Status: &webhook.MessageStatus{
+			MessageIDs: ids,
+			Status:     status,
+			Recipient:  v.Sender.User,
+			Chat:       v.Chat.ToNonAD().String(),
+		},
+	}, true
+}
+
+// handleReceipt posts delivered/read/played updates for outgoing messages to sessions that opted in.
+// Replies to status webhooks are ignored.
+func (cm *ClientManager) handleReceipt(sessionID string, v *events.Receipt) {
+	payload, ok := buildStatusPayload(sessionID, v)
+	if !ok {
+		return
+	}
+
+	session, err := cm.SessionRepo.GetSessionByID(sessionID)
+	if err != nil || !session.WebhookStatusEvents {
+		return
+	}
+
+	if _, err := cm.WebhookService.SendWebhook(webhook.EndpointForSession(session), payload); err != nil {
+		cm.sessionLog(sessionID).Error("failed to send status webhook", "error", err)
+	}
+}
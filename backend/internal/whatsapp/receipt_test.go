@@ -0,0 +1,69 @@
+package whatsapp
+
+import (
+	"testing"
+	"wago-backend/internal/webhook"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func testReceipt(receiptType types.ReceiptType, fromMe bool) *events.Receipt {
+	sender := testSender
+	if fromMe {
+		sender = testOwnJID
+	}
+	return &events.Receipt{
+		MessageSource: types.MessageSource{Chat: testSender, Sender: sender, IsFromMe: fromMe},
+		MessageIDs:    []types.MessageID{"m1"},
+		Timestamp:     testNow,
+		Type:          receiptType,
+	}
+}
+
+func TestBuildStatusPayload(t *testing.T) {
+	cases := []struct {
+		name string
+		v    *events.Receipt
+		want string // empty when the receipt must not be forwarded
+	}{
+		{"delivered", testReceipt(types.ReceiptTypeDelivered, false), webhook.StatusDelivered},
+		{"read by the recipient", testReceipt(types.ReceiptTypeRead, false), webhook.StatusRead},
+		{"played by the recipient", testReceipt(types.ReceiptTypePlayed, false), webhook.StatusPlayed},
+		{"read on our own phone", testReceipt(types.ReceiptTypeRead, true), ""},
+		{"view-once played on our own phone", testReceipt(types.ReceiptTypePlayed, true), ""},
+		{"sender receipt", testReceipt(types.ReceiptTypeSender, true), ""},
+		{"retry", testReceipt(types.ReceiptTypeRetry, false), ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			payload, ok := buildStatusPayload("s1", tc.v)
+			if tc.want == "" {
+				if ok {
+					t.Fatalf("forwarded %+v", payload.Status)
+				}
+				return
+			}
+			if !ok || payload.Status.Status != tc.want || payload.Status.Recipient != testSender.User {
+				t.Fatalf("buildStatusPayload = %+v, %v; want %s from %s", payload.Status, ok, tc.want, testSender.User)
+			}
+		})
+	}
+}
+
+func TestHandleEventSkipsOwnReadReceipts(t *testing.T) {
+	session := testSession("s1")
+	session.WebhookStatusEvents = true
+	h := newTestHarness(t, session)
+
+	h.cm.handleEvent("s1", testReceipt(types.ReceiptTypeRead, true))
+	h.cm.handleEvent("s1", testReceipt(types.ReceiptTypePlayed, true))
+	h.cm.handleEvent("s1", testReceipt(types.ReceiptTypeRead, false))
+
+	waitFor(t, "status webhook", func() bool { return len(h.webhook.sent()) >= 1 })
+	settle()
+	sent := h.webhook.sent()
+	if len(sent) != 1 || sent[0].Status.Recipient != testSender.User {
+		t.Fatalf("status webhooks = %+v, want only the recipient's read receipt", sent)
+	}
+}
@@ -0,0 +1,83 @@
+package whatsapp
+
+import (
+	"errors"
+	"time"
+	"wago-backend/internal/errs"
+)
+
+const (
+	reconnectBaseDelay = time.Second
+	reconnectMaxDelay  = 5 * time.Minute
+)
+
+// scheduleReconnect keeps retrying Connect for sessionID with exponential backoff until it
+// connects, the session can't be resumed without the user (needs relink, deleted), the user
+// stops it or the manager shuts down. At most one retry loop runs per session.
+//
+// whatsmeow reconnects on its own after most drops; this loop is the fallback for failed
+// connects and for cases where its auto-reconnect gives up.
+func (cm *ClientManager) scheduleReconnect(sessionID string) {
+	cm.reconnectMu.Lock()
+	if _, pending := cm.reconnects[sessionID]; pending {
+		cm.reconnectMu.Unlock()
+		return
+	}
+	cancel := make(chan struct{})
+	cm.reconnects[sessionID] = cancel
+	cm.reconnectMu.Unlock()
+
+	go func() {
+		defer cm.finishReconnect(sessionID, cancel)
+
+		delay := reconnectBaseDelay
+		for attempt := 1; ; attempt++ {
+			select {
+			case <-cancel:
+				return
+			case <-cm.stopCh:
+				return
+			case <-time.After(delay):
+			}
+
+			status, err := cm.Connect(sessionID, false)
+			switch {
+			case err == nil && status == "connected":
+				cm.sessionLog(sessionID).Info("reconnected session", "attempts", attempt)
+				return
+			case err == nil && status != StatusCoolingDown:
+				// needs_relink or a QR flow: only the user can finish these.
+				cm.sessionLog(sessionID).Info("giving up reconnecting session", "status", status)
+				return
+			case errors.Is(err, errs.ErrSessionNotFound):
+				return
+			case err != nil:
+				cm.sessionLog(sessionID).Warn("reconnect attempt failed", "attempt", attempt, "error", err)
+			}
+
+			delay *= 2
+			if delay > reconnectMaxDelay {
+				delay = reconnectMaxDelay
+			}
+		}
+	}()
+}
+
+// cancelReconnect stops a pending retry loop for sessionID, if any.
+func (cm *ClientManager) cancelReconnect(sessionID string) {
+	cm.reconnectMu.Lock()
+	defer cm.reconnectMu.Unlock()
+	if cancel, ok := cm.reconnects[sessionID]; ok {
+		close(cancel)
+		delete(cm.reconnects, sessionID)
+	}
+}
+
+// finishReconnect forgets a retry loop that ended on its own. It leaves a newer loop alone.
+func (cm *ClientManager) finishReconnect(sessionID string, cancel chan struct{}) {
+	cm.reconnectMu.Lock()
+	defer cm.reconnectMu.Unlock()
+	if cm.reconnects[sessionID] == cancel {
+		delete(cm.reconnects, sessionID)
+	}
+}
@@ -0,0 +1,187 @@
+package whatsapp
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+
+	"wago-backend/internal/model"
+	"wago-backend/internal/webhook"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// autoReplyVars are the placeholders available in the session's busy reply and footer:
+// push_name and from (the sender's push name and number), group_name, date and time.
+func autoReplyVars(info types.MessageInfo, groupName string, now time.Time) map[string]string {
+	return map[string]string{
+		"push_name":  info.PushName,
+		"from":       info.Sender.User,
+		"group_name": groupName,
+		"date":       now.Format("2006-01-02"),
+		"time":       now.Format("15:04"),
+	}
+}
+
+// withFooter appends the session's footer to a reply's text or media caption.
+// An empty footer leaves the reply unchanged.
+func withFooter(reply webhook.Reply, footer string) webhook.Reply {
+	if footer == "" {
+		return reply
+	}
+	if reply.Media != nil {
+		media := *reply.Media
+		media.Caption = appendFooter(media.Caption, footer)
+		reply.Media = &media
+		return reply
+	}
+	reply.Text = appendFooter(reply.Text, footer)
+	return reply
+}
+
+func appendFooter(text, footer string) string {
+	if text == "" {
+		return footer
+	}
+	return text + "\n\n" + footer
+}
+
+// Typing delay bounds: replies show "typing..." for typingDelayPerChar per character,
+// clamped so short replies still look typed and long ones aren't held back for long.
+const (
+	typingDelayPerChar = 40 * time.Millisecond
+	minTypingDelay     = 500 * time.Millisecond
+	maxTypingDelay     = 4 * time.Second
+)
+
+// typingDelay is how long to show the typing indicator before sending text.
+func typingDelay(text string) time.Duration {
+	delay := time.Duration(utf8.RuneCountInString(text)) * typingDelayPerChar
+	return min(max(delay, minTypingDelay), maxTypingDelay)
+}
+
+// quoteContext makes a reply quote the incoming message described by info and msg.
+// chat is where the reply goes; a reply sent elsewhere (e.g. privately to a group member)
+// names the original chat so WhatsApp can show where the quote came from.
+func quoteContext(info types.MessageInfo, msg *waE2E.Message, chat types.JID) *waE2E.ContextInfo {
+	quote := &waE2E.ContextInfo{
+		StanzaID:      proto.String(info.ID),
+		Participant:   proto.String(info.Sender.ToNonAD().String()),
+		QuotedMessage: msg,
+	}
+	if info.Chat.ToNonAD() != chat.ToNonAD() {
+		quote.RemoteJID = proto.String(info.Chat.ToNonAD().String())
+	}
+	return quote
+}
+
+// withQuote attaches quote to msg, turning plain text into extended text so it can carry it.
+func withQuote(msg *waE2E.Message, quote *waE2E.ContextInfo) *waE2E.Message {
+	switch {
+	case msg.Conversation != nil:
+		return &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+			Text:        msg.Conversation,
+			ContextInfo: quote,
+		}}
+	case msg.ImageMessage != nil:
+		msg.ImageMessage.ContextInfo = quote
+	case msg.VideoMessage != nil:
+		msg.VideoMessage.ContextInfo = quote
+	case msg.AudioMessage != nil:
+		msg.AudioMessage.ContextInfo = quote
+	case msg.DocumentMessage != nil:
+		msg.DocumentMessage.ContextInfo = quote
+	}
+	return msg
+}
+
+// sendReply delivers a webhook reply to replyJID as text or media and logs the outgoing message.
+// A non-nil quote makes it a reply to that message (see quoteContext).
+// In dry-run sessions the reply is only logged. It reports whether the reply was handled.
+// log carries the session and triggering message IDs.
+func (cm *ClientManager) sendReply(log *slog.Logger, client *whatsmeow.Client, sessionID string, session *model.Session, replyJID types.JID, replyInGroup bool, groupName string, reply webhook.Reply, quote *waE2E.ContextInfo) bool {
+	content := reply.Text
+	if reply.Media != nil {
+		content = reply.Media.Caption
+	}
+	log = log.With("to", replyJID.String())
+	log.Debug("got reply from webhook", "content", content, "media", reply.Media != nil)
+
+	if session.DryRun {
+		// Dry run: exercise the webhook against real traffic without replying.
+		log.Info("dry run: would send reply", "content", content, "media", reply.Media != nil)
+		return true
+	}
+	if client == nil {
+		log.Error("client is nil, cannot send reply")
+		return false
+	}
+
+	msg := &waE2E.Message{Conversation: proto.String(reply.Text)}
+	messageType := "text"
+	if reply.Media != nil {
+		data, mimeType, err := cm.WebhookService.ResolveMedia(reply.Media)
+		if err != nil {
+			log.Error("failed to resolve media reply", "error", err)
+			return false
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		msg, messageType, err = buildMediaMessage(ctx, client, data, mimeType, reply.Media.FileName, reply.Media.Caption)
+		cancel()
+		if err != nil {
+			log.Error("failed to build media reply", "error", err)
+			return false
+		}
+	}
+
+	if quote != nil {
+		msg = withQuote(msg, quote)
+	}
+
+	if session.TypingDelay {
+		// Paused is sent on every path out, so a failed send doesn't leave the chat showing "typing...".
+		client.SendChatPresence(context.Background(), replyJID, types.ChatPresenceComposing, types.ChatPresenceMediaText)
+		defer client.SendChatPresence(context.Background(), replyJID, types.ChatPresencePaused, types.ChatPresenceMediaText)
+		time.Sleep(typingDelay(content))
+	}
+
+	log.Debug("sending reply", "type", messageType)
+	resp, err := cm.send(context.Background(), sessionID, client, replyJID, msg)
+	if err != nil {
+		log.Error("failed to send reply", "type", messageType, "error", err)
+		return false
+	}
+	log.Info("reply sent", "type", messageType, "reply_id", resp.ID)
+	cm.touchActivity(sessionID)
+
+	// Log Outgoing Message (AI Reply)
+	go cm.logOutgoing(sessionID, resp.ID, replyJID, replyInGroup, groupName, messageType, content)
+	return true
+}
+
+// logOutgoing stores a message the session sent. groupName is only used for group chats.
+func (cm *ClientManager) logOutgoing(sessionID string, messageID types.MessageID, to types.JID, isGroup bool, groupName, messageType, content string) {
+	msgLog := &model.MessageLog{
+		SessionID:   sessionID,
+		MessageID:   messageID,
+		Direction:   "outgoing",
+		FromNumber:  "", // It's us
+		ToNumber:    to.User,
+		MessageType: messageType,
+		Content:     content,
+		IsGroup:     isGroup,
+		Timestamp:   cm.now(),
+	}
+	if isGroup {
+		msgLog.GroupID = to.User
+		msgLog.GroupName = groupName
+	}
+	if err := cm.AnalyticsRepo.LogMessage(msgLog); err != nil {
+		cm.sessionLog(sessionID).Error("failed to log outgoing message", "error", err)
+	}
+}
@@ -0,0 +1,125 @@
+package whatsapp
+
+import (
+	"errors"
+	"time"
+	"wago-backend/internal/errs"
+	"wago-backend/internal/model"
+)
+
+const (
+	scheduleInterval = 15 * time.Second
+	scheduleBatch    = 100
+
+	// scheduleGrace is how long a due message waits for its session to connect before it fails.
+	scheduleGrace = time.Hour
+)
+
+// runScheduler sends due scheduled messages until Shutdown. Messages of sessions that are
+// not connected wait up to scheduleGrace, so a restart or a short outage doesn't drop them.
+func (cm *ClientManager) runScheduler() {
+	if n, err := cm.ScheduleRepo.FailInterrupted(); err != nil {
+		cm.log.Error("failed to recover interrupted scheduled messages", "error", err)
+	} else if n > 0 {
+		cm.log.Warn("scheduled messages interrupted by a restart marked failed", "count", n)
+	}
+
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cm.stopCh:
+			return
+		case <-ticker.C:
+			cm.dispatchScheduled()
+		}
+	}
+}
+
+// connectedSessionIDs lists the sessions whose client is connected and logged in.
+func (cm *ClientManager) connectedSessionIDs() []string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	ids := make([]string, 0, len(cm.Clients))
+	for id, client := range cm.Clients {
+		if client.IsConnected() && client.IsLoggedIn() {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (cm *ClientManager) dispatchScheduled() {
+	now := cm.now().UTC()
+	connected := cm.connectedSessionIDs()
+
+	if n, err := cm.ScheduleRepo.FailOverdue(now.Add(-scheduleGrace), connected); err != nil {
+		cm.log.Error("failed to expire overdue scheduled messages", "error", err)
+	} else if n > 0 {
+		cm.log.Warn("scheduled messages failed, session not connected", "count", n)
+	}
+	if len(connected) == 0 {
+		return
+	}
+
+	due, err := cm.ScheduleRepo.ListDue(now, connected, scheduleBatch)
+	if err != nil {
+		cm.log.Error("failed to load due scheduled messages", "error", err)
+		return
+	}
+
+	bySession := make(map[string][]model.ScheduledMessage)
+	for _, m := range due {
+		bySession[m.SessionID] = append(bySession[m.SessionID], m)
+	}
+	for sessionID, messages := range bySession {
+		// One sender per session keeps its messages in order; the next poll picks up the rest.
+		if _, busy := cm.scheduling.LoadOrStore(sessionID, struct{}{}); busy {
+			continue
+		}
+		go func(sessionID string, messages []model.ScheduledMessage) {
+			defer cm.scheduling.Delete(sessionID)
+			for _, m := range messages {
+				if !cm.sendScheduled(m) {
+					return
+				}
+			}
+		}(sessionID, messages)
+	}
+}
+
+// sendScheduled claims and sends one message through the throttled send queue. It returns
+// false when the session can't send right now, leaving the message pending for a later poll.
+func (cm *ClientManager) sendScheduled(m model.ScheduledMessage) bool {
+	log := cm.sessionLog(m.SessionID).With("schedule_id", m.ID)
+
+	claimed, err := cm.ScheduleRepo.Claim(m.ID)
+	if err != nil {
+		log.Error("failed to claim scheduled message", "error", err)
+		return false
+	}
+	if !claimed {
+		return true // cancelled meanwhile
+	}
+
+	messageID, err := cm.SendMessage(m.SessionID, m.Recipient, m.Message)
+	switch {
+	case err == nil:
+		if err := cm.ScheduleRepo.MarkSent(m.ID, string(messageID), cm.now()); err != nil {
+			log.Error("failed to mark scheduled message sent", "message_id", messageID, "error", err)
+		}
+		return true
+	case errors.Is(err, errs.ErrNotConnected), errors.Is(err, errs.ErrRateLimited):
+		if err := cm.ScheduleRepo.Release(m.ID); err != nil {
+			log.Error("failed to release scheduled message", "error", err)
+		}
+		return false
+	default:
+		log.Warn("scheduled message failed", "error", err)
+		if err := cm.ScheduleRepo.MarkFailed(m.ID, err.Error()); err != nil {
+			log.Error("failed to mark scheduled message failed", "error", err)
+		}
+		return true
+	}
+}
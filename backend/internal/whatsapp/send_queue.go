@@ -0,0 +1,128 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+	"wago-backend/internal/errs"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// maxSendJitter is the most random delay added on top of the throttle interval, so
+// throttled sends don't go out at a perfectly regular, bot-like cadence.
+const maxSendJitter = 500 * time.Millisecond
+
+type sendJob struct {
+	ctx    context.Context
+	client *whatsmeow.Client
+	to     types.JID
+	msg    *waE2E.Message
+	result chan sendResult
+}
+
+type sendResult struct {
+	resp whatsmeow.SendResponse
+	err  error
+}
+
+// sendQueue serializes each session's outgoing messages through one worker that spaces
+// them at least interval apart. WhatsApp bans accounts that send in bursts.
+type sendQueue struct {
+	interval time.Duration // 0 sends back to back
+	size     int
+	stop     <-chan struct{}
+
+	mu     sync.Mutex
+	queues map[string]chan sendJob
+}
+
+func newSendQueue(perMinute, size int, stop <-chan struct{}) *sendQueue {
+	var interval time.Duration
+	if perMinute > 0 {
+		interval = time.Minute / time.Duration(perMinute)
+	}
+	if size < 1 {
+		size = 1
+	}
+	return &sendQueue{
+		interval: interval,
+		size:     size,
+		stop:     stop,
+		queues:   make(map[string]chan sendJob),
+	}
+}
+
+// Send queues msg for the session's worker and waits for the result. It fails with
+// errs.ErrRateLimited when the session already has too many sends waiting.
+func (q *sendQueue) Send(ctx context.Context, sessionID string, client *whatsmeow.Client, to types.JID, msg *waE2E.Message) (whatsmeow.SendResponse, error) {
+	job := sendJob{ctx: ctx, client: client, to: to, msg: msg, result: make(chan sendResult, 1)}
+	select {
+	case q.queue(sessionID) <- job:
+	default:
+		return whatsmeow.SendResponse{}, fmt.Errorf("%w: too many messages waiting to be sent", errs.ErrRateLimited)
+	}
+
+	select {
+	case res := <-job.result:
+		return res.resp, res.err
+	case <-ctx.Done():
+		return whatsmeow.SendResponse{}, ctx.Err()
+	case <-q.stop:
+		return whatsmeow.SendResponse{}, errs.ErrNotConnected
+	}
+}
+
+func (q *sendQueue) queue(sessionID string) chan sendJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs, ok := q.queues[sessionID]
+	if !ok {
+		jobs = make(chan sendJob, q.size)
+		q.queues[sessionID] = jobs
+		go q.work(jobs)
+	}
+	return jobs
+}
+
+func (q *sendQueue) work(jobs <-chan sendJob) {
+	var last time.Time
+	for {
+		var job sendJob
+		select {
+		case <-q.stop:
+			return
+		case job = <-jobs:
+		}
+
+		if q.interval > 0 && !last.IsZero() {
+			wait := q.interval + time.Duration(rand.Int63n(int64(maxSendJitter))) - time.Since(last)
+			if wait > 0 {
+				select {
+				case <-q.stop:
+					return
+				case <-job.ctx.Done():
+				case <-time.After(wait):
+				}
+			}
+		}
+
+		// The caller stopped waiting; don't send something it already reported as failed.
+		if err := job.ctx.Err(); err != nil {
+			job.result <- sendResult{err: err}
+			continue
+		}
+		resp, err := job.client.SendMessage(job.ctx, job.to, job.msg)
+		last = time.Now()
+		job.result <- sendResult{resp: resp, err: err}
+	}
+}
+
+// send delivers msg through the session's throttled send queue.
+func (cm *ClientManager) send(ctx context.Context, sessionID string, client *whatsmeow.Client, to types.JID, msg *waE2E.Message) (whatsmeow.SendResponse, error) {
+	return cm.sendQueue.Send(ctx, sessionID, client, to, msg)
+}
@@ -0,0 +1,40 @@
+package whatsapp
+
+import "time"
+
+// ClientStatus is the live state of a session's in-memory client, as opposed to the
+// status persisted on the session row.
+type ClientStatus struct {
+	Loaded      bool // a client exists in the manager
+	Connected   bool // the websocket to WhatsApp is up
+	LoggedIn    bool // the client is authenticated
+	JID         string
+	LastEventAt *time.Time // last event of any kind from whatsmeow
+}
+
+// touchEvent records that whatsmeow just delivered an event for the session.
+func (cm *ClientManager) touchEvent(sessionID string) {
+	cm.lastEvent.Store(sessionID, cm.now())
+}
+
+// ClientStatus reports the session's live client state. It is safe to call for sessions
+// without a client; those report Loaded false.
+func (cm *ClientManager) ClientStatus(sessionID string) ClientStatus {
+	var status ClientStatus
+	if val, ok := cm.lastEvent.Load(sessionID); ok {
+		at := val.(time.Time)
+		status.LastEventAt = &at
+	}
+
+	client := cm.GetClient(sessionID)
+	if client == nil {
+		return status
+	}
+	status.Loaded = true
+	status.Connected = client.IsConnected()
+	status.LoggedIn = client.IsLoggedIn()
+	if client.Store != nil && client.Store.ID != nil {
+		status.JID = client.Store.ID.String()
+	}
+	return status
+}
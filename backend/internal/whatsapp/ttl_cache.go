@@ -0,0 +1,67 @@
+package whatsapp
+
+import (
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// ttlSweepInterval is how often set drops every expired entry, so keys that are never read
+// again don't stay in the cache for the life of the process.
+const ttlSweepInterval = 10 * time.Minute
+
+type ttlEntry struct {
+	value   string
+	expires time.Time
+}
+
+// ttlCache holds strings until their expiry time, such as group subjects or profile picture
+// URLs looked up from WhatsApp. The zero value is ready to use.
+type ttlCache struct {
+	mu        sync.Mutex
+	entries   map[string]ttlEntry
+	lastSweep time.Time
+}
+
+// sessionJIDKey keys a cache entry by session and chat or contact, ignoring the device part.
+func sessionJIDKey(sessionID string, jid types.JID) string {
+	return sessionID + "|" + jid.ToNonAD().String()
+}
+
+func (c *ttlCache) get(key string, now time.Time) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	if !now.Before(entry.expires) {
+		delete(c.entries, key)
+		return "", false
+	}
+	return entry.value, true
+}
+
+func (c *ttlCache) set(key, value string, now time.Time, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]ttlEntry)
+	}
+	c.entries[key] = ttlEntry{value: value, expires: now.Add(ttl)}
+	c.sweep(now)
+}
+
+// sweep drops expired entries, at most once per ttlSweepInterval. c.mu must be held.
+func (c *ttlCache) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < ttlSweepInterval {
+		return
+	}
+	c.lastSweep = now
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+}
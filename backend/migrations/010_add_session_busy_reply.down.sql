@@ -0,0 +1,3 @@
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS busy_reply_grace_ms,
+    DROP COLUMN IF EXISTS busy_reply_text;
@@ -0,0 +1,3 @@
+ALTER TABLE sessions
+    ADD COLUMN IF NOT EXISTS busy_reply_text TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS busy_reply_grace_ms INTEGER NOT NULL DEFAULT 3000;
@@ -0,0 +1,2 @@
+ALTER TABLE analytics DROP COLUMN IF EXISTS dry_run;
+ALTER TABLE sessions DROP COLUMN IF EXISTS dry_run;
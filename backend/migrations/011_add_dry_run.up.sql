@@ -0,0 +1,2 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS dry_run BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE analytics ADD COLUMN IF NOT EXISTS dry_run BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS reply_privately_in_groups BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS trigger_pattern TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+-- webhook_secret is written by the application encrypted with AES-256-GCM when
+-- ENCRYPTION_KEY is set ("enc:v1:" prefix). Rows written without a key stay plaintext
+-- and are re-encrypted the next time the secret is saved.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS webhook_secret TEXT NOT NULL DEFAULT '';
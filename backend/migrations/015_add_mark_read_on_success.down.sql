@@ -0,0 +1 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS mark_read_on_success;
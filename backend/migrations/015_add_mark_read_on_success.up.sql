@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS mark_read_on_success BOOLEAN NOT NULL DEFAULT false;
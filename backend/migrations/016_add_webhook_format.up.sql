@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS webhook_format TEXT NOT NULL DEFAULT 'json';
@@ -0,0 +1 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS webhook_method;
@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS webhook_method TEXT NOT NULL DEFAULT 'POST';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_sessions_tags;
+ALTER TABLE sessions DROP COLUMN IF EXISTS tags;
@@ -0,0 +1,2 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS tags JSONB NOT NULL DEFAULT '[]'::jsonb;
+CREATE INDEX IF NOT EXISTS idx_sessions_tags ON sessions USING GIN (tags);
@@ -0,0 +1,2 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS webhook_exclude_fields;
+ALTER TABLE sessions DROP COLUMN IF EXISTS webhook_include_fields;
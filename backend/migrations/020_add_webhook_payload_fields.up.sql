@@ -0,0 +1,2 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS webhook_include_fields JSONB NOT NULL DEFAULT '[]'::jsonb;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS webhook_exclude_fields JSONB NOT NULL DEFAULT '[]'::jsonb;
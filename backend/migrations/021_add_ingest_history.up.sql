@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS ingest_history BOOLEAN NOT NULL DEFAULT false;
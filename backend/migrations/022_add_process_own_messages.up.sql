@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS process_own_messages BOOLEAN NOT NULL DEFAULT false;
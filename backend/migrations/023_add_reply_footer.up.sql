@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS reply_footer TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+UPDATE sessions SET status = 'disconnected' WHERE status = 'needs_relink';
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS valid_status;
+ALTER TABLE sessions ADD CONSTRAINT valid_status CHECK (status IN ('qr', 'connected', 'disconnected'));
@@ -0,0 +1,2 @@
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS valid_status;
+ALTER TABLE sessions ADD CONSTRAINT valid_status CHECK (status IN ('qr', 'connected', 'disconnected', 'needs_relink'));
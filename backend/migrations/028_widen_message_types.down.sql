@@ -0,0 +1,7 @@
+DELETE FROM messages_log WHERE message_type IN ('reaction', 'poll', 'button_response', 'list_response');
+ALTER TABLE messages_log DROP CONSTRAINT IF EXISTS valid_message_type;
+ALTER TABLE messages_log ADD CONSTRAINT valid_message_type CHECK (message_type IN ('text', 'image', 'document', 'audio', 'video', 'sticker', 'location', 'contact'));
+
+DELETE FROM analytics WHERE message_type IN ('reaction', 'poll', 'button_response', 'list_response');
+ALTER TABLE analytics DROP CONSTRAINT IF EXISTS valid_message_type;
+ALTER TABLE analytics ADD CONSTRAINT valid_message_type CHECK (message_type IN ('text', 'image', 'document', 'audio', 'video', 'sticker', 'location', 'contact'));
@@ -0,0 +1,5 @@
+ALTER TABLE messages_log DROP CONSTRAINT IF EXISTS valid_message_type;
+ALTER TABLE messages_log ADD CONSTRAINT valid_message_type CHECK (message_type IN ('text', 'image', 'document', 'audio', 'video', 'sticker', 'location', 'contact', 'reaction', 'poll', 'button_response', 'list_response'));
+
+ALTER TABLE analytics DROP CONSTRAINT IF EXISTS valid_message_type;
+ALTER TABLE analytics ADD CONSTRAINT valid_message_type CHECK (message_type IN ('text', 'image', 'document', 'audio', 'video', 'sticker', 'location', 'contact', 'reaction', 'poll', 'button_response', 'list_response'));
@@ -0,0 +1,2 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS webhook_timeout_seconds;
+ALTER TABLE sessions DROP COLUMN IF EXISTS webhook_retries;
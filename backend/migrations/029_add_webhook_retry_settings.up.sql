@@ -0,0 +1,2 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS webhook_retries INTEGER NOT NULL DEFAULT 2;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS webhook_timeout_seconds INTEGER NOT NULL DEFAULT 60;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS session_connections (
+    id BIGSERIAL PRIMARY KEY,
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    connected_at TIMESTAMP NOT NULL,
+    disconnected_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_connections_session ON session_connections(session_id, connected_at);
+
+-- At most one open connection per session, so repeated Connected events aren't counted twice.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_session_connections_open ON session_connections(session_id) WHERE disconnected_at IS NULL;
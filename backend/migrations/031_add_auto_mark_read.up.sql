@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS auto_mark_read BOOLEAN NOT NULL DEFAULT false;
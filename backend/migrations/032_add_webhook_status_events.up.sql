@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS webhook_status_events BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS group_settings (
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    group_jid TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    mention_only BOOLEAN NOT NULL DEFAULT true,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (session_id, group_jid)
+);
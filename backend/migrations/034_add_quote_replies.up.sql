@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS quote_replies BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS typing_delay BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pin_rotated_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+-- Existing PINs date from account creation, so PIN_MAX_AGE_DAYS applies to them too.
+UPDATE users SET pin_rotated_at = created_at;
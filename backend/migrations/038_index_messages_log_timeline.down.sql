@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_messages_log_session_timeline;
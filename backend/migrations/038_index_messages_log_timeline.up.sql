@@ -0,0 +1,2 @@
+-- Serves paginated message history: newest first per session, ties broken by id.
+CREATE INDEX IF NOT EXISTS idx_messages_log_session_timeline ON messages_log(session_id, timestamp DESC, id DESC);
@@ -0,0 +1 @@
+ALTER TABLE broadcast_recipients DROP COLUMN IF EXISTS variables;
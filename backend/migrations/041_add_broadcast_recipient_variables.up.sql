@@ -0,0 +1,2 @@
+-- Per-recipient {{variable}} values substituted into the broadcast message.
+ALTER TABLE broadcast_recipients ADD COLUMN IF NOT EXISTS variables JSONB NOT NULL DEFAULT '{}';
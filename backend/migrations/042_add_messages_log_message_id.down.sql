@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_messages_log_session_message;
+ALTER TABLE messages_log DROP COLUMN IF EXISTS message_id;
@@ -0,0 +1,4 @@
+-- WhatsApp message ID of each logged message, so history syncs that overlap each other or
+-- live traffic don't store the same message twice. Rows logged before this have none.
+ALTER TABLE messages_log ADD COLUMN IF NOT EXISTS message_id TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_log_session_message ON messages_log(session_id, message_id) WHERE message_id IS NOT NULL;